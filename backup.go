@@ -0,0 +1,45 @@
+package main
+
+import (
+	"autobot/internal/backup"
+	"autobot/internal/config"
+	"autobot/internal/leader"
+	"fmt"
+)
+
+// runBackup 处理 autobot backup [path] 子命令
+// 未指定路径时备份到配置的备份目录并按保留数量轮转
+func runBackup(cfg *config.Config, args []string) error {
+	if len(args) > 0 {
+		if err := backup.BackupTo(args[0]); err != nil {
+			return err
+		}
+		fmt.Printf("Backup written to %s\n", args[0])
+		return nil
+	}
+
+	manager := backup.NewManager(cfg.Backup.Dir, 0, cfg.Backup.Keep)
+	file, err := manager.Create()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Backup written to %s\n", file.Path)
+	return nil
+}
+
+// runRestore 处理 autobot restore <path> 子命令，必须在 autobot 停止时执行，有实例在运行时拒绝恢复
+func runRestore(cfg *config.Config, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: autobot restore <backup-file>\n"+
+			"all instances using the database must be stopped; after a crash wait %s for its heartbeat to expire", leader.DefaultLeaseDuration)
+	}
+	if cfg.Database.Driver != "sqlite" {
+		return fmt.Errorf("restore is only supported for SQLite, use the native tools for %s", cfg.Database.Driver)
+	}
+
+	if err := backup.Restore(args[0], cfg.SQLitePath()); err != nil {
+		return err
+	}
+	fmt.Printf("Database %s restored from %s\n", cfg.SQLitePath(), args[0])
+	return nil
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 以下响应结构与服务端一致，客户端不引用服务端的包，避免链接数据库驱动
+
+// backupFile 备份文件
+type backupFile struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// syncItem Git 同步中单个任务文件的结果
+type syncItem struct {
+	Path    string   `json:"path"`
+	Name    string   `json:"name"`
+	Action  string   `json:"action"`
+	Fields  []string `json:"fields"`
+	Message string   `json:"message"`
+}
+
+// syncResult Git 同步或漂移检查结果
+type syncResult struct {
+	Revision string     `json:"revision"`
+	Items    []syncItem `json:"items"`
+}
+
+// importItem 导入中单个对象的结果
+type importItem struct {
+	Name     string `json:"name"`
+	Action   string `json:"action"`
+	Conflict bool   `json:"conflict"`
+	NewName  string `json:"new_name"`
+	Message  string `json:"message"`
+}
+
+// importReport 导入报告
+type importReport struct {
+	DryRun  bool         `json:"dry_run"`
+	Servers []importItem `json:"bark_servers"`
+	Devices []importItem `json:"bark_devices"`
+	Groups  []importItem `json:"bark_groups"`
+	Tasks   []importItem `json:"tasks"`
+}
+
+// runAdmin 管理命令
+func runAdmin(a *app, args []string) error {
+	return subcommand(a, "admin", args, map[string]func(a *app, args []string) error{
+		"scheduler": adminScheduler,
+		"cluster":   adminCluster,
+		"config":    adminConfig,
+		"backups":   adminBackups,
+		"gitsync":   adminGitSync,
+	})
+}
+
+func adminScheduler(a *app, args []string) error {
+	positional, err := parseFlags(a.newFlags("admin scheduler"), args)
+	if err != nil {
+		return err
+	}
+
+	if len(positional) == 1 && (positional[0] == "pause" || positional[0] == "resume") {
+		var resp struct {
+			Message string `json:"message"`
+		}
+		data, err := a.call("POST", "/api/admin/scheduler/"+positional[0], nil, nil, &resp)
+		if err != nil {
+			return err
+		}
+		return a.render(data, func() { fmt.Println(resp.Message) })
+	}
+	if len(positional) != 0 {
+		return fmt.Errorf("usage: autobotctl admin scheduler [pause|resume]")
+	}
+
+	var resp struct {
+		Paused         bool `json:"paused"`
+		Running        bool `json:"running"`
+		ScheduledTasks int  `json:"scheduled_tasks"`
+	}
+	data, err := a.call("GET", "/api/admin/scheduler", nil, nil, &resp)
+	if err != nil {
+		return err
+	}
+	return a.render(data, func() {
+		printFields([][2]string{
+			{"Paused", strconv.FormatBool(resp.Paused)},
+			{"Running", strconv.FormatBool(resp.Running)},
+			{"Scheduled tasks", strconv.Itoa(resp.ScheduledTasks)},
+		})
+	})
+}
+
+func adminCluster(a *app, args []string) error {
+	if _, err := parseFlags(a.newFlags("admin cluster"), args); err != nil {
+		return err
+	}
+
+	var resp struct {
+		InstanceID     string     `json:"instance_id"`
+		IsLeader       bool       `json:"is_leader"`
+		LeaderID       string     `json:"leader_id"`
+		LeaseExpiresAt *time.Time `json:"lease_expires_at"`
+	}
+	data, err := a.call("GET", "/api/admin/cluster", nil, nil, &resp)
+	if err != nil {
+		return err
+	}
+	return a.render(data, func() {
+		printFields([][2]string{
+			{"Instance", resp.InstanceID},
+			{"Leader", orDash(resp.LeaderID)},
+			{"Is leader", strconv.FormatBool(resp.IsLeader)},
+			{"Lease expires", formatTime(resp.LeaseExpiresAt)},
+		})
+	})
+}
+
+func adminConfig(a *app, args []string) error {
+	if _, err := parseFlags(a.newFlags("admin config"), args); err != nil {
+		return err
+	}
+
+	// 配置结构层级较深，两种格式都输出 JSON
+	data, err := a.call("GET", "/api/admin/config", nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	return printJSON(data)
+}
+
+func adminBackups(a *app, args []string) error {
+	positional, err := parseFlags(a.newFlags("admin backups"), args)
+	if err != nil {
+		return err
+	}
+
+	if len(positional) == 1 && positional[0] == "create" {
+		var file backupFile
+		data, err := a.call("POST", "/api/admin/backups", nil, nil, &file)
+		if err != nil {
+			return err
+		}
+		return a.render(data, func() {
+			fmt.Printf("Backup created: %s (%d bytes)\n", file.Name, file.Size)
+		})
+	}
+	if len(positional) != 0 {
+		return fmt.Errorf("usage: autobotctl admin backups [create]")
+	}
+
+	var resp struct {
+		Backups []backupFile `json:"backups"`
+		Dir     string       `json:"dir"`
+	}
+	data, err := a.call("GET", "/api/admin/backups", nil, nil, &resp)
+	if err != nil {
+		return err
+	}
+	return a.render(data, func() {
+		rows := make([][]string, 0, len(resp.Backups))
+		for _, file := range resp.Backups {
+			rows = append(rows, []string{file.Name, strconv.FormatInt(file.Size, 10), formatTime(&file.CreatedAt)})
+		}
+		printTable([]string{"NAME", "SIZE", "CREATED"}, rows)
+	})
+}
+
+func adminGitSync(a *app, args []string) error {
+	positional, err := parseFlags(a.newFlags("admin gitsync"), args)
+	if err != nil {
+		return err
+	}
+
+	var result *syncResult
+	var data []byte
+	switch {
+	case len(positional) == 1 && positional[0] == "sync":
+		result = &syncResult{}
+		data, err = a.call("POST", "/api/admin/gitsync/sync", nil, nil, result)
+	case len(positional) == 0:
+		var resp struct {
+			Enabled bool        `json:"enabled"`
+			Drift   *syncResult `json:"drift"`
+		}
+		data, err = a.call("GET", "/api/admin/gitsync", nil, nil, &resp)
+		if err == nil && !resp.Enabled && a.output == "table" {
+			fmt.Println("Git sync is not enabled")
+			return nil
+		}
+		result = resp.Drift
+	default:
+		return fmt.Errorf("usage: autobotctl admin gitsync [sync]")
+	}
+	if err != nil {
+		return err
+	}
+
+	return a.render(data, func() {
+		fmt.Printf("Revision: %s\n\n", orDash(result.Revision))
+		rows := make([][]string, 0, len(result.Items))
+		for _, item := range result.Items {
+			rows = append(rows, []string{
+				item.Path,
+				orDash(item.Name),
+				item.Action,
+				orDash(strings.Join(item.Fields, ",")),
+				orDash(item.Message),
+			})
+		}
+		printTable([]string{"PATH", "TASK", "ACTION", "FIELDS", "MESSAGE"}, rows)
+	})
+}
+
+// runExport 导出任务包
+func runExport(a *app, args []string) error {
+	fs := a.newFlags("export")
+	format := fs.String("format", "yaml", "格式：yaml 或 json")
+	tasks := fs.String("tasks", "", "任务ID，逗号分隔，默认全部")
+	file := fs.String("file", "", "输出文件，默认标准输出")
+	includeSecrets := fs.Bool("include-secrets", false, "包含设备的加密密钥和 IV")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	query := url.Values{"format": {*format}}
+	if *tasks != "" {
+		query.Set("task_ids", *tasks)
+	}
+	if *includeSecrets {
+		query.Set("include_secrets", "true")
+	}
+	data, err := a.request("GET", "/api/export", query, nil, "")
+	if err != nil {
+		return err
+	}
+	if *file == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	// 包含密钥时只允许当前用户读取
+	perm := os.FileMode(0644)
+	if *includeSecrets {
+		perm = 0600
+	}
+	if err := os.WriteFile(*file, data, perm); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Exported to %s\n", *file)
+	return nil
+}
+
+// runImport 导入任务包
+func runImport(a *app, args []string) error {
+	fs := a.newFlags("import")
+	dryRun := fs.Bool("dry-run", false, "只报告冲突，不写入")
+	onConflict := fs.String("on-conflict", "skip", "冲突处理：skip, overwrite, rename")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: autobotctl import FILE [-dry-run] [-on-conflict skip|overwrite|rename]")
+	}
+
+	content, err := readInput(positional[0])
+	if err != nil {
+		return err
+	}
+	query := url.Values{
+		"dry_run":     {strconv.FormatBool(*dryRun)},
+		"on_conflict": {*onConflict},
+		"format":      {detectFormat(content)},
+	}
+
+	data, err := a.request("POST", "/api/import", query, strings.NewReader(content), "")
+	// 有无法导入的内容时服务端返回 422 和报告
+	var report importReport
+	if apiErr, ok := err.(*apiError); ok && apiErr.Status == 422 {
+		var resp struct {
+			Report importReport `json:"report"`
+		}
+		if jsonErr := json.Unmarshal(data, &resp); jsonErr == nil {
+			printImportReport(a, data, &resp.Report)
+		}
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return err
+	}
+	printImportReport(a, data, &report)
+	return nil
+}
+
+// detectFormat 根据内容判断任务包格式
+func detectFormat(content string) string {
+	if strings.HasPrefix(strings.TrimSpace(content), "{") {
+		return "json"
+	}
+	return "yaml"
+}
+
+// printImportReport 输出导入报告
+func printImportReport(a *app, data []byte, report *importReport) {
+	a.render(data, func() {
+		var rows [][]string
+		add := func(kind string, items []importItem) {
+			for _, item := range items {
+				name := item.Name
+				if item.NewName != "" {
+					name += " -> " + item.NewName
+				}
+				rows = append(rows, []string{kind, name, item.Action, strconv.FormatBool(item.Conflict), orDash(item.Message)})
+			}
+		}
+		add("server", report.Servers)
+		add("device", report.Devices)
+		add("group", report.Groups)
+		add("task", report.Tasks)
+		printTable([]string{"KIND", "NAME", "ACTION", "CONFLICT", "MESSAGE"}, rows)
+		if report.DryRun {
+			fmt.Println("\nDry run, nothing was changed")
+		}
+	})
+}
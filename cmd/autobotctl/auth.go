@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// apiToken API 令牌
+type apiToken struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// createTokenResponse 创建令牌的响应，明文令牌只返回这一次
+type createTokenResponse struct {
+	Token    string   `json:"token"`
+	APIToken apiToken `json:"api_token"`
+}
+
+// runLogin 用户名密码登录，创建 API 令牌并保存到配置文件
+func runLogin(a *app, args []string) error {
+	fs := a.newFlags("login")
+	server := fs.String("server", a.server, "autobot 地址")
+	username := fs.String("username", "", "用户名")
+	password := fs.String("password", os.Getenv("AUTOBOT_PASSWORD"), "密码（AUTOBOT_PASSWORD），为空时从标准输入读取")
+	expires := fs.String("expires", "", "令牌有效期，例如 720h；为空表示不过期")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *username == "" {
+		return fmt.Errorf("usage: autobotctl login -server URL -username NAME [-password PASSWORD] [-expires 720h]")
+	}
+	if *password == "" {
+		fmt.Fprint(os.Stderr, "Password: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return fmt.Errorf("failed to read password: %v", err)
+		}
+		*password = strings.TrimRight(line, "\r\n")
+	}
+
+	// 登录接口使用会话 cookie，用它创建令牌后不再需要
+	a.server = strings.TrimRight(*server, "/")
+	a.token = ""
+	jar, _ := cookiejar.New(nil)
+	a.http = &http.Client{Timeout: a.http.Timeout, Jar: jar}
+
+	login := map[string]string{"username": *username, "password": *password}
+	if _, err := a.call("POST", "/login", nil, login, nil); err != nil {
+		return err
+	}
+
+	hostname, _ := os.Hostname()
+	req := map[string]string{"name": "autobotctl@" + hostname, "expires_in": *expires}
+	var resp createTokenResponse
+	if _, err := a.call("POST", "/api/tokens", nil, req, &resp); err != nil {
+		return err
+	}
+
+	a.token = resp.Token
+	if err := a.saveConfig(); err != nil {
+		return fmt.Errorf("failed to save config: %v", err)
+	}
+	fmt.Printf("Logged in to %s, token saved to %s\n", a.server, a.configPath)
+	return nil
+}
+
+// runLogout 吊销当前令牌并从配置文件中删除
+func runLogout(a *app, args []string) error {
+	if _, err := parseFlags(a.newFlags("logout"), args); err != nil {
+		return err
+	}
+	if a.token == "" {
+		return fmt.Errorf("not logged in")
+	}
+
+	// 按前缀找到当前令牌并吊销
+	var resp struct {
+		Tokens []apiToken `json:"tokens"`
+	}
+	if _, err := a.call("GET", "/api/tokens", nil, nil, &resp); err == nil {
+		for _, token := range resp.Tokens {
+			if strings.HasPrefix(a.token, token.Prefix) {
+				if _, err := a.call("DELETE", "/api/tokens/"+strconv.Itoa(int(token.ID)), nil, nil, nil); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+
+	a.token = ""
+	if err := a.saveConfig(); err != nil {
+		return fmt.Errorf("failed to save config: %v", err)
+	}
+	fmt.Println("Logged out")
+	return nil
+}
+
+// runTokens API 令牌管理
+func runTokens(a *app, args []string) error {
+	return subcommand(a, "tokens", args, map[string]func(a *app, args []string) error{
+		"list":   tokensList,
+		"create": tokensCreate,
+		"revoke": tokensRevoke,
+	})
+}
+
+func tokensList(a *app, args []string) error {
+	if _, err := parseFlags(a.newFlags("tokens list"), args); err != nil {
+		return err
+	}
+
+	var resp struct {
+		Tokens []apiToken `json:"tokens"`
+	}
+	data, err := a.call("GET", "/api/tokens", nil, nil, &resp)
+	if err != nil {
+		return err
+	}
+	return a.render(data, func() {
+		rows := make([][]string, 0, len(resp.Tokens))
+		for _, token := range resp.Tokens {
+			rows = append(rows, []string{
+				strconv.Itoa(int(token.ID)),
+				token.Name,
+				token.Prefix + "…",
+				formatTime(&token.CreatedAt),
+				formatTime(token.LastUsedAt),
+				formatTime(token.ExpiresAt),
+			})
+		}
+		printTable([]string{"ID", "NAME", "TOKEN", "CREATED", "LAST USED", "EXPIRES"}, rows)
+	})
+}
+
+func tokensCreate(a *app, args []string) error {
+	fs := a.newFlags("tokens create")
+	name := fs.String("name", "", "令牌名称")
+	expires := fs.String("expires", "", "有效期，例如 720h；为空表示不过期")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *name == "" {
+		return fmt.Errorf("usage: autobotctl tokens create -name NAME [-expires 720h]")
+	}
+
+	var resp createTokenResponse
+	data, err := a.call("POST", "/api/tokens", nil, map[string]string{"name": *name, "expires_in": *expires}, &resp)
+	if err != nil {
+		return err
+	}
+	return a.render(data, func() {
+		fmt.Printf("Token %d created, it will not be shown again:\n%s\n", resp.APIToken.ID, resp.Token)
+	})
+}
+
+func tokensRevoke(a *app, args []string) error {
+	positional, err := parseFlags(a.newFlags("tokens revoke"), args)
+	if err != nil {
+		return err
+	}
+	id, err := idArg(positional, "tokens revoke ID")
+	if err != nil {
+		return err
+	}
+
+	data, err := a.call("DELETE", "/api/tokens/"+id, nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	return a.render(data, func() {
+		fmt.Printf("Token %s revoked\n", id)
+	})
+}
@@ -0,0 +1,381 @@
+package main
+
+import (
+	"autobot/internal/models"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// runDevices Bark 设备命令
+func runDevices(a *app, args []string) error {
+	return subcommand(a, "devices", args, map[string]func(a *app, args []string) error{
+		"list":   devicesList,
+		"add":    devicesAdd,
+		"delete": devicesDelete,
+		"test":   devicesTest,
+	})
+}
+
+// runServers Bark 服务器命令
+func runServers(a *app, args []string) error {
+	return subcommand(a, "servers", args, map[string]func(a *app, args []string) error{
+		"list":  serversList,
+		"check": serversCheck,
+	})
+}
+
+func devicesList(a *app, args []string) error {
+	fs := a.newFlags("devices list")
+	limit := fs.Int("limit", 100, "数量")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	var resp struct {
+		Devices []models.BarkDevice `json:"devices"`
+	}
+	data, err := a.call("GET", "/api/bark/devices", url.Values{"limit": {strconv.Itoa(*limit)}}, nil, &resp)
+	if err != nil {
+		return err
+	}
+	return a.render(data, func() {
+		rows := make([][]string, 0, len(resp.Devices))
+		for _, device := range resp.Devices {
+			rows = append(rows, []string{
+				strconv.Itoa(int(device.ID)),
+				device.Name,
+				maskKey(device.DeviceKey),
+				orDash(device.Server.Name),
+				strconv.FormatBool(device.IsDefault),
+				device.Status,
+			})
+		}
+		printTable([]string{"ID", "NAME", "KEY", "SERVER", "DEFAULT", "STATUS"}, rows)
+	})
+}
+
+func devicesAdd(a *app, args []string) error {
+	fs := a.newFlags("devices add")
+	var req models.CreateBarkDeviceRequest
+	fs.StringVar(&req.Name, "name", "", "设备名称")
+	fs.StringVar(&req.DeviceKey, "key", "", "设备密钥")
+	fs.StringVar(&req.Description, "description", "", "描述")
+	serverID := fs.Uint("server-id", 0, "服务器ID，0 表示默认服务器")
+	fs.BoolVar(&req.IsDefault, "default", false, "设为默认设备")
+	fs.StringVar(&req.EncryptionAlgorithm, "encrypt", "", "推送加密算法：aes128, aes192, aes256")
+	fs.StringVar(&req.EncryptionMode, "encrypt-mode", models.BarkEncryptionCBC, "加密模式：cbc, ecb, gcm")
+	fs.StringVar(&req.EncryptionKey, "encrypt-key", "", "加密密钥")
+	fs.StringVar(&req.EncryptionIV, "encrypt-iv", "", "固定 IV，留空则每次推送随机生成")
+	fs.IntVar(&req.RateLimitHour, "rate-hour", 0, "每小时最多推送数，0 表示不限")
+	fs.IntVar(&req.RateLimitDay, "rate-day", 0, "每天最多推送数，0 表示不限")
+	quiet := fs.String("quiet", "", "免打扰时段，例如 22:00-07:00")
+	fs.StringVar(&req.QuietMode, "quiet-mode", "", "免打扰处理方式：passive, delay")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if req.Name == "" || req.DeviceKey == "" {
+		return fmt.Errorf("usage: autobotctl devices add -name NAME -key KEY [-server-id ID] [-description TEXT] [-default] [-encrypt ALG -encrypt-key KEY [-encrypt-mode MODE] [-encrypt-iv IV]] [-rate-hour N] [-rate-day N] [-quiet HH:MM-HH:MM [-quiet-mode MODE]]")
+	}
+	if *quiet != "" {
+		start, end, ok := strings.Cut(*quiet, "-")
+		if !ok {
+			return fmt.Errorf("invalid -quiet %q, expected HH:MM-HH:MM", *quiet)
+		}
+		req.QuietStart, req.QuietEnd = strings.TrimSpace(start), strings.TrimSpace(end)
+	}
+	if req.EncryptionAlgorithm == "" {
+		req.EncryptionMode = ""
+	}
+	req.ServerID = *serverID
+
+	var device models.BarkDevice
+	data, err := a.call("POST", "/api/bark/devices", nil, req, &device)
+	if err != nil {
+		return err
+	}
+	return a.render(data, func() {
+		fmt.Printf("Device %d created: %s\n", device.ID, device.Name)
+	})
+}
+
+func devicesDelete(a *app, args []string) error {
+	positional, err := parseFlags(a.newFlags("devices delete"), args)
+	if err != nil {
+		return err
+	}
+	id, err := idArg(positional, "devices delete ID")
+	if err != nil {
+		return err
+	}
+
+	data, err := a.call("DELETE", "/api/bark/devices/"+id, nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	return a.render(data, func() {
+		fmt.Printf("Device %s deleted\n", id)
+	})
+}
+
+func devicesTest(a *app, args []string) error {
+	positional, err := parseFlags(a.newFlags("devices test"), args)
+	if err != nil {
+		return err
+	}
+	id, err := idArg(positional, "devices test ID")
+	if err != nil {
+		return err
+	}
+
+	var resp struct {
+		Message string `json:"message"`
+	}
+	data, err := a.call("POST", "/api/bark/devices/"+id+"/test", nil, nil, &resp)
+	if err != nil {
+		return err
+	}
+	return a.render(data, func() {
+		fmt.Printf("Device %s: %s\n", id, resp.Message)
+	})
+}
+
+func serversList(a *app, args []string) error {
+	fs := a.newFlags("servers list")
+	limit := fs.Int("limit", 100, "数量")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	var resp struct {
+		Servers []models.BarkServer `json:"servers"`
+	}
+	data, err := a.call("GET", "/api/bark/servers", url.Values{"limit": {strconv.Itoa(*limit)}}, nil, &resp)
+	if err != nil {
+		return err
+	}
+	return a.render(data, func() {
+		rows := make([][]string, 0, len(resp.Servers))
+		for _, server := range resp.Servers {
+			rows = append(rows, []string{
+				strconv.Itoa(int(server.ID)),
+				server.Name,
+				server.URL,
+				strconv.FormatBool(server.IsDefault),
+				server.Status,
+				serverHealth(&server),
+			})
+		}
+		printTable([]string{"ID", "NAME", "URL", "DEFAULT", "STATUS", "HEALTH"}, rows)
+	})
+}
+
+func serversCheck(a *app, args []string) error {
+	positional, err := parseFlags(a.newFlags("servers check"), args)
+	if err != nil {
+		return err
+	}
+	id, err := idArg(positional, "servers check ID")
+	if err != nil {
+		return err
+	}
+
+	var server models.BarkServer
+	data, err := a.call("POST", "/api/bark/servers/"+id+"/check", nil, nil, &server)
+	if err != nil {
+		return err
+	}
+	return a.render(data, func() {
+		fmt.Printf("Server %s: %s\n", server.Name, serverHealth(&server))
+		if server.HealthError != "" {
+			fmt.Printf("Last error: %s\n", server.HealthError)
+		}
+	})
+}
+
+// serverHealth 健康状态，健康时附带响应时间
+func serverHealth(server *models.BarkServer) string {
+	switch server.Health {
+	case models.ServerHealthHealthy:
+		return fmt.Sprintf("healthy (%dms)", server.HealthLatency)
+	case "":
+		return models.ServerHealthUnknown
+	}
+	return server.Health
+}
+
+// runOutbox Bark 投递队列命令
+func runOutbox(a *app, args []string) error {
+	return subcommand(a, "outbox", args, map[string]func(a *app, args []string) error{
+		"list":   outboxList,
+		"resend": outboxResend,
+	})
+}
+
+func outboxList(a *app, args []string) error {
+	fs := a.newFlags("outbox list")
+	status := fs.String("status", "", "状态筛选：pending, sending, dead")
+	limit := fs.Int("limit", 50, "数量")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	query := url.Values{"limit": {strconv.Itoa(*limit)}}
+	if *status != "" {
+		query.Set("status", *status)
+	}
+	var resp struct {
+		Entries []models.BarkOutbox `json:"entries"`
+	}
+	data, err := a.call("GET", "/api/bark/outbox", query, nil, &resp)
+	if err != nil {
+		return err
+	}
+	return a.render(data, func() {
+		rows := make([][]string, 0, len(resp.Entries))
+		for _, entry := range resp.Entries {
+			rows = append(rows, []string{
+				strconv.Itoa(int(entry.RecordID)),
+				strconv.Itoa(int(entry.TaskID)),
+				maskKey(entry.DeviceKey),
+				entry.Status,
+				strconv.Itoa(entry.Attempts),
+				formatTime(&entry.NextAttemptAt),
+				orDash(truncate(entry.LastError, 60)),
+			})
+		}
+		printTable([]string{"RECORD", "TASK", "KEY", "STATUS", "ATTEMPTS", "NEXT", "ERROR"}, rows)
+	})
+}
+
+func outboxResend(a *app, args []string) error {
+	positional, err := parseFlags(a.newFlags("outbox resend"), args)
+	if err != nil {
+		return err
+	}
+	id, err := idArg(positional, "outbox resend RECORD_ID")
+	if err != nil {
+		return err
+	}
+
+	var resp struct {
+		Message string            `json:"message"`
+		Record  models.BarkRecord `json:"record"`
+	}
+	data, err := a.call("POST", "/api/bark/records/"+id+"/resend", nil, nil, &resp)
+	if err != nil {
+		return err
+	}
+	return a.render(data, func() {
+		fmt.Printf("Record %s: %s (%s)\n", id, resp.Message, resp.Record.Status)
+	})
+}
+
+// runRecords Bark 发送记录命令
+func runRecords(a *app, args []string) error {
+	return subcommand(a, "records", args, map[string]func(a *app, args []string) error{
+		"list":   recordsList,
+		"export": recordsExport,
+	})
+}
+
+// recordFlags 发送记录的筛选参数，与 API 的查询参数同名
+type recordFlags struct {
+	task, device, status, from, to, query *string
+}
+
+func newRecordFlags(fs *flag.FlagSet) *recordFlags {
+	return &recordFlags{
+		task:   fs.String("task", "", "任务ID"),
+		device: fs.String("device", "", "设备ID"),
+		status: fs.String("status", "", "状态，逗号分隔：success, failed, skipped 等"),
+		from:   fs.String("from", "", "开始日期 YYYY-MM-DD 或 RFC3339 时间"),
+		to:     fs.String("to", "", "结束日期（包含当天）或 RFC3339 时间"),
+		query:  fs.String("q", "", "搜索标题和内容"),
+	}
+}
+
+func (f *recordFlags) values() url.Values {
+	query := url.Values{}
+	for name, value := range map[string]string{
+		"task_id": *f.task, "device_id": *f.device, "status": *f.status,
+		"from": *f.from, "to": *f.to, "q": *f.query,
+	} {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+	return query
+}
+
+func recordsList(a *app, args []string) error {
+	fs := a.newFlags("records list")
+	filter := newRecordFlags(fs)
+	limit := fs.Int("limit", 20, "数量，最多 100")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	query := filter.values()
+	query.Set("page_size", strconv.Itoa(*limit))
+	var resp struct {
+		Records []models.BarkRecord `json:"records"`
+		Total   int64               `json:"total"`
+	}
+	data, err := a.call("GET", "/api/bark/records", query, nil, &resp)
+	if err != nil {
+		return err
+	}
+	return a.render(data, func() {
+		rows := make([][]string, 0, len(resp.Records))
+		for _, record := range resp.Records {
+			rows = append(rows, []string{
+				strconv.Itoa(int(record.ID)),
+				formatTime(&record.CreatedAt),
+				strconv.Itoa(int(record.TaskID)),
+				orDash(maskKey(record.DeviceKey)),
+				record.Status,
+				orDash(truncate(record.Title, 40)),
+			})
+		}
+		printTable([]string{"ID", "TIME", "TASK", "KEY", "STATUS", "TITLE"}, rows)
+		fmt.Printf("%d of %d records\n", len(resp.Records), resp.Total)
+	})
+}
+
+func recordsExport(a *app, args []string) error {
+	fs := a.newFlags("records export")
+	filter := newRecordFlags(fs)
+	format := fs.String("format", "csv", "格式：csv 或 json")
+	file := fs.String("file", "", "输出文件，默认标准输出")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	query := filter.values()
+	query.Set("format", *format)
+	data, err := a.request("GET", "/api/bark/records/export", query, nil, "")
+	if err != nil {
+		return err
+	}
+	if *file == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(*file, data, 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Exported to %s\n", *file)
+	return nil
+}
+
+// maskKey 表格中只显示设备密钥的首尾
+func maskKey(key string) string {
+	if len(key) <= 8 {
+		return key
+	}
+	return key[:4] + "…" + key[len(key)-4:]
+}
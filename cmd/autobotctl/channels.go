@@ -0,0 +1,114 @@
+package main
+
+import (
+	"autobot/internal/models"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// runChannels 通知渠道命令
+func runChannels(a *app, args []string) error {
+	return subcommand(a, "channels", args, map[string]func(a *app, args []string) error{
+		"list":   channelsList,
+		"add":    channelsAdd,
+		"delete": channelsDelete,
+		"test":   channelsTest,
+	})
+}
+
+func channelsList(a *app, args []string) error {
+	if _, err := parseFlags(a.newFlags("channels list"), args); err != nil {
+		return err
+	}
+
+	var resp struct {
+		Channels []models.NotificationChannel `json:"channels"`
+	}
+	data, err := a.call("GET", "/api/channels", nil, nil, &resp)
+	if err != nil {
+		return err
+	}
+	return a.render(data, func() {
+		rows := make([][]string, 0, len(resp.Channels))
+		for _, ch := range resp.Channels {
+			rows = append(rows, []string{
+				strconv.Itoa(int(ch.ID)),
+				ch.Name,
+				ch.Type,
+				ch.Status,
+				truncate(orDash(ch.Description), 40),
+			})
+		}
+		printTable([]string{"ID", "NAME", "TYPE", "STATUS", "DESCRIPTION"}, rows)
+	})
+}
+
+func channelsAdd(a *app, args []string) error {
+	fs := a.newFlags("channels add")
+	var req models.CreateChannelRequest
+	fs.StringVar(&req.Name, "name", "", "渠道名称")
+	fs.StringVar(&req.Type, "type", "", "渠道类型：webhook, email, telegram, slack, dingtalk, wecom, feishu, ntfy, gotify")
+	fs.StringVar(&req.Config, "config", "", "渠道配置 JSON，以 @ 开头时从文件读取")
+	fs.StringVar(&req.Description, "description", "", "描述")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if req.Name == "" || req.Type == "" || req.Config == "" {
+		return fmt.Errorf("usage: autobotctl channels add -name NAME -type TYPE -config JSON|@FILE [-description TEXT]")
+	}
+	if req.Config[0] == '@' {
+		data, err := os.ReadFile(req.Config[1:])
+		if err != nil {
+			return err
+		}
+		req.Config = string(data)
+	}
+
+	var ch models.NotificationChannel
+	data, err := a.call("POST", "/api/channels", nil, req, &ch)
+	if err != nil {
+		return err
+	}
+	return a.render(data, func() {
+		fmt.Printf("Channel %d created\n", ch.ID)
+	})
+}
+
+func channelsDelete(a *app, args []string) error {
+	positional, err := parseFlags(a.newFlags("channels delete"), args)
+	if err != nil {
+		return err
+	}
+	id, err := idArg(positional, "channels delete ID")
+	if err != nil {
+		return err
+	}
+
+	data, err := a.call("DELETE", "/api/channels/"+id, nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	return a.render(data, func() {
+		fmt.Printf("Channel %s deleted\n", id)
+	})
+}
+
+func channelsTest(a *app, args []string) error {
+	positional, err := parseFlags(a.newFlags("channels test"), args)
+	if err != nil {
+		return err
+	}
+	id, err := idArg(positional, "channels test ID")
+	if err != nil {
+		return err
+	}
+
+	data, err := a.call("POST", "/api/channels/"+id+"/test", nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	return a.render(data, func() {
+		fmt.Printf("Test notification sent through channel %s\n", id)
+	})
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// clientConfig 保存在配置文件中的连接信息
+type clientConfig struct {
+	Server string `json:"server"`
+	Token  string `json:"token"`
+}
+
+// app 命令执行上下文
+type app struct {
+	configPath string
+	output     string // table, json
+	server     string
+	token      string
+	http       *http.Client
+}
+
+// defaultConfigPath 默认配置文件路径：$XDG_CONFIG_HOME/autobot/autobotctl.json
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "autobotctl.json"
+	}
+	return filepath.Join(dir, "autobot", "autobotctl.json")
+}
+
+// loadConfig 加载连接信息，优先级：配置文件 < 环境变量 < 命令行参数
+func (a *app) loadConfig(server, token string) error {
+	if a.output != "table" && a.output != "json" {
+		return fmt.Errorf("invalid output format: %s (use table or json)", a.output)
+	}
+	if a.configPath == "" {
+		a.configPath = defaultConfigPath()
+	}
+	a.http = &http.Client{Timeout: 60 * time.Second}
+
+	if data, err := os.ReadFile(a.configPath); err == nil {
+		var cfg clientConfig
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return fmt.Errorf("invalid config file %s: %v", a.configPath, err)
+		}
+		a.server, a.token = cfg.Server, cfg.Token
+	}
+
+	if value := os.Getenv("AUTOBOT_SERVER"); value != "" {
+		a.server = value
+	}
+	if value := os.Getenv("AUTOBOT_TOKEN"); value != "" {
+		a.token = value
+	}
+	if server != "" {
+		a.server = server
+	}
+	if token != "" {
+		a.token = token
+	}
+	if a.server == "" {
+		a.server = "http://localhost:8080"
+	}
+	a.server = strings.TrimRight(a.server, "/")
+	return nil
+}
+
+// saveConfig 保存连接信息，文件只对当前用户可读
+func (a *app) saveConfig() error {
+	data, err := json.MarshalIndent(clientConfig{Server: a.server, Token: a.token}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(a.configPath), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(a.configPath, data, 0600)
+}
+
+// apiError 服务端返回的错误
+type apiError struct {
+	Status  int
+	Message string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%s (HTTP %d)", e.Message, e.Status)
+}
+
+// request 发送请求并返回响应体，非 2xx 时返回 apiError
+func (a *app) request(method, path string, query url.Values, body io.Reader, contentType string) ([]byte, error) {
+	u := a.server + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if a.token != "" {
+		req.Header.Set("Authorization", "Bearer "+a.token)
+	}
+
+	resp, err := a.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		var payload struct {
+			Error string `json:"error"`
+		}
+		message := strings.TrimSpace(string(data))
+		if json.Unmarshal(data, &payload) == nil && payload.Error != "" {
+			message = payload.Error
+		}
+		if resp.StatusCode == http.StatusUnauthorized && a.token == "" {
+			message += "; run `autobotctl login` or set AUTOBOT_TOKEN"
+		}
+		return data, &apiError{Status: resp.StatusCode, Message: message}
+	}
+	return data, nil
+}
+
+// call 发送 JSON 请求，out 不为 nil 时解析响应
+func (a *app) call(method, path string, query url.Values, in interface{}, out interface{}) ([]byte, error) {
+	var body io.Reader
+	contentType := ""
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+		contentType = "application/json"
+	}
+
+	data, err := a.request(method, path, query, body, contentType)
+	if err != nil {
+		return data, err
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return data, fmt.Errorf("unexpected response: %v", err)
+		}
+	}
+	return data, nil
+}
+
+// newFlags 创建子命令参数，统一支持 -o
+func (a *app) newFlags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.StringVar(&a.output, "o", a.output, "输出格式：table 或 json")
+	return fs
+}
+
+// parseFlags 解析子命令参数，允许参数出现在位置参数之后
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
@@ -0,0 +1,112 @@
+// autobotctl autobot 命令行客户端，通过 HTTP API 管理任务、Bark 设备、通知渠道和实例
+//
+// 鉴权使用 API 令牌：先执行 autobotctl login 用用户名密码换取令牌并保存到配置文件，
+// 也可以用 -token 或 AUTOBOT_TOKEN 直接指定。
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// command 子命令
+type command struct {
+	usage string
+	run   func(a *app, args []string) error
+}
+
+// commands 全部子命令，分组命令在各自的处理函数中继续分发
+var commands = map[string]command{
+	"login":    {"login -server URL -username NAME   登录并保存 API 令牌", runLogin},
+	"logout":   {"logout                             吊销并删除保存的令牌", runLogout},
+	"tasks":    {"tasks list|show|create|edit|delete|run|logs|tail", runTasks},
+	"devices":  {"devices list|add|delete|test        Bark 设备管理", runDevices},
+	"servers":  {"servers list|check                  Bark 服务器列表与健康检查", runServers},
+	"outbox":   {"outbox list|resend                  Bark 投递队列与失败重发", runOutbox},
+	"records":  {"records list|export                 Bark 发送记录搜索与导出", runRecords},
+	"channels": {"channels list|add|delete|test       通知渠道管理", runChannels},
+	"export":   {"export [-format yaml|json] [-tasks 1,2] [-file FILE] [-include-secrets]", runExport},
+	"import":   {"import FILE [-dry-run] [-on-conflict skip|overwrite|rename]", runImport},
+	"admin":    {"admin scheduler|cluster|config|backups|gitsync", runAdmin},
+	"tokens":   {"tokens list|create|revoke            API 令牌管理", runTokens},
+	"status":   {"status                             实例版本、调度条目和正在运行的执行", runStatus},
+}
+
+func main() {
+	a := &app{}
+
+	fs := flag.NewFlagSet("autobotctl", flag.ContinueOnError)
+	fs.StringVar(&a.configPath, "config", os.Getenv("AUTOBOT_CTL_CONFIG"), "客户端配置文件路径")
+	server := fs.String("server", "", "autobot 地址，例如 http://localhost:8080（AUTOBOT_SERVER）")
+	token := fs.String("token", "", "API 令牌（AUTOBOT_TOKEN）")
+	fs.StringVar(&a.output, "o", "table", "输出格式：table 或 json")
+	fs.Usage = func() { usage(fs) }
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		os.Exit(2)
+	}
+
+	if err := a.loadConfig(*server, *token); err != nil {
+		fatal(err)
+	}
+
+	args := fs.Args()
+	if len(args) == 0 {
+		usage(fs)
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n\n", args[0])
+		usage(fs)
+		os.Exit(2)
+	}
+	if err := cmd.run(a, args[1:]); err != nil {
+		fatal(err)
+	}
+}
+
+// usage 输出帮助信息
+func usage(fs *flag.FlagSet) {
+	fmt.Fprintln(os.Stderr, "Usage: autobotctl [-server URL] [-token TOKEN] [-o table|json] <command> [args]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %s\n", commands[name].usage)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Flags:")
+	fs.PrintDefaults()
+}
+
+// subcommand 分发分组命令，例如 tasks list
+func subcommand(a *app, group string, args []string, subs map[string]func(a *app, args []string) error) error {
+	names := make([]string, 0, len(subs))
+	for name := range subs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if len(args) == 0 {
+		return fmt.Errorf("usage: autobotctl %s %s", group, strings.Join(names, "|"))
+	}
+	run, ok := subs[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown %s command: %s (available: %s)", group, args[0], strings.Join(names, ", "))
+	}
+	return run(a, args[1:])
+}
+
+// fatal 输出错误并退出
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "Error:", err)
+	os.Exit(1)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// instanceStatus /api/status 的响应
+type instanceStatus struct {
+	Version    string    `json:"version"`
+	Revision   string    `json:"revision"`
+	InstanceID string    `json:"instance_id"`
+	IsLeader   bool      `json:"is_leader"`
+	StartedAt  time.Time `json:"started_at"`
+	Uptime     string    `json:"uptime"`
+	Scheduler  struct {
+		Running bool `json:"running"`
+		Paused  bool `json:"paused"`
+		Entries []struct {
+			TaskID   uint      `json:"task_id"`
+			TaskName string    `json:"task_name"`
+			CronExpr string    `json:"cron_expr"`
+			NextRun  time.Time `json:"next_run"`
+		} `json:"entries"`
+	} `json:"scheduler"`
+	RunningExecutions []struct {
+		LogID     uint      `json:"log_id"`
+		TaskID    uint      `json:"task_id"`
+		TaskName  string    `json:"task_name"`
+		StartedAt time.Time `json:"started_at"`
+	} `json:"running_executions"`
+	LastError *struct {
+		TaskID   uint      `json:"task_id"`
+		TaskName string    `json:"task_name"`
+		Status   string    `json:"status"`
+		Message  string    `json:"message"`
+		Time     time.Time `json:"time"`
+	} `json:"last_error"`
+}
+
+// runStatus 实例运行状态
+func runStatus(a *app, args []string) error {
+	if _, err := parseFlags(a.newFlags("status"), args); err != nil {
+		return err
+	}
+
+	var resp instanceStatus
+	data, err := a.call("GET", "/api/status", nil, nil, &resp)
+	if err != nil {
+		return err
+	}
+	return a.render(data, func() {
+		lastError := "-"
+		if resp.LastError != nil {
+			lastError = fmt.Sprintf("%s #%d %s at %s: %s", resp.LastError.TaskName, resp.LastError.TaskID,
+				resp.LastError.Status, formatTime(&resp.LastError.Time), truncate(resp.LastError.Message, 60))
+		}
+		printFields([][2]string{
+			{"Version", resp.Version + " " + resp.Revision},
+			{"Instance", resp.InstanceID},
+			{"Is leader", strconv.FormatBool(resp.IsLeader)},
+			{"Started", formatTime(&resp.StartedAt)},
+			{"Uptime", resp.Uptime},
+			{"Scheduler running", strconv.FormatBool(resp.Scheduler.Running)},
+			{"Scheduler paused", strconv.FormatBool(resp.Scheduler.Paused)},
+			{"Last error", lastError},
+		})
+
+		if len(resp.Scheduler.Entries) > 0 {
+			fmt.Println()
+			rows := make([][]string, 0, len(resp.Scheduler.Entries))
+			for _, entry := range resp.Scheduler.Entries {
+				rows = append(rows, []string{
+					strconv.Itoa(int(entry.TaskID)), entry.TaskName, entry.CronExpr, formatTime(&entry.NextRun),
+				})
+			}
+			printTable([]string{"ID", "TASK", "CRON", "NEXT RUN"}, rows)
+		}
+
+		if len(resp.RunningExecutions) > 0 {
+			fmt.Println()
+			rows := make([][]string, 0, len(resp.RunningExecutions))
+			for _, execution := range resp.RunningExecutions {
+				rows = append(rows, []string{
+					strconv.Itoa(int(execution.LogID)), execution.TaskName, formatTime(&execution.StartedAt),
+				})
+			}
+			printTable([]string{"LOG", "RUNNING TASK", "STARTED"}, rows)
+		}
+	})
+}
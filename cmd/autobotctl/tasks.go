@@ -0,0 +1,412 @@
+package main
+
+import (
+	"autobot/internal/models"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+// runTasks 任务相关命令
+func runTasks(a *app, args []string) error {
+	return subcommand(a, "tasks", args, map[string]func(a *app, args []string) error{
+		"list":   tasksList,
+		"show":   tasksShow,
+		"create": tasksCreate,
+		"edit":   tasksEdit,
+		"delete": tasksDelete,
+		"run":    tasksRun,
+		"logs":   tasksLogs,
+		"tail":   tasksTail,
+	})
+}
+
+// idArg 解析唯一的ID参数
+func idArg(args []string, usage string) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("usage: autobotctl %s", usage)
+	}
+	if _, err := strconv.ParseUint(args[0], 10, 32); err != nil {
+		return "", fmt.Errorf("invalid id: %s", args[0])
+	}
+	return args[0], nil
+}
+
+func tasksList(a *app, args []string) error {
+	fs := a.newFlags("tasks list")
+	status := fs.String("status", "", "按状态筛选：active 或 inactive")
+	page := fs.Int("page", 1, "页码")
+	limit := fs.Int("limit", 50, "每页数量")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	query := url.Values{"page": {strconv.Itoa(*page)}, "limit": {strconv.Itoa(*limit)}}
+	if *status != "" {
+		query.Set("status", *status)
+	}
+
+	var resp struct {
+		Tasks []models.Task `json:"tasks"`
+		Total int64         `json:"total"`
+	}
+	data, err := a.call("GET", "/api/tasks", query, nil, &resp)
+	if err != nil {
+		return err
+	}
+	return a.render(data, func() {
+		rows := make([][]string, 0, len(resp.Tasks))
+		for _, task := range resp.Tasks {
+			rows = append(rows, []string{
+				strconv.Itoa(int(task.ID)),
+				truncate(task.Name, 40),
+				task.Status,
+				task.CronExpr,
+				formatTime(task.LastRun),
+				formatTime(task.NextRun),
+				orDash(task.Source),
+			})
+		}
+		printTable([]string{"ID", "NAME", "STATUS", "CRON", "LAST RUN", "NEXT RUN", "SOURCE"}, rows)
+		fmt.Printf("\n%d of %d tasks\n", len(resp.Tasks), resp.Total)
+	})
+}
+
+func tasksShow(a *app, args []string) error {
+	fs := a.newFlags("tasks show")
+	script := fs.Bool("script", false, "同时输出脚本内容")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	id, err := idArg(positional, "tasks show ID [-script]")
+	if err != nil {
+		return err
+	}
+
+	var task models.Task
+	data, err := a.call("GET", "/api/tasks/"+id, nil, nil, &task)
+	if err != nil {
+		return err
+	}
+	return a.render(data, func() {
+		fields := [][2]string{
+			{"ID", strconv.Itoa(int(task.ID))},
+			{"Name", task.Name},
+			{"Description", orDash(task.Description)},
+			{"Status", task.Status},
+			{"Cron", task.CronExpr},
+			{"Last run", formatTime(task.LastRun)},
+			{"Next run", formatTime(task.NextRun)},
+			{"Bark", orDash(truncate(task.BarkConfig, 80))},
+			{"Created", formatTime(&task.CreatedAt)},
+			{"Updated", formatTime(&task.UpdatedAt)},
+		}
+		if task.Source != "" {
+			fields = append(fields,
+				[2]string{"Source", task.Source + " (read-only)"},
+				[2]string{"Source path", task.SourcePath},
+				[2]string{"Revision", orDash(task.SourceRevision)},
+			)
+		}
+		printFields(fields)
+		if *script {
+			fmt.Println()
+			fmt.Println(task.Script)
+		}
+	})
+}
+
+// taskFlags create 和 edit 共用的任务参数
+type taskFlags struct {
+	name, description, cron, status, scriptFile, barkFile string
+}
+
+func (f *taskFlags) register(a *app, name string) *flag.FlagSet {
+	fs := a.newFlags(name)
+	fs.StringVar(&f.name, "name", "", "任务名称")
+	fs.StringVar(&f.description, "description", "", "任务描述")
+	fs.StringVar(&f.cron, "cron", "", "cron 表达式（6位：秒 分 时 日 月 周）")
+	fs.StringVar(&f.status, "status", "", "状态：active 或 inactive")
+	fs.StringVar(&f.scriptFile, "script", "", "Python 脚本文件，- 表示标准输入")
+	fs.StringVar(&f.barkFile, "bark", "", "Bark 配置 JSON 文件")
+	return fs
+}
+
+// readInput 读取文件内容，- 表示标准输入
+func readInput(path string) (string, error) {
+	if path == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		return string(data), err
+	}
+	data, err := os.ReadFile(path)
+	return string(data), err
+}
+
+// request 将参数转换为更新请求，只包含显式指定的字段
+func (f *taskFlags) request() (models.UpdateTaskRequest, error) {
+	req := models.UpdateTaskRequest{
+		Name:        f.name,
+		Description: f.description,
+		CronExpr:    f.cron,
+		Status:      f.status,
+	}
+	if f.scriptFile != "" {
+		script, err := readInput(f.scriptFile)
+		if err != nil {
+			return req, fmt.Errorf("failed to read script: %v", err)
+		}
+		req.Script = script
+	}
+	if f.barkFile != "" {
+		bark, err := readInput(f.barkFile)
+		if err != nil {
+			return req, fmt.Errorf("failed to read bark config: %v", err)
+		}
+		req.BarkConfig = bark
+	}
+	return req, nil
+}
+
+func tasksCreate(a *app, args []string) error {
+	var f taskFlags
+	fs := f.register(a, "tasks create")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	req, err := f.request()
+	if err != nil {
+		return err
+	}
+	if req.Name == "" || req.CronExpr == "" || req.Script == "" {
+		return fmt.Errorf("usage: autobotctl tasks create -name NAME -cron EXPR -script FILE [-description TEXT] [-status active] [-bark FILE]")
+	}
+
+	var task models.Task
+	data, err := a.call("POST", "/api/tasks", nil, models.CreateTaskRequest(req), &task)
+	if err != nil {
+		return err
+	}
+	return a.render(data, func() {
+		fmt.Printf("Task %d created: %s\n", task.ID, task.Name)
+	})
+}
+
+func tasksEdit(a *app, args []string) error {
+	var f taskFlags
+	fs := f.register(a, "tasks edit")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	id, err := idArg(positional, "tasks edit ID [-name NAME] [-cron EXPR] [-script FILE] [-description TEXT] [-status STATUS] [-bark FILE]")
+	if err != nil {
+		return err
+	}
+	req, err := f.request()
+	if err != nil {
+		return err
+	}
+	if req == (models.UpdateTaskRequest{}) {
+		return fmt.Errorf("nothing to update")
+	}
+
+	var task models.Task
+	data, err := a.call("PUT", "/api/tasks/"+id, nil, req, &task)
+	if err != nil {
+		return err
+	}
+	return a.render(data, func() {
+		fmt.Printf("Task %d updated: %s\n", task.ID, task.Name)
+	})
+}
+
+func tasksDelete(a *app, args []string) error {
+	positional, err := parseFlags(a.newFlags("tasks delete"), args)
+	if err != nil {
+		return err
+	}
+	id, err := idArg(positional, "tasks delete ID")
+	if err != nil {
+		return err
+	}
+
+	var resp struct {
+		TaskName    string `json:"task_name"`
+		DeletedLogs int64  `json:"deleted_logs"`
+	}
+	data, err := a.call("DELETE", "/api/tasks/"+id, nil, nil, &resp)
+	if err != nil {
+		return err
+	}
+	return a.render(data, func() {
+		fmt.Printf("Task %s deleted (%d logs removed)\n", resp.TaskName, resp.DeletedLogs)
+	})
+}
+
+func tasksRun(a *app, args []string) error {
+	fs := a.newFlags("tasks run")
+	follow := fs.Bool("follow", false, "等待执行完成并输出结果")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	id, err := idArg(positional, "tasks run ID [-follow]")
+	if err != nil {
+		return err
+	}
+
+	// 记录触发前最新的日志，-follow 时等待新的执行完成
+	var latest uint
+	if *follow {
+		logs, err := a.fetchLogs(id, 1)
+		if err != nil {
+			return err
+		}
+		if len(logs) > 0 {
+			latest = logs[0].ID
+		}
+	}
+
+	data, err := a.call("POST", "/api/tasks/"+id+"/run", nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	if !*follow {
+		return a.render(data, func() {
+			fmt.Printf("Task %s started\n", id)
+		})
+	}
+
+	for {
+		time.Sleep(time.Second)
+		logs, err := a.fetchLogs(id, 5)
+		if err != nil {
+			return err
+		}
+		for i := len(logs) - 1; i >= 0; i-- {
+			if logs[i].ID > latest && logs[i].Status != "running" {
+				return a.printLog(logs[i])
+			}
+		}
+	}
+}
+
+func tasksLogs(a *app, args []string) error {
+	fs := a.newFlags("tasks logs")
+	limit := fs.Int("limit", 20, "日志数量")
+	status := fs.String("status", "", "按状态筛选")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	id, err := idArg(positional, "tasks logs ID [-limit N] [-status STATUS]")
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{"limit": {strconv.Itoa(*limit)}}
+	if *status != "" {
+		query.Set("status", *status)
+	}
+	var resp struct {
+		Logs  []models.TaskLog `json:"logs"`
+		Total int64            `json:"total"`
+	}
+	data, err := a.call("GET", "/api/tasks/"+id+"/logs", query, nil, &resp)
+	if err != nil {
+		return err
+	}
+	return a.render(data, func() {
+		rows := make([][]string, 0, len(resp.Logs))
+		for _, entry := range resp.Logs {
+			rows = append(rows, []string{
+				strconv.Itoa(int(entry.ID)),
+				formatTime(&entry.StartTime),
+				entry.Status,
+				fmt.Sprintf("%dms", entry.Duration),
+				orDash(entry.Instance),
+				orDash(truncate(entry.Result, 60)),
+			})
+		}
+		printTable([]string{"ID", "STARTED", "STATUS", "DURATION", "INSTANCE", "RESULT"}, rows)
+	})
+}
+
+func tasksTail(a *app, args []string) error {
+	fs := a.newFlags("tasks tail")
+	interval := fs.Duration("interval", 2*time.Second, "轮询间隔")
+	last := fs.Int("n", 1, "启动时先输出最近的 N 条执行")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	id, err := idArg(positional, "tasks tail ID [-n N] [-interval 2s]")
+	if err != nil {
+		return err
+	}
+
+	printed := make(map[uint]bool)
+	first := true
+	for {
+		logs, err := a.fetchLogs(id, 20)
+		if err != nil {
+			return err
+		}
+		// 接口按时间倒序返回，逆序输出
+		for i := len(logs) - 1; i >= 0; i-- {
+			entry := logs[i]
+			if printed[entry.ID] || entry.Status == "running" {
+				continue
+			}
+			printed[entry.ID] = true
+			if first && i >= *last {
+				continue
+			}
+			if err := a.printLog(entry); err != nil {
+				return err
+			}
+		}
+		first = false
+		time.Sleep(*interval)
+	}
+}
+
+// fetchLogs 获取任务最近的执行日志（按时间倒序）
+func (a *app) fetchLogs(id string, limit int) ([]models.TaskLog, error) {
+	var resp struct {
+		Logs []models.TaskLog `json:"logs"`
+	}
+	_, err := a.call("GET", "/api/tasks/"+id+"/logs", url.Values{"limit": {strconv.Itoa(limit)}}, nil, &resp)
+	return resp.Logs, err
+}
+
+// printLog 输出一次执行的详细结果
+func (a *app) printLog(entry models.TaskLog) error {
+	if a.output == "json" {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		return printJSON(data)
+	}
+	fmt.Printf("==> #%d %s %s (%dms)\n", entry.ID, formatTime(&entry.StartTime), entry.Status, entry.Duration)
+	if entry.Output != "" {
+		fmt.Print(entry.Output)
+		if entry.Output[len(entry.Output)-1] != '\n' {
+			fmt.Println()
+		}
+	}
+	if entry.Error != "" {
+		fmt.Fprint(os.Stderr, entry.Error)
+		if entry.Error[len(entry.Error)-1] != '\n' {
+			fmt.Fprintln(os.Stderr)
+		}
+	}
+	return nil
+}
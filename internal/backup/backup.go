@@ -0,0 +1,391 @@
+package backup
+
+import (
+	"autobot/internal/database"
+	"autobot/internal/leader"
+	"autobot/internal/migrations"
+	"database/sql"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// filePrefix 备份文件名前缀
+	filePrefix = "autobot-"
+	// fileSuffix 备份文件扩展名
+	fileSuffix = ".db"
+	// timeLayout 备份文件名中的时间格式，精确到毫秒，同一秒内的多个备份不会互相覆盖
+	timeLayout = "20060102-150405.000"
+)
+
+// BackupFile 备份文件信息
+type BackupFile struct {
+	Name      string    `json:"name"`
+	Path      string    `json:"-"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Manager 备份管理器，负责在线备份、定时备份和轮转
+type Manager struct {
+	dir      string
+	interval time.Duration
+	keep     int
+
+	mutex sync.Mutex // 防止并发备份
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// NewManager 创建备份管理器
+// interval 为0时不做定时备份；keep 为保留的备份数量，不大于0时不清理
+func NewManager(dir string, interval time.Duration, keep int) *Manager {
+	return &Manager{
+		dir:      dir,
+		interval: interval,
+		keep:     keep,
+	}
+}
+
+// Dir 备份目录
+func (m *Manager) Dir() string {
+	return m.dir
+}
+
+// Start 启动定时备份
+func (m *Manager) Start() {
+	if m.interval <= 0 {
+		return
+	}
+
+	m.stop = make(chan struct{})
+	m.done = make(chan struct{})
+
+	go func() {
+		defer close(m.done)
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := m.Create(); err != nil {
+					slog.Error("Scheduled backup failed", "error", err)
+				}
+			case <-m.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止定时备份，等待正在进行的备份完成
+func (m *Manager) Stop() {
+	if m.stop == nil {
+		return
+	}
+	close(m.stop)
+	<-m.done
+}
+
+// Create 在备份目录中创建一个新的在线备份，并按保留数量轮转
+func (m *Manager) Create() (*BackupFile, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if err := os.MkdirAll(m.dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %v", err)
+	}
+
+	// 文件名已存在（例如另一个进程同时备份）时换一个时间，保证按文件名排序即按时间排序
+	name, path := "", ""
+	for {
+		name = filePrefix + time.Now().Format(timeLayout) + fileSuffix
+		path = filepath.Join(m.dir, name)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := BackupTo(path); err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("Database backup created", "path", path, "bytes", info.Size())
+
+	m.rotate()
+
+	return &BackupFile{
+		Name:      name,
+		Path:      path,
+		Size:      info.Size(),
+		CreatedAt: info.ModTime(),
+	}, nil
+}
+
+// List 列出备份目录中的备份，按时间倒序
+func (m *Manager) List() ([]BackupFile, error) {
+	entries, err := os.ReadDir(m.dir)
+	if os.IsNotExist(err) {
+		return []BackupFile{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]BackupFile, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, filePrefix) || !strings.HasSuffix(name, fileSuffix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, BackupFile{
+			Name:      name,
+			Path:      filepath.Join(m.dir, name),
+			Size:      info.Size(),
+			CreatedAt: info.ModTime(),
+		})
+	}
+
+	// 文件名中的时间可以直接按字符串排序
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name > files[j].Name
+	})
+	return files, nil
+}
+
+// Get 按文件名获取备份，拒绝目录穿越
+func (m *Manager) Get(name string) (*BackupFile, error) {
+	if name != filepath.Base(name) || !strings.HasPrefix(name, filePrefix) || !strings.HasSuffix(name, fileSuffix) {
+		return nil, fmt.Errorf("invalid backup name: %s", name)
+	}
+
+	path := filepath.Join(m.dir, name)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	return &BackupFile{
+		Name:      name,
+		Path:      path,
+		Size:      info.Size(),
+		CreatedAt: info.ModTime(),
+	}, nil
+}
+
+// rotate 删除超出保留数量的旧备份
+func (m *Manager) rotate() {
+	if m.keep <= 0 {
+		return
+	}
+
+	files, err := m.List()
+	if err != nil {
+		slog.Error("Failed to list backups for rotation", "error", err)
+		return
+	}
+
+	for _, file := range files[min(m.keep, len(files)):] {
+		if err := os.Remove(file.Path); err != nil {
+			slog.Error("Failed to remove old backup", "path", file.Path, "error", err)
+			continue
+		}
+		slog.Info("Removed old backup", "path", file.Path)
+	}
+}
+
+// BackupTo 将当前数据库在线备份到指定文件
+// 使用 VACUUM INTO，生成的是一致的、不依赖 WAL 文件的完整数据库
+func BackupTo(path string) error {
+	if database.Driver() != database.DriverSQLite {
+		return fmt.Errorf("online backup is only supported for SQLite, use the native tools (pg_dump, mysqldump) for %s", database.Driver())
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("backup file already exists: %s", path)
+	}
+
+	if err := database.GetDB().Exec("VACUUM INTO ?", path).Error; err != nil {
+		return fmt.Errorf("failed to back up database: %v", err)
+	}
+	return nil
+}
+
+// Restore 用备份文件替换 SQLite 数据库文件，必须在 autobot 停止时执行
+// 任一实例的心跳未过期（有实例正在运行，无论是否为 Leader）时拒绝恢复；实例异常退出后需等心跳过期
+// 恢复前校验备份的完整性和表结构版本，原数据库（连同 -wal、-shm 文件）保留为 <dbPath>.before-restore-<时间>
+func Restore(backupPath string, dbPath string) error {
+	if err := checkNotRunning(dbPath); err != nil {
+		return err
+	}
+
+	version, err := inspect(backupPath)
+	if err != nil {
+		return err
+	}
+	if version > migrations.Latest() {
+		return fmt.Errorf("%w: backup is at version %d, binary supports up to %d", migrations.ErrDatabaseTooNew, version, migrations.Latest())
+	}
+
+	// 先复制到临时文件，再原子替换
+	tmpPath := dbPath + ".restore-tmp"
+	if err := copyFile(backupPath, tmpPath); err != nil {
+		return fmt.Errorf("failed to copy backup: %v", err)
+	}
+
+	// 原数据库连同 WAL 文件一起改名保留，未检查点的提交留在 <previous>-wal 中，打开 previous 时会被应用
+	// WAL 文件不能留在原路径，否则会被应用到恢复后的数据库上
+	var previous string
+	if _, err := os.Stat(dbPath); err == nil {
+		previous = dbPath + ".before-restore-" + time.Now().Format(timeLayout)
+		if err := moveDatabase(dbPath, previous); err != nil {
+			moveDatabase(previous, dbPath)
+			os.Remove(tmpPath)
+			return fmt.Errorf("failed to keep current database: %v", err)
+		}
+		slog.Info("Current database moved", "path", previous)
+	}
+
+	if err := os.Rename(tmpPath, dbPath); err != nil {
+		os.Remove(tmpPath)
+		if previous != "" {
+			if rollbackErr := moveDatabase(previous, dbPath); rollbackErr != nil {
+				return fmt.Errorf("failed to move restored database into place: %v (the original database is kept at %s: %v)", err, previous, rollbackErr)
+			}
+		}
+		return fmt.Errorf("failed to move restored database into place: %v", err)
+	}
+
+	slog.Info("Database restored", "path", backupPath, "schema_version", version)
+	return nil
+}
+
+// moveDatabase 将 SQLite 数据库文件及其 -wal、-shm 文件一起改名，不存在的 WAL 文件忽略
+func moveDatabase(from string, to string) error {
+	if _, err := os.Stat(from); err == nil {
+		if err := os.Rename(from, to); err != nil {
+			return err
+		}
+	}
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if _, err := os.Stat(from + suffix); err != nil {
+			continue
+		}
+		if err := os.Rename(from+suffix, to+suffix); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkNotRunning 检查是否有实例正在使用数据库
+// 运行中的实例（包括非 Leader）每隔几秒更新心跳，正常停止时删除心跳；
+// 没有心跳表的旧版本数据库退回到检查调度器租约
+func checkNotRunning(dbPath string) error {
+	if _, err := os.Stat(dbPath); err != nil {
+		return nil
+	}
+
+	db, err := sql.Open("sqlite", "file:"+dbPath+"?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	liveSince := leader.LiveSince()
+	rows, err := db.Query("SELECT instance_id, last_seen FROM instance_heartbeats")
+	if err == nil {
+		defer rows.Close()
+		for rows.Next() {
+			var instance string
+			var lastSeen time.Time
+			if err := rows.Scan(&instance, &lastSeen); err != nil {
+				return fmt.Errorf("failed to read instance heartbeats: %v", err)
+			}
+			if lastSeen.After(liveSince) {
+				return fmt.Errorf("instance %s is still running against %s (last heartbeat at %s), stop it before restoring",
+					instance, dbPath, lastSeen.Local().Format(time.RFC3339))
+			}
+		}
+		return rows.Err()
+	}
+
+	var holder string
+	var expiresAt time.Time
+	err = db.QueryRow("SELECT holder_id, expires_at FROM scheduler_leases WHERE name = ?", leader.LeaseName).Scan(&holder, &expiresAt)
+	if err != nil {
+		// 没有租约（从未启动过或旧版本数据库）
+		return nil
+	}
+	if holder != "" && expiresAt.After(time.Now()) {
+		return fmt.Errorf("instance %s is still running against %s (lease expires at %s), stop it before restoring",
+			holder, dbPath, expiresAt.Local().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// inspect 校验备份文件完整性并返回其表结构版本
+func inspect(path string) (int, error) {
+	if _, err := os.Stat(path); err != nil {
+		return 0, err
+	}
+
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	var integrity string
+	if err := db.QueryRow("PRAGMA integrity_check").Scan(&integrity); err != nil {
+		return 0, fmt.Errorf("backup is not a valid SQLite database: %v", err)
+	}
+	if integrity != "ok" {
+		return 0, fmt.Errorf("backup failed integrity check: %s", integrity)
+	}
+
+	var version sql.NullInt64
+	if err := db.QueryRow("SELECT MAX(version) FROM schema_migrations").Scan(&version); err != nil {
+		return 0, fmt.Errorf("backup has no schema version information: %v", err)
+	}
+	return int(version.Int64), nil
+}
+
+// copyFile 复制文件并同步到磁盘
+func copyFile(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
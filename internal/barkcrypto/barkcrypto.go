@@ -0,0 +1,169 @@
+// Package barkcrypto 按 Bark App 的加密推送协议加密推送内容
+//
+// 密钥和 IV 均按原始字节使用（与 App 内填写的字符串一致），
+// CBC/ECB 使用 PKCS7 填充，GCM 的密文末尾附带认证标签，结果为 base64。
+package barkcrypto
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"autobot/internal/models"
+)
+
+// EncryptedFields 加密后从明文请求中移除的字段
+// sound、level、group 等影响投递行为的字段由 Bark 服务端使用，保留明文
+var EncryptedFields = []string{"title", "subtitle", "body", "url", "copy", "icon", "image", "markdown"}
+
+// keySizes 各算法要求的密钥长度
+var keySizes = map[string]int{
+	models.BarkEncryptionAES128: 16,
+	models.BarkEncryptionAES192: 24,
+	models.BarkEncryptionAES256: 32,
+}
+
+// ivSizes 各模式要求的 IV 长度，ECB 不使用 IV
+var ivSizes = map[string]int{
+	models.BarkEncryptionCBC: aes.BlockSize,
+	models.BarkEncryptionECB: 0,
+	models.BarkEncryptionGCM: 12,
+}
+
+// ivAlphabet 随机 IV 使用的字符，保证 IV 可以原样写入请求
+const ivAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+// Validate 校验加密配置，algorithm 为空表示不加密
+// CBC 可以指定固定 IV（相同内容会得到相同密文），GCM 不允许固定 IV
+func Validate(algorithm, mode, key, iv string) error {
+	if algorithm == "" {
+		return nil
+	}
+	size, ok := keySizes[algorithm]
+	if !ok {
+		return fmt.Errorf("unsupported algorithm %q", algorithm)
+	}
+	ivSize, ok := ivSizes[mode]
+	if !ok {
+		return fmt.Errorf("unsupported mode %q", mode)
+	}
+	if len(key) != size {
+		return fmt.Errorf("%s requires a %d-byte key, got %d", algorithm, size, len(key))
+	}
+	if iv != "" {
+		if ivSize == 0 {
+			return fmt.Errorf("%s mode does not use an IV", mode)
+		}
+		// GCM 的 IV 即 nonce，同一密钥下重复使用会泄露明文并允许伪造消息，必须每次随机生成
+		if mode == models.BarkEncryptionGCM {
+			return fmt.Errorf("%s mode does not accept a fixed IV, a random one is generated for every push", mode)
+		}
+		if len(iv) != ivSize {
+			return fmt.Errorf("%s mode requires a %d-byte IV, got %d", mode, ivSize, len(iv))
+		}
+	}
+	return nil
+}
+
+// Encrypt 将 payload 中的内容字段加密为 ciphertext（及 iv），并移除对应明文
+// 设备未启用加密或没有可加密字段时 payload 保持不变
+func Encrypt(device *models.BarkDevice, payload map[string]interface{}) error {
+	if !device.Encrypted() {
+		return nil
+	}
+	// 只有 CBC 使用配置的固定 IV；之前保存的 GCM 固定 IV 被忽略，每次随机生成
+	fixedIV := device.EncryptionIV
+	if device.EncryptionMode != models.BarkEncryptionCBC {
+		fixedIV = ""
+	}
+	if err := Validate(device.EncryptionAlgorithm, device.EncryptionMode, device.EncryptionKey, fixedIV); err != nil {
+		return fmt.Errorf("invalid encryption settings for device %s: %w", device.Name, err)
+	}
+
+	content := make(map[string]interface{})
+	for _, field := range EncryptedFields {
+		if value, ok := payload[field]; ok {
+			content[field] = value
+		}
+	}
+	if len(content) == 0 {
+		return nil
+	}
+	plaintext, err := json.Marshal(content)
+	if err != nil {
+		return fmt.Errorf("failed to marshal encrypted content: %w", err)
+	}
+
+	iv := fixedIV
+	if iv == "" && ivSizes[device.EncryptionMode] > 0 {
+		if iv, err = randomIV(ivSizes[device.EncryptionMode]); err != nil {
+			return err
+		}
+	}
+	ciphertext, err := seal(device.EncryptionMode, []byte(device.EncryptionKey), []byte(iv), plaintext)
+	if err != nil {
+		return err
+	}
+
+	for field := range content {
+		delete(payload, field)
+	}
+	payload["ciphertext"] = base64.StdEncoding.EncodeToString(ciphertext)
+	if iv != "" {
+		payload["iv"] = iv
+	}
+	return nil
+}
+
+// seal 按模式加密
+func seal(mode string, key, iv, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	switch mode {
+	case models.BarkEncryptionCBC:
+		data := pad(plaintext, block.BlockSize())
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(data, data)
+		return data, nil
+	case models.BarkEncryptionECB:
+		data := pad(plaintext, block.BlockSize())
+		for i := 0; i < len(data); i += block.BlockSize() {
+			block.Encrypt(data[i:i+block.BlockSize()], data[i:i+block.BlockSize()])
+		}
+		return data, nil
+	case models.BarkEncryptionGCM:
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		return aead.Seal(nil, iv, plaintext, nil), nil
+	default:
+		return nil, fmt.Errorf("unsupported mode %q", mode)
+	}
+}
+
+// pad PKCS7 填充，返回新切片
+func pad(data []byte, blockSize int) []byte {
+	n := blockSize - len(data)%blockSize
+	return append(append([]byte{}, data...), bytes.Repeat([]byte{byte(n)}, n)...)
+}
+
+// randomIV 生成由字母数字组成的随机 IV
+func randomIV(size int) (string, error) {
+	buf := make([]byte, size)
+	max := big.NewInt(int64(len(ivAlphabet)))
+	for i := range buf {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate IV: %w", err)
+		}
+		buf[i] = ivAlphabet[n.Int64()]
+	}
+	return string(buf), nil
+}
@@ -0,0 +1,173 @@
+package barkcrypto
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"autobot/internal/models"
+)
+
+// decrypt 按 Bark App 的方式解密 Encrypt 生成的 payload
+func decrypt(t *testing.T, device *models.BarkDevice, payload map[string]interface{}) map[string]interface{} {
+	t.Helper()
+	ciphertext, err := base64.StdEncoding.DecodeString(payload["ciphertext"].(string))
+	if err != nil {
+		t.Fatalf("ciphertext is not base64: %v", err)
+	}
+	iv, _ := payload["iv"].(string)
+
+	block, err := aes.NewCipher([]byte(device.EncryptionKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var plaintext []byte
+	switch device.EncryptionMode {
+	case models.BarkEncryptionCBC, models.BarkEncryptionECB:
+		if len(ciphertext)%aes.BlockSize != 0 {
+			t.Fatalf("ciphertext length %d is not a multiple of the block size", len(ciphertext))
+		}
+		plaintext = make([]byte, len(ciphertext))
+		if device.EncryptionMode == models.BarkEncryptionCBC {
+			cipher.NewCBCDecrypter(block, []byte(iv)).CryptBlocks(plaintext, ciphertext)
+		} else {
+			for i := 0; i < len(ciphertext); i += aes.BlockSize {
+				block.Decrypt(plaintext[i:i+aes.BlockSize], ciphertext[i:i+aes.BlockSize])
+			}
+		}
+		n := int(plaintext[len(plaintext)-1])
+		if n == 0 || n > aes.BlockSize || !bytes.Equal(plaintext[len(plaintext)-n:], bytes.Repeat([]byte{byte(n)}, n)) {
+			t.Fatalf("invalid PKCS7 padding")
+		}
+		plaintext = plaintext[:len(plaintext)-n]
+	case models.BarkEncryptionGCM:
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if plaintext, err = aead.Open(nil, []byte(iv), ciphertext, nil); err != nil {
+			t.Fatalf("GCM open: %v", err)
+		}
+	}
+
+	var content map[string]interface{}
+	if err := json.Unmarshal(plaintext, &content); err != nil {
+		t.Fatalf("plaintext is not JSON: %v", err)
+	}
+	return content
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		device models.BarkDevice
+	}{
+		{"aes128-cbc-random-iv", models.BarkDevice{EncryptionAlgorithm: models.BarkEncryptionAES128, EncryptionMode: models.BarkEncryptionCBC, EncryptionKey: "1234567890123456"}},
+		{"aes192-cbc-fixed-iv", models.BarkDevice{EncryptionAlgorithm: models.BarkEncryptionAES192, EncryptionMode: models.BarkEncryptionCBC, EncryptionKey: "123456789012345678901234", EncryptionIV: "abcdefghijklmnop"}},
+		{"aes256-ecb", models.BarkDevice{EncryptionAlgorithm: models.BarkEncryptionAES256, EncryptionMode: models.BarkEncryptionECB, EncryptionKey: "12345678901234567890123456789012"}},
+		{"aes128-gcm", models.BarkDevice{EncryptionAlgorithm: models.BarkEncryptionAES128, EncryptionMode: models.BarkEncryptionGCM, EncryptionKey: "1234567890123456"}},
+		{"aes256-gcm", models.BarkDevice{EncryptionAlgorithm: models.BarkEncryptionAES256, EncryptionMode: models.BarkEncryptionGCM, EncryptionKey: "12345678901234567890123456789012"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := map[string]interface{}{
+				"title":      "磁盘告警",
+				"body":       "used 95%",
+				"url":        "https://example.com",
+				"level":      "timeSensitive",
+				"device_key": "key",
+			}
+			if err := Encrypt(&tt.device, payload); err != nil {
+				t.Fatalf("Encrypt: %v", err)
+			}
+
+			for _, field := range []string{"title", "body", "url"} {
+				if _, ok := payload[field]; ok {
+					t.Errorf("plaintext field %q left in payload", field)
+				}
+			}
+			if payload["level"] != "timeSensitive" || payload["device_key"] != "key" {
+				t.Errorf("delivery fields changed: %v", payload)
+			}
+			wantIV := map[string]int{models.BarkEncryptionCBC: 16, models.BarkEncryptionECB: 0, models.BarkEncryptionGCM: 12}[tt.device.EncryptionMode]
+			if iv, _ := payload["iv"].(string); len(iv) != wantIV {
+				t.Errorf("iv = %q, want %d bytes", iv, wantIV)
+			}
+			if tt.device.EncryptionIV != "" && payload["iv"] != tt.device.EncryptionIV {
+				t.Errorf("iv = %v, want the configured %q", payload["iv"], tt.device.EncryptionIV)
+			}
+
+			content := decrypt(t, &tt.device, payload)
+			if content["title"] != "磁盘告警" || content["body"] != "used 95%" || content["url"] != "https://example.com" {
+				t.Errorf("decrypted content = %v", content)
+			}
+		})
+	}
+}
+
+func TestEncryptGCMUsesFreshNonce(t *testing.T) {
+	// 之前保存的固定 IV 在 GCM 下被忽略
+	device := models.BarkDevice{EncryptionAlgorithm: models.BarkEncryptionAES128, EncryptionMode: models.BarkEncryptionGCM, EncryptionKey: "1234567890123456", EncryptionIV: "abcdefghijkl"}
+
+	seen := make(map[string]bool)
+	for i := 0; i < 20; i++ {
+		payload := map[string]interface{}{"body": "same"}
+		if err := Encrypt(&device, payload); err != nil {
+			t.Fatalf("Encrypt: %v", err)
+		}
+		iv := payload["iv"].(string)
+		if iv == device.EncryptionIV || seen[iv] {
+			t.Fatalf("nonce %q reused", iv)
+		}
+		seen[iv] = true
+		if content := decrypt(t, &device, payload); content["body"] != "same" {
+			t.Errorf("decrypted content = %v", content)
+		}
+	}
+}
+
+func TestEncryptUnencryptedDevice(t *testing.T) {
+	payload := map[string]interface{}{"title": "t", "body": "b"}
+	if err := Encrypt(&models.BarkDevice{}, payload); err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if payload["title"] != "t" || payload["body"] != "b" || payload["ciphertext"] != nil {
+		t.Errorf("payload changed: %v", payload)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		algorithm, mode, key, iv string
+		wantErr                  string
+	}{
+		{"", "", "", "", ""},
+		{models.BarkEncryptionAES128, models.BarkEncryptionCBC, "1234567890123456", "", ""},
+		{models.BarkEncryptionAES128, models.BarkEncryptionCBC, "1234567890123456", "abcdefghijklmnop", ""},
+		{models.BarkEncryptionAES128, models.BarkEncryptionCBC, "1234567890123456", "short", "16-byte IV"},
+		{models.BarkEncryptionAES128, models.BarkEncryptionECB, "1234567890123456", "abcdefghijklmnop", "does not use an IV"},
+		{models.BarkEncryptionAES128, models.BarkEncryptionGCM, "1234567890123456", "", ""},
+		{models.BarkEncryptionAES128, models.BarkEncryptionGCM, "1234567890123456", "abcdefghijkl", "fixed IV"},
+		{models.BarkEncryptionAES256, models.BarkEncryptionCBC, "1234567890123456", "", "32-byte key"},
+		{"des", models.BarkEncryptionCBC, "12345678", "", "unsupported algorithm"},
+		{models.BarkEncryptionAES128, "ctr", "1234567890123456", "", "unsupported mode"},
+	}
+
+	for _, tt := range tests {
+		err := Validate(tt.algorithm, tt.mode, tt.key, tt.iv)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("Validate(%s, %s, iv=%q): %v", tt.algorithm, tt.mode, tt.iv, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("Validate(%s, %s, iv=%q) = %v, want error containing %q", tt.algorithm, tt.mode, tt.iv, err, tt.wantErr)
+		}
+	}
+}
@@ -3,17 +3,43 @@ package barkhistory
 import (
 	"autobot/internal/database"
 	"autobot/internal/models"
-	"log"
+	"autobot/internal/msgtemplate"
+	"fmt"
+	"log/slog"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
 )
 
 const (
-	// MaxBarkRecords 最大Bark记录条数
-	MaxBarkRecords = 50000
+	// DefaultMaxBarkRecords 默认最大Bark记录条数
+	DefaultMaxBarkRecords = 50000
 )
 
+// dedupStatuses 参与去重的记录状态，等待投递（含重试中）和已加入汇总的记录视为已发送
+var dedupStatuses = []string{"success", "pending", models.RecordQueued, models.RecordDigested}
+
+// 最大Bark记录条数，可通过配置修改
+var maxBarkRecords = DefaultMaxBarkRecords
+
+// SetMaxBarkRecords 设置最大Bark记录条数
+func SetMaxBarkRecords(n int) {
+	if n > 0 {
+		maxBarkRecords = n
+	}
+}
+
+// Bark记录最长保留时间，0 表示只按条数清理
+var maxRecordAge time.Duration
+
+// SetRecordRetention 设置Bark记录最长保留时间
+func SetRecordRetention(d time.Duration) {
+	if d >= 0 {
+		maxRecordAge = d
+	}
+}
+
 // BarkHistoryManager Bark历史记录管理器
 type BarkHistoryManager struct{}
 
@@ -30,7 +56,7 @@ func (bhm *BarkHistoryManager) SaveBarkRecord(record *models.BarkRecord) error {
 	})
 
 	if err != nil {
-		log.Printf("Failed to save bark record: %v", err)
+		slog.Error("Failed to save Bark record", "task_id", record.TaskID, "error", err)
 		return err
 	}
 
@@ -49,63 +75,92 @@ func (bhm *BarkHistoryManager) CheckDuplication(record *models.BarkRecord, dedup
 	db := database.GetDB()
 
 	switch dedupConfig.Mode {
-	case "recentN":
+	case models.DedupRecentN:
 		return bhm.checkRecentN(db, record, dedupConfig.RecentN)
-	case "hash":
+	case models.DedupHash:
 		return bhm.checkHash(db, record)
-	case "timeWindow":
+	case models.DedupTimeWindow:
 		return bhm.checkTimeWindow(db, record, dedupConfig.TimeWindow)
+	case models.DedupChanged:
+		return bhm.checkChanged(db, record)
+	case models.DedupCooldown:
+		return bhm.checkTimeWindow(db, record, dedupConfig.Cooldown)
 	default:
-		log.Printf("Unknown deduplication mode: %s", dedupConfig.Mode)
+		slog.Warn("Unknown deduplication mode", "mode", dedupConfig.Mode)
 		return false, nil
 	}
 }
 
+// ValidateDeduplication 检查去重配置，未启用时不检查
+func ValidateDeduplication(config *models.BarkDeduplicationConfig) error {
+	if !config.Enabled {
+		return nil
+	}
+	switch config.Mode {
+	case models.DedupRecentN, models.DedupHash, models.DedupTimeWindow, models.DedupChanged:
+	case models.DedupCooldown:
+		if config.Cooldown <= 0 {
+			return fmt.Errorf("cooldown must be greater than 0 minutes")
+		}
+	default:
+		return fmt.Errorf("unknown mode %q", config.Mode)
+	}
+	if err := msgtemplate.Validate(config.Key); err != nil {
+		return fmt.Errorf("key: %v", err)
+	}
+	for _, field := range config.Fields {
+		if strings.TrimSpace(field) == "" {
+			return fmt.Errorf("fields must not contain empty names")
+		}
+	}
+	return nil
+}
+
 // checkRecentN 检查最近N条记录中是否有重复
 func (bhm *BarkHistoryManager) checkRecentN(db *gorm.DB, record *models.BarkRecord, recentN int) (bool, error) {
 	var count int64
 
-	// 只查询该任务最近N条成功发送的记录中是否有相同的content_hash
-	// 注意：只统计 status = 'success' 的记录，跳过的记录不计入
+	// 只查询该任务最近N条已发送（或等待投递）的记录中是否有相同的去重键
+	// 注意：跳过和失败的记录不计入
 	// 使用重试机制
 	err := database.WithRetry(func(db *gorm.DB) error {
 		return db.Model(&models.BarkRecord{}).
-			Where("task_id = ? AND content_hash = ? AND status = ?", record.TaskID, record.ContentHash, "success").
+			Where("task_id = ? AND dedup_key = ? AND status IN ?", record.TaskID, record.DedupKey, dedupStatuses).
 			Order("created_at DESC").
 			Limit(recentN).
 			Count(&count).Error
 	})
 
 	if err != nil {
-		log.Printf("Failed to check recent N duplication: %v", err)
+		slog.Error("Failed to check recent N duplication", "error", err)
 		return false, err
 	}
 
 	return count > 0, nil
 }
 
-// checkHash 检查全局是否有相同hash的记录
+// checkHash 检查全局是否有相同去重键的记录
 func (bhm *BarkHistoryManager) checkHash(db *gorm.DB, record *models.BarkRecord) (bool, error) {
 	var count int64
 
-	// 只查询全局成功发送的记录中是否有相同的content_hash
-	// 注意：只统计 status = 'success' 的记录，跳过的记录不计入
+	// 只查询全局已发送（或等待投递）的记录中是否有相同的去重键
+	// 注意：跳过和失败的记录不计入
 	// 使用重试机制
 	err := database.WithRetry(func(db *gorm.DB) error {
 		return db.Model(&models.BarkRecord{}).
-			Where("content_hash = ? AND status = ?", record.ContentHash, "success").
+			Where("dedup_key = ? AND status IN ?", record.DedupKey, dedupStatuses).
 			Count(&count).Error
 	})
 
 	if err != nil {
-		log.Printf("Failed to check hash duplication: %v", err)
+		slog.Error("Failed to check hash duplication", "error", err)
 		return false, err
 	}
 
 	return count > 0, nil
 }
 
-// checkTimeWindow 检查时间窗口内是否有重复
+// checkTimeWindow 检查时间窗口内是否有重复，cooldown 模式同样按去重键在冷却时间内检查
 func (bhm *BarkHistoryManager) checkTimeWindow(db *gorm.DB, record *models.BarkRecord, timeWindowMinutes int) (bool, error) {
 	var count int64
 
@@ -113,50 +168,75 @@ func (bhm *BarkHistoryManager) checkTimeWindow(db *gorm.DB, record *models.BarkR
 	timeWindow := time.Duration(timeWindowMinutes) * time.Minute
 	startTime := time.Now().Add(-timeWindow)
 
-	// 只查询时间窗口内成功发送的记录中是否有相同的content_hash
-	// 注意：只统计 status = 'success' 的记录，跳过的记录不计入
+	// 只查询时间窗口内已发送（或等待投递）的记录中是否有相同的去重键
+	// 注意：跳过和失败的记录不计入
 	// 使用重试机制
 	err := database.WithRetry(func(db *gorm.DB) error {
 		return db.Model(&models.BarkRecord{}).
-			Where("task_id = ? AND content_hash = ? AND status = ? AND created_at >= ?",
-				record.TaskID, record.ContentHash, "success", startTime).
+			Where("task_id = ? AND dedup_key = ? AND status IN ? AND created_at >= ?",
+				record.TaskID, record.DedupKey, dedupStatuses, startTime).
 			Count(&count).Error
 	})
 
 	if err != nil {
-		log.Printf("Failed to check time window duplication: %v", err)
+		slog.Error("Failed to check time window duplication", "error", err)
 		return false, err
 	}
 
 	return count > 0, nil
 }
 
-// cleanupOldRecords 清理旧记录，保持在最大记录数内
+// checkChanged 与该任务发往同一目标的上一条已发送记录比较，去重键相同视为重复
+func (bhm *BarkHistoryManager) checkChanged(db *gorm.DB, record *models.BarkRecord) (bool, error) {
+	var last []models.BarkRecord
+
+	// 使用重试机制
+	err := database.WithRetry(func(db *gorm.DB) error {
+		return db.Select("id, dedup_key").
+			Where("task_id = ? AND device_key = ? AND channel_id = ? AND status IN ?",
+				record.TaskID, record.DeviceKey, record.ChannelID, dedupStatuses).
+			Order("created_at DESC, id DESC").
+			Limit(1).
+			Find(&last).Error
+	})
+
+	if err != nil {
+		slog.Error("Failed to check changed duplication", "error", err)
+		return false, err
+	}
+
+	return len(last) > 0 && last[0].DedupKey == record.DedupKey, nil
+}
+
+// cleanupOldRecords 清理旧记录：删除超过保留时间的记录，并保持在最大记录数内
 func (bhm *BarkHistoryManager) cleanupOldRecords() {
+	bhm.cleanupExpiredRecords()
+
 	// 计算当前记录数 - 使用重试机制
 	var count int64
 	err := database.WithRetry(func(db *gorm.DB) error {
 		return db.Model(&models.BarkRecord{}).Count(&count).Error
 	})
 	if err != nil {
-		log.Printf("Failed to count bark records: %v", err)
+		slog.Error("Failed to count Bark records", "error", err)
 		return
 	}
 
-	// 如果超过限制，删除最旧的记录
-	if count > MaxBarkRecords {
-		excessCount := count - MaxBarkRecords
+	// 如果超过限制，删除最旧的记录，等待汇总的记录保留到汇总发送
+	if count > int64(maxBarkRecords) {
+		excessCount := count - int64(maxBarkRecords)
 
 		// 获取需要删除的最旧记录的ID - 使用重试机制
 		var recordIDs []uint
 		err := database.WithRetry(func(db *gorm.DB) error {
 			return db.Model(&models.BarkRecord{}).
+				Where("status <> ?", models.RecordQueued).
 				Order("created_at ASC").
 				Limit(int(excessCount)).
 				Pluck("id", &recordIDs).Error
 		})
 		if err != nil {
-			log.Printf("Failed to get old bark record IDs: %v", err)
+			slog.Error("Failed to get old Bark record IDs", "error", err)
 			return
 		}
 
@@ -169,26 +249,44 @@ func (bhm *BarkHistoryManager) cleanupOldRecords() {
 				return result.Error
 			})
 			if err != nil {
-				log.Printf("Failed to delete old bark records: %v", err)
+				slog.Error("Failed to delete old Bark records", "error", err)
 				return
 			}
-			log.Printf("Cleaned up %d old bark records (kept %d)", rowsAffected, MaxBarkRecords)
+			slog.Info("Cleaned up old Bark records", "deleted", rowsAffected, "kept", maxBarkRecords)
 		}
 	}
 }
 
-// GetBarkRecords 获取Bark记录（支持分页）
-func (bhm *BarkHistoryManager) GetBarkRecords(taskID uint, page int, pageSize int) ([]models.BarkRecord, int64, error) {
+// cleanupExpiredRecords 删除超过保留时间的记录，等待汇总的记录保留到汇总发送
+func (bhm *BarkHistoryManager) cleanupExpiredRecords() {
+	if maxRecordAge <= 0 {
+		return
+	}
+
+	var rowsAffected int64
+	err := database.WithRetry(func(db *gorm.DB) error {
+		result := db.Where("created_at < ? AND status <> ?", time.Now().Add(-maxRecordAge), models.RecordQueued).
+			Delete(&models.BarkRecord{})
+		rowsAffected = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		slog.Error("Failed to delete expired Bark records", "error", err)
+		return
+	}
+	if rowsAffected > 0 {
+		slog.Info("Cleaned up expired Bark records", "deleted", rowsAffected, "retention", maxRecordAge)
+	}
+}
+
+// GetBarkRecords 获取筛选后的Bark记录（支持分页）
+func (bhm *BarkHistoryManager) GetBarkRecords(filter RecordFilter, page int, pageSize int) ([]models.BarkRecord, int64, error) {
 	var records []models.BarkRecord
 	var total int64
 
 	// 获取总数 - 使用重试机制
 	err := database.WithRetry(func(db *gorm.DB) error {
-		query := db.Model(&models.BarkRecord{})
-		if taskID > 0 {
-			query = query.Where("task_id = ?", taskID)
-		}
-		return query.Count(&total).Error
+		return filter.apply(db.Model(&models.BarkRecord{})).Count(&total).Error
 	})
 	if err != nil {
 		return nil, 0, err
@@ -197,11 +295,8 @@ func (bhm *BarkHistoryManager) GetBarkRecords(taskID uint, page int, pageSize in
 	// 获取分页数据 - 使用重试机制
 	offset := (page - 1) * pageSize
 	err = database.WithRetry(func(db *gorm.DB) error {
-		query := db.Model(&models.BarkRecord{})
-		if taskID > 0 {
-			query = query.Where("task_id = ?", taskID)
-		}
-		return query.Order("created_at DESC").
+		return filter.apply(db.Model(&models.BarkRecord{})).
+			Order("created_at DESC").
 			Limit(pageSize).
 			Offset(offset).
 			Find(&records).Error
@@ -213,32 +308,65 @@ func (bhm *BarkHistoryManager) GetBarkRecords(taskID uint, page int, pageSize in
 	return records, total, nil
 }
 
-// GetBarkStats 获取Bark发送统计信息
-func (bhm *BarkHistoryManager) GetBarkStats() map[string]interface{} {
+// GetBarkStats 获取筛选后的Bark发送统计信息，包含按设备和按天的统计
+func (bhm *BarkHistoryManager) GetBarkStats(filter RecordFilter, days int) map[string]interface{} {
 	var totalRecords int64
 	var successRecords int64
 	var failedRecords int64
+	var pendingRecords int64
+	var queuedRecords int64
+	var throttledRecords int64
 
 	// 总记录数 - 使用重试机制
 	database.WithRetry(func(db *gorm.DB) error {
-		return db.Model(&models.BarkRecord{}).Count(&totalRecords).Error
+		return filter.apply(db.Model(&models.BarkRecord{})).Count(&totalRecords).Error
 	})
 
 	// 成功记录数 - 使用重试机制
 	database.WithRetry(func(db *gorm.DB) error {
-		return db.Model(&models.BarkRecord{}).Where("status = ?", "success").Count(&successRecords).Error
+		return filter.apply(db.Model(&models.BarkRecord{})).Where("status = ?", "success").Count(&successRecords).Error
 	})
 
 	// 失败记录数 - 使用重试机制
 	database.WithRetry(func(db *gorm.DB) error {
-		return db.Model(&models.BarkRecord{}).Where("status = ?", "failed").Count(&failedRecords).Error
+		return filter.apply(db.Model(&models.BarkRecord{})).Where("status = ?", "failed").Count(&failedRecords).Error
+	})
+
+	// 等待投递（含重试中）记录数 - 使用重试机制
+	database.WithRetry(func(db *gorm.DB) error {
+		return filter.apply(db.Model(&models.BarkRecord{})).Where("status = ?", "pending").Count(&pendingRecords).Error
+	})
+
+	// 等待汇总记录数 - 使用重试机制
+	database.WithRetry(func(db *gorm.DB) error {
+		return filter.apply(db.Model(&models.BarkRecord{})).Where("status = ?", models.RecordQueued).Count(&queuedRecords).Error
+	})
+
+	// 超过推送限额记录数 - 使用重试机制
+	database.WithRetry(func(db *gorm.DB) error {
+		return filter.apply(db.Model(&models.BarkRecord{})).Where("status = ?", models.RecordThrottled).Count(&throttledRecords).Error
 	})
 
 	stats := map[string]interface{}{
-		"total_records":   totalRecords,
-		"success_records": successRecords,
-		"failed_records":  failedRecords,
-		"max_records":     MaxBarkRecords,
+		"total_records":     totalRecords,
+		"success_records":   successRecords,
+		"failed_records":    failedRecords,
+		"pending_records":   pendingRecords,
+		"queued_records":    queuedRecords,
+		"throttled_records": throttledRecords,
+		"max_records":       maxBarkRecords,
+		"retention_hours":   maxRecordAge.Hours(),
+	}
+
+	if byDevice, err := bhm.statsByDevice(filter); err == nil {
+		stats["by_device"] = byDevice
+	} else {
+		slog.Error("Failed to get Bark stats by device", "error", err)
+	}
+	if byDay, err := bhm.statsByDay(filter, days); err == nil {
+		stats["by_day"] = byDay
+	} else {
+		slog.Error("Failed to get Bark stats by day", "error", err)
 	}
 
 	// 获取最新和最旧记录时间
@@ -246,11 +374,11 @@ func (bhm *BarkHistoryManager) GetBarkStats() map[string]interface{} {
 		var oldestRecord, newestRecord models.BarkRecord
 
 		database.WithRetry(func(db *gorm.DB) error {
-			return db.Order("created_at asc").First(&oldestRecord).Error
+			return filter.apply(db).Order("created_at asc").First(&oldestRecord).Error
 		})
 
 		database.WithRetry(func(db *gorm.DB) error {
-			return db.Order("created_at desc").First(&newestRecord).Error
+			return filter.apply(db).Order("created_at desc").First(&newestRecord).Error
 		})
 
 		stats["oldest_record"] = oldestRecord.CreatedAt
@@ -272,10 +400,10 @@ func (bhm *BarkHistoryManager) DeleteAllBarkRecords() (int64, error) {
 	})
 
 	if err != nil {
-		log.Printf("Failed to delete all Bark records: %v", err)
+		slog.Error("Failed to delete all Bark records", "error", err)
 		return 0, err
 	}
 
-	log.Printf("Deleted all Bark records: %d records", rowsAffected)
+	slog.Info("Deleted all Bark records", "count", rowsAffected)
 	return rowsAffected, nil
 }
@@ -0,0 +1,238 @@
+package barkhistory
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"autobot/internal/database"
+	"autobot/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// recordStatuses 可用于筛选的记录状态
+var recordStatuses = []string{
+	"pending", "success", "failed", "skipped",
+	models.RecordQueued, models.RecordDigested, models.RecordThrottled,
+}
+
+// RecordFilter 发送记录筛选条件，零值字段不参与筛选
+type RecordFilter struct {
+	TaskID    uint
+	DeviceKey string
+	ChannelID uint
+	Statuses  []string
+	From      *time.Time // 包含
+	To        *time.Time // 不包含
+	Query     string     // 在标题、副标题和内容中搜索
+}
+
+// Validate 检查筛选条件中的状态和时间范围
+func (f *RecordFilter) Validate() error {
+	for _, status := range f.Statuses {
+		known := false
+		for _, s := range recordStatuses {
+			if status == s {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("unknown status %q", status)
+		}
+	}
+	if f.From != nil && f.To != nil && !f.From.Before(*f.To) {
+		return fmt.Errorf("from must be before to")
+	}
+	return nil
+}
+
+// apply 将筛选条件加到查询上
+func (f *RecordFilter) apply(query *gorm.DB) *gorm.DB {
+	if f.TaskID > 0 {
+		query = query.Where("task_id = ?", f.TaskID)
+	}
+	if f.DeviceKey != "" {
+		query = query.Where("device_key = ?", f.DeviceKey)
+	}
+	if f.ChannelID > 0 {
+		query = query.Where("channel_id = ?", f.ChannelID)
+	}
+	if len(f.Statuses) > 0 {
+		query = query.Where("status IN ?", f.Statuses)
+	}
+	if f.From != nil {
+		query = query.Where("created_at >= ?", *f.From)
+	}
+	if f.To != nil {
+		query = query.Where("created_at < ?", *f.To)
+	}
+	if q := strings.TrimSpace(f.Query); q != "" {
+		pattern := "%" + escapeLike(q) + "%"
+		query = query.Where("(title LIKE ? ESCAPE '!' OR subtitle LIKE ? ESCAPE '!' OR body LIKE ? ESCAPE '!')", pattern, pattern, pattern)
+	}
+	return query
+}
+
+// escapeLike 转义 LIKE 通配符，搜索词按原文匹配
+// 使用 ! 作为转义字符，MySQL 默认把字符串中的反斜杠当作转义符
+func escapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
+
+// DeviceStats 按设备（或通知渠道）汇总的发送统计
+type DeviceStats struct {
+	DeviceKey string `json:"device_key,omitempty"`
+	ChannelID uint   `json:"channel_id,omitempty"`
+	Name      string `json:"name"` // 设备或渠道名称，已删除时为空
+	Total     int64  `json:"total"`
+	Success   int64  `json:"success"`
+	Failed    int64  `json:"failed"`
+	Skipped   int64  `json:"skipped"`
+}
+
+// DayStats 按天汇总的发送统计
+type DayStats struct {
+	Day     string `json:"day"` // YYYY-MM-DD（UTC）
+	Total   int64  `json:"total"`
+	Success int64  `json:"success"`
+	Failed  int64  `json:"failed"`
+	Skipped int64  `json:"skipped"`
+}
+
+// statusCounts 按状态计数的聚合列
+const statusCounts = "COUNT(*) AS total, " +
+	"SUM(CASE WHEN status = 'success' THEN 1 ELSE 0 END) AS success, " +
+	"SUM(CASE WHEN status = 'failed' THEN 1 ELSE 0 END) AS failed, " +
+	"SUM(CASE WHEN status = 'skipped' THEN 1 ELSE 0 END) AS skipped"
+
+// statsByDevice 按设备和通知渠道统计筛选后的记录，按总数降序
+func (bhm *BarkHistoryManager) statsByDevice(filter RecordFilter) ([]DeviceStats, error) {
+	stats := []DeviceStats{}
+	err := database.WithRetry(func(db *gorm.DB) error {
+		return filter.apply(db.Model(&models.BarkRecord{})).
+			Select("device_key, channel_id, " + statusCounts).
+			Group("device_key, channel_id").
+			Order("total DESC").
+			Scan(&stats).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 补充设备和渠道名称
+	var devices []models.BarkDevice
+	var channels []models.NotificationChannel
+	database.WithRetry(func(db *gorm.DB) error {
+		return db.Select("device_key, name").Find(&devices).Error
+	})
+	database.WithRetry(func(db *gorm.DB) error {
+		return db.Select("id, name").Find(&channels).Error
+	})
+	deviceNames := make(map[string]string, len(devices))
+	for _, device := range devices {
+		deviceNames[device.DeviceKey] = device.Name
+	}
+	channelNames := make(map[uint]string, len(channels))
+	for _, ch := range channels {
+		channelNames[ch.ID] = ch.Name
+	}
+	for i := range stats {
+		if stats[i].ChannelID != 0 {
+			stats[i].Name = channelNames[stats[i].ChannelID]
+		} else {
+			stats[i].Name = deviceNames[stats[i].DeviceKey]
+		}
+	}
+	return stats, nil
+}
+
+// statsByDay 按天统计筛选后的记录，按日期升序；未指定开始时间时只统计最近 days 天
+func (bhm *BarkHistoryManager) statsByDay(filter RecordFilter, days int) ([]DayStats, error) {
+	if filter.From == nil {
+		from := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1-days)
+		filter.From = &from
+	}
+
+	// SQLite 中时间保存为以日期开头的文本，DATE() 无法解析，直接取前 10 个字符
+	day := "DATE(created_at)"
+	if database.Driver() == database.DriverSQLite {
+		day = "SUBSTR(created_at, 1, 10)"
+	}
+
+	stats := []DayStats{}
+	err := database.WithRetry(func(db *gorm.DB) error {
+		return filter.apply(db.Model(&models.BarkRecord{})).
+			Select(day + " AS day, " + statusCounts).
+			Group(day).
+			Order("day ASC").
+			Scan(&stats).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	// PostgreSQL 和 MySQL 的 DATE() 扫描为完整时间，只保留日期部分
+	for i := range stats {
+		if len(stats[i].Day) > 10 {
+			stats[i].Day = stats[i].Day[:10]
+		}
+	}
+	return stats, nil
+}
+
+// ExportBarkRecords 获取筛选后的记录用于导出，按时间升序，最多 limit 条
+func (bhm *BarkHistoryManager) ExportBarkRecords(filter RecordFilter, limit int) ([]models.BarkRecord, error) {
+	var records []models.BarkRecord
+	err := database.WithRetry(func(db *gorm.DB) error {
+		return filter.apply(db.Model(&models.BarkRecord{})).
+			Order("created_at ASC, id ASC").
+			Limit(limit).
+			Find(&records).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// csvColumns 导出 CSV 的列，与记录的 JSON 字段名一致
+var csvColumns = []string{
+	"id", "created_at", "task_id", "device_key", "channel_id", "status",
+	"title", "subtitle", "body", "level", "group", "url", "error_message", "digest_id", "digest_count",
+}
+
+// WriteCSV 以 CSV 格式写出记录
+func WriteCSV(w io.Writer, records []models.BarkRecord) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvColumns); err != nil {
+		return err
+	}
+	for _, r := range records {
+		row := []string{
+			strconv.FormatUint(uint64(r.ID), 10),
+			r.CreatedAt.Format(time.RFC3339),
+			strconv.FormatUint(uint64(r.TaskID), 10),
+			r.DeviceKey,
+			strconv.FormatUint(uint64(r.ChannelID), 10),
+			r.Status,
+			r.Title,
+			r.Subtitle,
+			r.Body,
+			r.Level,
+			r.Group,
+			r.URL,
+			r.ErrorMessage,
+			strconv.FormatUint(uint64(r.DigestID), 10),
+			strconv.Itoa(r.DigestCount),
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
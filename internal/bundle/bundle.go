@@ -0,0 +1,447 @@
+package bundle
+
+import (
+	"autobot/internal/database"
+	"autobot/internal/models"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+// FormatVersion 导出包格式版本
+const FormatVersion = 1
+
+// Bundle 可在实例之间迁移的任务包
+// Bark 设备、设备分组和服务器按名称引用，导入时映射为目标实例中的ID
+type Bundle struct {
+	Version     int          `json:"version"`
+	ExportedAt  time.Time    `json:"exported_at"`
+	Tasks       []TaskSpec   `json:"tasks"`
+	BarkServers []ServerSpec `json:"bark_servers,omitempty"`
+	BarkDevices []DeviceSpec `json:"bark_devices,omitempty"`
+	BarkGroups  []GroupSpec  `json:"bark_groups,omitempty"`
+}
+
+// TaskSpec 任务定义
+type TaskSpec struct {
+	Name          string                      `json:"name"`
+	Description   string                      `json:"description,omitempty"`
+	CronExpr      string                      `json:"cron_expr"`
+	Status        string                      `json:"status,omitempty"`
+	Script        string                      `json:"script"`
+	Bark          *BarkSpec                   `json:"bark,omitempty"`
+	TimeExclusion *models.TimeExclusionConfig `json:"time_exclusion,omitempty"`
+}
+
+// BarkSpec 任务的 Bark 配置，选中的设备、设备分组和通知渠道使用名称代替ID
+// 通知渠道的配置含有密钥，不随任务导出，导入时按名称匹配目标实例中已有的渠道
+type BarkSpec struct {
+	models.BarkConfig
+	Devices  []string    `json:"devices,omitempty"`  // 设备名称
+	Groups   []string    `json:"groups,omitempty"`   // 设备分组名称
+	Channels []string    `json:"channels,omitempty"` // 通知渠道名称
+	Rules    []RuleSpec  `json:"rules,omitempty"`    // 通知规则
+	Routes   []RouteSpec `json:"routes,omitempty"`   // 设备路由
+}
+
+// RuleSpec 通知规则，目标设备、设备分组和通知渠道使用名称代替ID
+type RuleSpec struct {
+	models.NotificationRule
+	Devices  []string `json:"devices,omitempty"`  // 设备名称
+	Groups   []string `json:"groups,omitempty"`   // 设备分组名称
+	Channels []string `json:"channels,omitempty"` // 通知渠道名称
+}
+
+// RouteSpec 设备路由，目标分组使用名称代替ID
+type RouteSpec struct {
+	models.BarkRoute
+	Groups []string `json:"groups,omitempty"` // 设备分组名称
+}
+
+// GroupSpec Bark 设备分组定义
+type GroupSpec struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Devices     []string `json:"devices,omitempty"` // 成员设备名称
+}
+
+// ServerSpec Bark 服务器定义
+type ServerSpec struct {
+	Name        string `json:"name"`
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+	IsDefault   bool   `json:"is_default,omitempty"`
+	Status      string `json:"status,omitempty"`
+
+	RateLimitHour int `json:"rate_limit_hour,omitempty"`
+	RateLimitDay  int `json:"rate_limit_day,omitempty"`
+
+	Fallback string `json:"fallback,omitempty"` // 备用服务器名称
+}
+
+// DeviceSpec Bark 设备定义
+type DeviceSpec struct {
+	Name        string `json:"name"`
+	DeviceKey   string `json:"device_key"`
+	Description string `json:"description,omitempty"`
+	Server      string `json:"server,omitempty"` // 服务器名称
+	IsDefault   bool   `json:"is_default,omitempty"`
+	Status      string `json:"status,omitempty"`
+
+	// 推送加密配置，见 barkcrypto
+	// 密钥和 IV 只在导出时显式要求包含密钥才会写入；导入时没有密钥则保留已有设备的密钥
+	EncryptionAlgorithm string `json:"encryption_algorithm,omitempty"`
+	EncryptionMode      string `json:"encryption_mode,omitempty"`
+	EncryptionKey       string `json:"encryption_key,omitempty"`
+	EncryptionIV        string `json:"encryption_iv,omitempty"`
+
+	// 推送限额和免打扰时段，见 throttle
+	RateLimitHour int    `json:"rate_limit_hour,omitempty"`
+	RateLimitDay  int    `json:"rate_limit_day,omitempty"`
+	QuietStart    string `json:"quiet_start,omitempty"`
+	QuietEnd      string `json:"quiet_end,omitempty"`
+	QuietMode     string `json:"quiet_mode,omitempty"`
+}
+
+// ExportOptions 导出选项
+type ExportOptions struct {
+	TaskIDs        []uint // 为空时导出全部任务
+	IncludeSecrets bool   // 包含设备的加密密钥和 IV
+}
+
+// Export 导出任务及其引用的 Bark 设备、设备分组和服务器
+// 设备的加密密钥和 IV 默认不导出，与接口不返回密钥保持一致
+func Export(opts ExportOptions) (*Bundle, error) {
+	taskIDs := opts.TaskIDs
+	var tasks []models.Task
+	err := database.WithRetry(func(db *gorm.DB) error {
+		query := db.Order("id asc")
+		if len(taskIDs) > 0 {
+			query = query.Where("id IN ?", taskIDs)
+		}
+		return query.Find(&tasks).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 收集任务引用的设备和设备分组
+	deviceIDs := make(map[uint]bool)
+	groupIDs := make(map[uint]bool)
+	for _, task := range tasks {
+		barkConfig, err := task.GetBarkConfig()
+		if err != nil {
+			continue
+		}
+		for _, id := range barkConfig.SelectedDeviceIds {
+			deviceIDs[id] = true
+		}
+		for _, id := range barkConfig.SelectedGroupIds {
+			groupIDs[id] = true
+		}
+		for _, rule := range barkConfig.Rules {
+			for _, id := range rule.SelectedDeviceIds {
+				deviceIDs[id] = true
+			}
+			for _, id := range rule.SelectedGroupIds {
+				groupIDs[id] = true
+			}
+		}
+		for _, route := range barkConfig.Routes {
+			for _, id := range route.GroupIDs {
+				groupIDs[id] = true
+			}
+		}
+	}
+
+	// 分组的成员设备一并导出
+	var groups []models.BarkDeviceGroup
+	if len(groupIDs) > 0 {
+		ids := make([]uint, 0, len(groupIDs))
+		for id := range groupIDs {
+			ids = append(ids, id)
+		}
+		err := database.WithRetry(func(db *gorm.DB) error {
+			return db.Preload("Devices").Where("id IN ?", ids).Order("id asc").Find(&groups).Error
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, group := range groups {
+			for _, device := range group.Devices {
+				deviceIDs[device.ID] = true
+			}
+		}
+	}
+
+	var devices []models.BarkDevice
+	if len(deviceIDs) > 0 {
+		ids := make([]uint, 0, len(deviceIDs))
+		for id := range deviceIDs {
+			ids = append(ids, id)
+		}
+		err := database.WithRetry(func(db *gorm.DB) error {
+			return db.Preload("Server").Where("id IN ?", ids).Order("id asc").Find(&devices).Error
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	bundle := &Bundle{
+		Version:    FormatVersion,
+		ExportedAt: time.Now(),
+		Tasks:      make([]TaskSpec, 0, len(tasks)),
+	}
+
+	channelNames := make(map[uint]string)
+	var channels []models.NotificationChannel
+	err = database.WithRetry(func(db *gorm.DB) error {
+		return db.Select("id, name").Find(&channels).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, ch := range channels {
+		channelNames[ch.ID] = ch.Name
+	}
+
+	serverNames := make(map[uint]string)
+	var servers []models.BarkServer
+	err = database.WithRetry(func(db *gorm.DB) error {
+		return db.Select("id, name").Find(&servers).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, server := range servers {
+		serverNames[server.ID] = server.Name
+	}
+
+	deviceNames := make(map[uint]string)
+	serverSeen := make(map[uint]bool)
+	for _, device := range devices {
+		deviceNames[device.ID] = device.Name
+		spec := DeviceSpec{
+			Name:        device.Name,
+			DeviceKey:   device.DeviceKey,
+			Description: device.Description,
+			IsDefault:   device.IsDefault,
+			Status:      device.Status,
+
+			EncryptionAlgorithm: device.EncryptionAlgorithm,
+			EncryptionMode:      device.EncryptionMode,
+
+			RateLimitHour: device.RateLimitHour,
+			RateLimitDay:  device.RateLimitDay,
+			QuietStart:    device.QuietStart,
+			QuietEnd:      device.QuietEnd,
+			QuietMode:     device.QuietMode,
+		}
+		if opts.IncludeSecrets {
+			spec.EncryptionKey = device.EncryptionKey
+			// 只有 CBC 使用固定 IV，之前保存的 GCM 固定 IV 已不再使用
+			if device.EncryptionMode == models.BarkEncryptionCBC {
+				spec.EncryptionIV = device.EncryptionIV
+			}
+		}
+		if device.ServerID != 0 && device.Server.ID != 0 {
+			spec.Server = device.Server.Name
+			if !serverSeen[device.ServerID] {
+				serverSeen[device.ServerID] = true
+				bundle.BarkServers = append(bundle.BarkServers, ServerSpec{
+					Name:        device.Server.Name,
+					URL:         device.Server.URL,
+					Description: device.Server.Description,
+					IsDefault:   device.Server.IsDefault,
+					Status:      device.Server.Status,
+
+					RateLimitHour: device.Server.RateLimitHour,
+					RateLimitDay:  device.Server.RateLimitDay,
+
+					Fallback: serverNames[device.Server.FallbackServerID],
+				})
+			}
+		}
+		bundle.BarkDevices = append(bundle.BarkDevices, spec)
+	}
+
+	groupNames := make(map[uint]string)
+	for _, group := range groups {
+		groupNames[group.ID] = group.Name
+		spec := GroupSpec{Name: group.Name, Description: group.Description}
+		for _, device := range group.Devices {
+			spec.Devices = append(spec.Devices, device.Name)
+		}
+		bundle.BarkGroups = append(bundle.BarkGroups, spec)
+	}
+
+	for _, task := range tasks {
+		spec := TaskSpec{
+			Name:        task.Name,
+			Description: task.Description,
+			CronExpr:    task.CronExpr,
+			Status:      task.Status,
+			Script:      task.Script,
+		}
+
+		if task.BarkConfig != "" {
+			barkConfig, err := task.GetBarkConfig()
+			if err != nil {
+				return nil, fmt.Errorf("task %s has invalid bark config: %v", task.Name, err)
+			}
+			barkSpec := &BarkSpec{BarkConfig: *barkConfig}
+			for _, id := range barkConfig.SelectedDeviceIds {
+				if name, ok := deviceNames[id]; ok {
+					barkSpec.Devices = append(barkSpec.Devices, name)
+				}
+			}
+			barkSpec.SelectedDeviceIds = nil
+			barkSpec.Groups = namesOf(barkConfig.SelectedGroupIds, groupNames)
+			barkSpec.SelectedGroupIds = nil
+			for _, id := range barkConfig.SelectedChannelIds {
+				if name, ok := channelNames[id]; ok {
+					barkSpec.Channels = append(barkSpec.Channels, name)
+				}
+			}
+			barkSpec.SelectedChannelIds = nil
+			for _, rule := range barkConfig.Rules {
+				ruleSpec := RuleSpec{NotificationRule: rule}
+				for _, id := range rule.SelectedDeviceIds {
+					if name, ok := deviceNames[id]; ok {
+						ruleSpec.Devices = append(ruleSpec.Devices, name)
+					}
+				}
+				for _, id := range rule.SelectedChannelIds {
+					if name, ok := channelNames[id]; ok {
+						ruleSpec.Channels = append(ruleSpec.Channels, name)
+					}
+				}
+				ruleSpec.Groups = namesOf(rule.SelectedGroupIds, groupNames)
+				ruleSpec.SelectedDeviceIds = nil
+				ruleSpec.SelectedGroupIds = nil
+				ruleSpec.SelectedChannelIds = nil
+				barkSpec.Rules = append(barkSpec.Rules, ruleSpec)
+			}
+			barkSpec.BarkConfig.Rules = nil
+			for _, route := range barkConfig.Routes {
+				routeSpec := RouteSpec{BarkRoute: route, Groups: namesOf(route.GroupIDs, groupNames)}
+				routeSpec.GroupIDs = nil
+				barkSpec.Routes = append(barkSpec.Routes, routeSpec)
+			}
+			barkSpec.BarkConfig.Routes = nil
+			spec.Bark = barkSpec
+		}
+
+		if task.TimeExclusionConfig != "" {
+			exclusion, err := task.GetTimeExclusionConfig()
+			if err != nil {
+				return nil, fmt.Errorf("task %s has invalid time exclusion config: %v", task.Name, err)
+			}
+			spec.TimeExclusion = exclusion
+		}
+
+		bundle.Tasks = append(bundle.Tasks, spec)
+	}
+
+	return bundle, nil
+}
+
+// namesOf 将ID映射为名称，丢弃找不到的ID
+func namesOf(ids []uint, names map[uint]string) []string {
+	var result []string
+	for _, id := range ids {
+		if name, ok := names[id]; ok {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// Marshal 按格式序列化，format 为 yaml 或 json
+// YAML 经由 JSON 转换，保证两种格式字段名一致
+func (b *Bundle) Marshal(format string) ([]byte, error) {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if format != "yaml" {
+		return data, nil
+	}
+
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(prune(generic))
+}
+
+// prune 去掉空字符串和 null 字段，让 YAML 只保留有意义的配置
+func prune(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if item == nil || item == "" {
+				delete(v, key)
+				continue
+			}
+			v[key] = prune(item)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = prune(item)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// Parse 解析 YAML 或 JSON 格式的任务包
+func Parse(data []byte, format string) (*Bundle, error) {
+	if format == "yaml" {
+		converted, err := YAMLToJSON(data)
+		if err != nil {
+			return nil, err
+		}
+		data = converted
+	}
+
+	var bundle Bundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return nil, fmt.Errorf("invalid bundle: %v", err)
+	}
+	if bundle.Version > FormatVersion {
+		return nil, fmt.Errorf("bundle version %d is not supported (max %d)", bundle.Version, FormatVersion)
+	}
+	return &bundle, nil
+}
+
+// YAMLToJSON 将 YAML 转换为 JSON，使 YAML 和 JSON 共用同一套 json 字段名
+func YAMLToJSON(data []byte) ([]byte, error) {
+	var generic interface{}
+	if err := yaml.Unmarshal(data, &generic); err != nil {
+		return nil, fmt.Errorf("invalid yaml: %v", err)
+	}
+	return json.Marshal(generic)
+}
+
+// DetectFormat 根据 Content-Type 或内容判断格式
+func DetectFormat(contentType string, data []byte) string {
+	if strings.Contains(contentType, "yaml") {
+		return "yaml"
+	}
+	if strings.Contains(contentType, "json") {
+		return "json"
+	}
+	trimmed := strings.TrimSpace(string(data))
+	if strings.HasPrefix(trimmed, "{") {
+		return "json"
+	}
+	return "yaml"
+}
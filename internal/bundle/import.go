@@ -0,0 +1,801 @@
+package bundle
+
+import (
+	"autobot/internal/barkcrypto"
+	"autobot/internal/barkhistory"
+	"autobot/internal/database"
+	"autobot/internal/digest"
+	"autobot/internal/executor"
+	"autobot/internal/models"
+	"autobot/internal/msgtemplate"
+	"autobot/internal/rules"
+	"autobot/internal/throttle"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/robfig/cron/v3"
+	"gorm.io/gorm"
+)
+
+// 冲突处理策略
+const (
+	OnConflictSkip      = "skip"      // 保留已有记录，引用指向已有记录
+	OnConflictOverwrite = "overwrite" // 用包中的定义覆盖已有记录
+	OnConflictRename    = "rename"    // 以新名称创建
+)
+
+// 导入动作
+const (
+	ActionCreate    = "create"
+	ActionSkip      = "skip"
+	ActionOverwrite = "overwrite"
+	ActionRename    = "rename"
+	ActionError     = "error"
+)
+
+// ImportOptions 导入选项
+type ImportOptions struct {
+	DryRun     bool   // 只报告冲突和计划，不写入
+	OnConflict string // skip, overwrite, rename
+}
+
+// ItemResult 单个对象的导入结果
+type ItemResult struct {
+	Name       string `json:"name"`
+	Action     string `json:"action"`
+	Conflict   bool   `json:"conflict"`              // 目标实例中已存在同名（或同密钥）对象
+	NewName    string `json:"new_name,omitempty"`    // rename 时的新名称
+	ExistingID uint   `json:"existing_id,omitempty"` // 冲突的已有对象ID
+	Message    string `json:"message,omitempty"`
+}
+
+// ImportReport 导入报告
+type ImportReport struct {
+	DryRun     bool         `json:"dry_run"`
+	OnConflict string       `json:"on_conflict"`
+	Servers    []ItemResult `json:"bark_servers"`
+	Devices    []ItemResult `json:"bark_devices"`
+	Groups     []ItemResult `json:"bark_groups"`
+	Tasks      []ItemResult `json:"tasks"`
+
+	// 实际创建或更新的任务，供调用方同步调度器
+	ChangedTasks []models.Task `json:"-"`
+}
+
+// HasErrors 是否有无法导入的对象
+func (r *ImportReport) HasErrors() bool {
+	for _, items := range [][]ItemResult{r.Servers, r.Devices, r.Groups, r.Tasks} {
+		for _, item := range items {
+			if item.Action == ActionError {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Import 导入任务包
+// 所有写入在同一事务中完成，任何错误都会回滚；DryRun 时同样执行全部检查但最终回滚
+func Import(bundle *Bundle, opts ImportOptions) (*ImportReport, error) {
+	switch opts.OnConflict {
+	case "":
+		opts.OnConflict = OnConflictSkip
+	case OnConflictSkip, OnConflictOverwrite, OnConflictRename:
+	default:
+		return nil, fmt.Errorf("invalid on_conflict: %s", opts.OnConflict)
+	}
+
+	report := &ImportReport{
+		DryRun:     opts.DryRun,
+		OnConflict: opts.OnConflict,
+		Servers:    []ItemResult{},
+		Devices:    []ItemResult{},
+		Groups:     []ItemResult{},
+		Tasks:      []ItemResult{},
+	}
+
+	// 脚本检查会启动 python 进程，在事务外完成，重试时也不再重复
+	scripts := ValidateScripts(bundle.Tasks, nil)
+
+	// errDryRun 用于在预演结束时回滚事务
+	errDryRun := fmt.Errorf("dry run")
+
+	err := database.WithRetry(func(db *gorm.DB) error {
+		// 重试时重新生成报告
+		report.Servers = report.Servers[:0]
+		report.Devices = report.Devices[:0]
+		report.Groups = report.Groups[:0]
+		report.Tasks = report.Tasks[:0]
+		report.ChangedTasks = nil
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			im := &importer{tx: tx, opts: opts, report: report, scripts: scripts}
+			if err := im.run(bundle); err != nil {
+				return err
+			}
+			if opts.DryRun || report.HasErrors() {
+				return errDryRun
+			}
+			return nil
+		})
+		if err == errDryRun {
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if report.HasErrors() {
+		report.ChangedTasks = nil
+	}
+	return report, nil
+}
+
+// importer 单次导入的状态
+type importer struct {
+	tx      *gorm.DB
+	opts    ImportOptions
+	report  *ImportReport
+	scripts ScriptErrors // 事务外完成的脚本语法检查结果
+
+	serverIDs map[string]uint // 包内服务器名称 -> 目标实例ID
+	deviceIDs map[string]uint // 包内设备名称 -> 目标实例ID
+	groupIDs  map[string]uint // 包内设备分组名称 -> 目标实例ID
+}
+
+func (im *importer) run(bundle *Bundle) error {
+	im.serverIDs = make(map[string]uint)
+	im.deviceIDs = make(map[string]uint)
+	im.groupIDs = make(map[string]uint)
+
+	for _, spec := range bundle.BarkServers {
+		if err := im.importServer(spec); err != nil {
+			return err
+		}
+	}
+	// 备用服务器可能在包中排在后面，所有服务器导入后再设置
+	for i, spec := range bundle.BarkServers {
+		if err := im.linkFallback(spec, &im.report.Servers[i]); err != nil {
+			return err
+		}
+	}
+	for _, spec := range bundle.BarkDevices {
+		if err := im.importDevice(spec); err != nil {
+			return err
+		}
+	}
+	for _, spec := range bundle.BarkGroups {
+		if err := im.importGroup(spec); err != nil {
+			return err
+		}
+	}
+	for _, spec := range bundle.Tasks {
+		if err := im.importTask(spec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// uniqueName 生成目标实例中不存在的名称
+func (im *importer) uniqueName(model interface{}, name string) (string, error) {
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s (imported)", name)
+		if i > 1 {
+			candidate = fmt.Sprintf("%s (imported %d)", name, i)
+		}
+		var count int64
+		if err := im.tx.Model(model).Where("name = ?", candidate).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+	}
+}
+
+func (im *importer) importServer(spec ServerSpec) error {
+	result := ItemResult{Name: spec.Name}
+	if spec.Name == "" || spec.URL == "" {
+		result.Action = ActionError
+		result.Message = "name and url are required"
+		im.report.Servers = append(im.report.Servers, result)
+		return nil
+	}
+
+	server := models.BarkServer{
+		Name:        spec.Name,
+		URL:         spec.URL,
+		Description: spec.Description,
+		IsDefault:   spec.IsDefault,
+		Status:      defaultString(spec.Status, "active"),
+
+		RateLimitHour: spec.RateLimitHour,
+		RateLimitDay:  spec.RateLimitDay,
+	}
+
+	var existing models.BarkServer
+	err := im.tx.Where("name = ?", spec.Name).First(&existing).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return err
+	}
+
+	if err == gorm.ErrRecordNotFound {
+		result.Action = ActionCreate
+		// 导入的服务器不抢占目标实例已有的默认服务器
+		if server.IsDefault {
+			var defaults int64
+			if err := im.tx.Model(&models.BarkServer{}).Where("is_default = true").Count(&defaults).Error; err != nil {
+				return err
+			}
+			server.IsDefault = defaults == 0
+		}
+		if err := im.tx.Create(&server).Error; err != nil {
+			return err
+		}
+		im.serverIDs[spec.Name] = server.ID
+		im.report.Servers = append(im.report.Servers, result)
+		return nil
+	}
+
+	result.Conflict = true
+	result.ExistingID = existing.ID
+	switch im.opts.OnConflict {
+	case OnConflictOverwrite:
+		result.Action = ActionOverwrite
+		existing.URL = server.URL
+		existing.Description = server.Description
+		existing.Status = server.Status
+		existing.RateLimitHour = server.RateLimitHour
+		existing.RateLimitDay = server.RateLimitDay
+		if err := im.tx.Save(&existing).Error; err != nil {
+			return err
+		}
+		im.serverIDs[spec.Name] = existing.ID
+	case OnConflictRename:
+		result.Action = ActionRename
+		newName, err := im.uniqueName(&models.BarkServer{}, spec.Name)
+		if err != nil {
+			return err
+		}
+		result.NewName = newName
+		server.Name = newName
+		server.IsDefault = false
+		if err := im.tx.Create(&server).Error; err != nil {
+			return err
+		}
+		im.serverIDs[spec.Name] = server.ID
+	default:
+		result.Action = ActionSkip
+		im.serverIDs[spec.Name] = existing.ID
+	}
+
+	im.report.Servers = append(im.report.Servers, result)
+	return nil
+}
+
+// linkFallback 设置新建或覆盖的服务器的备用服务器，按名称在包内或目标实例中查找
+func (im *importer) linkFallback(spec ServerSpec, result *ItemResult) error {
+	if spec.Fallback == "" || result.Action == ActionError || result.Action == ActionSkip {
+		return nil
+	}
+	fallbackID, ok := im.serverIDs[spec.Fallback]
+	if !ok {
+		var server models.BarkServer
+		if err := im.tx.Where("name = ?", spec.Fallback).First(&server).Error; err != nil {
+			result.Message = fmt.Sprintf("fallback server %q not found and was dropped", spec.Fallback)
+			return nil
+		}
+		fallbackID = server.ID
+	}
+	serverID := im.serverIDs[spec.Name]
+	if fallbackID == serverID {
+		return nil
+	}
+	return im.tx.Model(&models.BarkServer{}).Where("id = ?", serverID).Update("fallback_server_id", fallbackID).Error
+}
+
+func (im *importer) importDevice(spec DeviceSpec) error {
+	result := ItemResult{Name: spec.Name}
+	if spec.Name == "" || spec.DeviceKey == "" {
+		result.Action = ActionError
+		result.Message = "name and device_key are required"
+		im.report.Devices = append(im.report.Devices, result)
+		return nil
+	}
+	// 导出默认不包含密钥：新建的设备不启用加密，覆盖已有设备时保留其加密配置
+	withoutKey := spec.EncryptionAlgorithm != "" && spec.EncryptionKey == ""
+	if withoutKey {
+		spec.EncryptionAlgorithm, spec.EncryptionMode, spec.EncryptionIV = "", "", ""
+	} else if err := barkcrypto.Validate(spec.EncryptionAlgorithm, spec.EncryptionMode, spec.EncryptionKey, spec.EncryptionIV); err != nil {
+		result.Action = ActionError
+		result.Message = "invalid encryption settings: " + err.Error()
+		im.report.Devices = append(im.report.Devices, result)
+		return nil
+	}
+	if err := throttle.ValidateQuietHours(spec.QuietStart, spec.QuietEnd); err != nil {
+		result.Action = ActionError
+		result.Message = "invalid quiet hours: " + err.Error()
+		im.report.Devices = append(im.report.Devices, result)
+		return nil
+	}
+
+	device := models.BarkDevice{
+		Name:        spec.Name,
+		DeviceKey:   spec.DeviceKey,
+		Description: spec.Description,
+		IsDefault:   false, // 不抢占目标实例的默认设备
+		Status:      defaultString(spec.Status, "active"),
+
+		EncryptionAlgorithm: spec.EncryptionAlgorithm,
+		EncryptionMode:      spec.EncryptionMode,
+		EncryptionKey:       spec.EncryptionKey,
+		EncryptionIV:        spec.EncryptionIV,
+
+		RateLimitHour: spec.RateLimitHour,
+		RateLimitDay:  spec.RateLimitDay,
+		QuietStart:    spec.QuietStart,
+		QuietEnd:      spec.QuietEnd,
+		QuietMode:     spec.QuietMode,
+	}
+	device.NormalizeQuietHours()
+	if spec.Server != "" {
+		serverID, ok := im.serverIDs[spec.Server]
+		if !ok {
+			// 包中没有该服务器的定义，尝试按名称匹配目标实例中的服务器
+			var server models.BarkServer
+			if err := im.tx.Where("name = ?", spec.Server).First(&server).Error; err == nil {
+				serverID = server.ID
+			} else {
+				result.Message = fmt.Sprintf("server %q not found, device will use the default server", spec.Server)
+			}
+		}
+		device.ServerID = serverID
+	}
+
+	// 设备密钥唯一，同密钥视为同一设备；否则按名称判断冲突
+	var existing models.BarkDevice
+	err := im.tx.Where("device_key = ?", spec.DeviceKey).First(&existing).Error
+	if err == gorm.ErrRecordNotFound {
+		err = im.tx.Where("name = ?", spec.Name).First(&existing).Error
+	}
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return err
+	}
+
+	if err == gorm.ErrRecordNotFound {
+		result.Action = ActionCreate
+		if withoutKey {
+			result.Message = JoinMessages(result.Message, "encryption key not included in bundle, encryption is disabled for this device")
+		}
+		if err := im.tx.Create(&device).Error; err != nil {
+			return err
+		}
+		im.deviceIDs[spec.Name] = device.ID
+		im.report.Devices = append(im.report.Devices, result)
+		return nil
+	}
+
+	result.Conflict = true
+	result.ExistingID = existing.ID
+	sameKey := existing.DeviceKey == spec.DeviceKey
+	switch {
+	case im.opts.OnConflict == OnConflictOverwrite:
+		result.Action = ActionOverwrite
+		existing.Name = device.Name
+		existing.DeviceKey = device.DeviceKey
+		existing.Description = device.Description
+		existing.Status = device.Status
+		if !withoutKey {
+			existing.EncryptionAlgorithm = device.EncryptionAlgorithm
+			existing.EncryptionMode = device.EncryptionMode
+			existing.EncryptionKey = device.EncryptionKey
+			existing.EncryptionIV = device.EncryptionIV
+		}
+		existing.RateLimitHour = device.RateLimitHour
+		existing.RateLimitDay = device.RateLimitDay
+		existing.QuietStart = device.QuietStart
+		existing.QuietEnd = device.QuietEnd
+		existing.QuietMode = device.QuietMode
+		if device.ServerID != 0 {
+			existing.ServerID = device.ServerID
+		}
+		if err := im.tx.Save(&existing).Error; err != nil {
+			return err
+		}
+		im.deviceIDs[spec.Name] = existing.ID
+	case im.opts.OnConflict == OnConflictRename && !sameKey:
+		result.Action = ActionRename
+		newName, err := im.uniqueName(&models.BarkDevice{}, spec.Name)
+		if err != nil {
+			return err
+		}
+		result.NewName = newName
+		device.Name = newName
+		if withoutKey {
+			result.Message = JoinMessages(result.Message, "encryption key not included in bundle, encryption is disabled for this device")
+		}
+		if err := im.tx.Create(&device).Error; err != nil {
+			return err
+		}
+		im.deviceIDs[spec.Name] = device.ID
+	default:
+		result.Action = ActionSkip
+		if im.opts.OnConflict == OnConflictRename {
+			result.Message = "device key already exists, using the existing device"
+		}
+		im.deviceIDs[spec.Name] = existing.ID
+	}
+
+	im.report.Devices = append(im.report.Devices, result)
+	return nil
+}
+
+// resolveDevice 按名称查找设备，优先使用本次导入的设备
+func (im *importer) resolveDevice(name string) (uint, bool) {
+	if id, ok := im.deviceIDs[name]; ok {
+		return id, true
+	}
+	var device models.BarkDevice
+	if err := im.tx.Where("name = ?", name).First(&device).Error; err != nil {
+		return 0, false
+	}
+	return device.ID, true
+}
+
+func (im *importer) importGroup(spec GroupSpec) error {
+	result := ItemResult{Name: spec.Name}
+	if spec.Name == "" {
+		result.Action = ActionError
+		result.Message = "name is required"
+		im.report.Groups = append(im.report.Groups, result)
+		return nil
+	}
+
+	var members []models.BarkDevice
+	for _, name := range spec.Devices {
+		id, ok := im.resolveDevice(name)
+		if !ok {
+			result.Message = fmt.Sprintf("device %q not found and was dropped", name)
+			continue
+		}
+		members = append(members, models.BarkDevice{ID: id})
+	}
+	group := models.BarkDeviceGroup{Name: spec.Name, Description: spec.Description}
+
+	var existing models.BarkDeviceGroup
+	err := im.tx.Where("name = ?", spec.Name).First(&existing).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return err
+	}
+
+	if err == gorm.ErrRecordNotFound {
+		result.Action = ActionCreate
+		if err := im.createGroup(&group, members); err != nil {
+			return err
+		}
+		im.groupIDs[spec.Name] = group.ID
+		im.report.Groups = append(im.report.Groups, result)
+		return nil
+	}
+
+	result.Conflict = true
+	result.ExistingID = existing.ID
+	switch im.opts.OnConflict {
+	case OnConflictOverwrite:
+		result.Action = ActionOverwrite
+		existing.Description = group.Description
+		if err := im.tx.Omit("Devices").Save(&existing).Error; err != nil {
+			return err
+		}
+		if err := im.tx.Model(&existing).Association("Devices").Replace(members); err != nil {
+			return err
+		}
+		im.groupIDs[spec.Name] = existing.ID
+	case OnConflictRename:
+		result.Action = ActionRename
+		newName, err := im.uniqueName(&models.BarkDeviceGroup{}, spec.Name)
+		if err != nil {
+			return err
+		}
+		result.NewName = newName
+		group.Name = newName
+		if err := im.createGroup(&group, members); err != nil {
+			return err
+		}
+		im.groupIDs[spec.Name] = group.ID
+	default:
+		result.Action = ActionSkip
+		im.groupIDs[spec.Name] = existing.ID
+	}
+
+	im.report.Groups = append(im.report.Groups, result)
+	return nil
+}
+
+// createGroup 创建分组并关联成员设备
+func (im *importer) createGroup(group *models.BarkDeviceGroup, members []models.BarkDevice) error {
+	if err := im.tx.Omit("Devices").Create(group).Error; err != nil {
+		return err
+	}
+	if len(members) == 0 {
+		return nil
+	}
+	return im.tx.Model(group).Association("Devices").Append(members)
+}
+
+func (im *importer) importTask(spec TaskSpec) error {
+	result := ItemResult{Name: spec.Name}
+
+	task, warning, err := spec.ToTask(im.scripts[spec.Script], im.resolveDevice, func(name string) (uint, bool) {
+		if id, ok := im.groupIDs[name]; ok {
+			return id, true
+		}
+		return GroupResolver(im.tx)(name)
+	}, ChannelResolver(im.tx))
+	if err != nil {
+		result.Action = ActionError
+		result.Message = JoinMessages(err.Error(), warning)
+		im.report.Tasks = append(im.report.Tasks, result)
+		return nil
+	}
+	result.Message = warning
+
+	var existing models.Task
+	err = im.tx.Where("name = ?", spec.Name).First(&existing).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return err
+	}
+
+	if err == gorm.ErrRecordNotFound {
+		result.Action = ActionCreate
+		if err := im.tx.Create(&task).Error; err != nil {
+			return err
+		}
+		im.report.ChangedTasks = append(im.report.ChangedTasks, task)
+		im.report.Tasks = append(im.report.Tasks, result)
+		return nil
+	}
+
+	result.Conflict = true
+	result.ExistingID = existing.ID
+	switch {
+	case im.opts.OnConflict == OnConflictOverwrite && existing.IsReadOnly():
+		// 由 Git 仓库管理的任务只能通过仓库修改
+		result.Action = ActionSkip
+		result.Message = "task is managed by git sync and was not overwritten"
+	case im.opts.OnConflict == OnConflictOverwrite:
+		result.Action = ActionOverwrite
+		existing.Description = task.Description
+		existing.Script = task.Script
+		existing.CronExpr = task.CronExpr
+		existing.Status = task.Status
+		existing.BarkConfig = task.BarkConfig
+		existing.TimeExclusionConfig = task.TimeExclusionConfig
+		if err := im.tx.Save(&existing).Error; err != nil {
+			return err
+		}
+		im.report.ChangedTasks = append(im.report.ChangedTasks, existing)
+	case im.opts.OnConflict == OnConflictRename:
+		result.Action = ActionRename
+		newName, err := im.uniqueName(&models.Task{}, spec.Name)
+		if err != nil {
+			return err
+		}
+		result.NewName = newName
+		task.Name = newName
+		if err := im.tx.Create(&task).Error; err != nil {
+			return err
+		}
+		im.report.ChangedTasks = append(im.report.ChangedTasks, task)
+	default:
+		result.Action = ActionSkip
+	}
+
+	im.report.Tasks = append(im.report.Tasks, result)
+	return nil
+}
+
+// ScriptErrors 脚本的 Python 语法检查结果，按脚本内容索引，nil 表示检查通过
+type ScriptErrors map[string]error
+
+// ValidateScripts 检查所有任务脚本的 Python 语法
+// 每次检查都会启动 python 进程，必须在打开事务之前调用，避免长时间占用写锁
+// 相同的脚本只检查一次；known 中已通过检查的脚本不再检查，失败的重新检查，避免缓存临时错误
+func ValidateScripts(specs []TaskSpec, known ScriptErrors) ScriptErrors {
+	results := make(ScriptErrors, len(specs))
+	for _, spec := range specs {
+		if spec.Script == "" {
+			continue
+		}
+		if _, ok := results[spec.Script]; ok {
+			continue
+		}
+		if err, ok := known[spec.Script]; ok && err == nil {
+			results[spec.Script] = nil
+			continue
+		}
+		results[spec.Script] = executor.ValidatePythonScript(spec.Script)
+	}
+	return results
+}
+
+// ToTask 校验任务定义并转换为任务模型
+// scriptErr 为事先通过 ValidateScripts 得到的脚本语法检查结果
+// resolveDevice、resolveGroup、resolveChannel 将设备、设备分组和通知渠道名称映射为ID，
+// 找不到的被丢弃并在 warning 中说明
+func (spec TaskSpec) ToTask(scriptErr error, resolveDevice, resolveGroup, resolveChannel func(name string) (uint, bool)) (models.Task, string, error) {
+	var warning string
+
+	if spec.Name == "" || spec.Script == "" || spec.CronExpr == "" {
+		return models.Task{}, "", fmt.Errorf("name, script and cron_expr are required")
+	}
+
+	parser := cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+	if _, err := parser.Parse(spec.CronExpr); err != nil {
+		return models.Task{}, "", fmt.Errorf("invalid cron expression: %v", err)
+	}
+	task := models.Task{
+		Name:        spec.Name,
+		Description: spec.Description,
+		Script:      spec.Script,
+		CronExpr:    spec.CronExpr,
+		Status:      defaultString(spec.Status, "inactive"),
+	}
+
+	if spec.Bark != nil {
+		barkConfig := spec.Bark.BarkConfig
+		barkConfig.SelectedDeviceIds = nil
+		for _, name := range spec.Bark.Devices {
+			id, ok := resolveDevice(name)
+			if !ok {
+				warning = fmt.Sprintf("device %q not found and was dropped", name)
+				continue
+			}
+			barkConfig.SelectedDeviceIds = append(barkConfig.SelectedDeviceIds, id)
+		}
+		barkConfig.SelectedGroupIds = nil
+		for _, name := range spec.Bark.Groups {
+			id, ok := resolveGroup(name)
+			if !ok {
+				warning = fmt.Sprintf("device group %q not found and was dropped", name)
+				continue
+			}
+			barkConfig.SelectedGroupIds = append(barkConfig.SelectedGroupIds, id)
+		}
+		barkConfig.SelectedChannelIds = nil
+		for _, name := range spec.Bark.Channels {
+			id, ok := resolveChannel(name)
+			if !ok {
+				warning = fmt.Sprintf("channel %q not found and was dropped", name)
+				continue
+			}
+			barkConfig.SelectedChannelIds = append(barkConfig.SelectedChannelIds, id)
+		}
+		barkConfig.Rules = nil
+		for _, ruleSpec := range spec.Bark.Rules {
+			rule := ruleSpec.NotificationRule
+			rule.SelectedDeviceIds = nil
+			rule.SelectedGroupIds = nil
+			rule.SelectedChannelIds = nil
+			for _, name := range ruleSpec.Devices {
+				id, ok := resolveDevice(name)
+				if !ok {
+					warning = fmt.Sprintf("device %q not found and was dropped", name)
+					continue
+				}
+				rule.SelectedDeviceIds = append(rule.SelectedDeviceIds, id)
+			}
+			for _, name := range ruleSpec.Groups {
+				id, ok := resolveGroup(name)
+				if !ok {
+					warning = fmt.Sprintf("device group %q not found and was dropped", name)
+					continue
+				}
+				rule.SelectedGroupIds = append(rule.SelectedGroupIds, id)
+			}
+			for _, name := range ruleSpec.Channels {
+				id, ok := resolveChannel(name)
+				if !ok {
+					warning = fmt.Sprintf("channel %q not found and was dropped", name)
+					continue
+				}
+				rule.SelectedChannelIds = append(rule.SelectedChannelIds, id)
+			}
+			barkConfig.Rules = append(barkConfig.Rules, rule)
+		}
+		barkConfig.Routes = nil
+		for _, routeSpec := range spec.Bark.Routes {
+			route := routeSpec.BarkRoute
+			route.GroupIDs = nil
+			for _, name := range routeSpec.Groups {
+				id, ok := resolveGroup(name)
+				if !ok {
+					warning = fmt.Sprintf("device group %q not found and was dropped", name)
+					continue
+				}
+				route.GroupIDs = append(route.GroupIDs, id)
+			}
+			barkConfig.Routes = append(barkConfig.Routes, route)
+		}
+		if err := rules.Validate(barkConfig.Rules); err != nil {
+			return models.Task{}, "", err
+		}
+		if err := rules.ValidateRoutes(barkConfig.Routes); err != nil {
+			return models.Task{}, "", fmt.Errorf("invalid routes: %v", err)
+		}
+		if err := msgtemplate.ValidateConfig(&barkConfig); err != nil {
+			return models.Task{}, "", fmt.Errorf("invalid template: %v", err)
+		}
+		if err := digest.Validate(&barkConfig.Digest); err != nil {
+			return models.Task{}, "", fmt.Errorf("invalid digest: %v", err)
+		}
+		if err := barkhistory.ValidateDeduplication(&barkConfig.Deduplication); err != nil {
+			return models.Task{}, "", fmt.Errorf("invalid deduplication: %v", err)
+		}
+		data, err := json.Marshal(barkConfig)
+		if err != nil {
+			return models.Task{}, "", err
+		}
+		task.BarkConfig = string(data)
+	}
+
+	if spec.TimeExclusion != nil {
+		data, err := json.Marshal(spec.TimeExclusion)
+		if err != nil {
+			return models.Task{}, "", err
+		}
+		task.TimeExclusionConfig = string(data)
+	}
+
+	// 与任务表单相同的 Python 语法检查，避免导入每次运行都会失败的任务
+	if scriptErr != nil {
+		return models.Task{}, warning, fmt.Errorf("invalid script: %v", scriptErr)
+	}
+
+	return task, warning, nil
+}
+
+// JoinMessages 合并错误和警告信息，忽略空的部分
+func JoinMessages(messages ...string) string {
+	var parts []string
+	for _, message := range messages {
+		if message != "" {
+			parts = append(parts, message)
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// defaultString 空字符串时返回默认值
+func defaultString(value string, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
+
+// ChannelResolver 按名称在 db 中查找通知渠道，用于 ToTask
+func ChannelResolver(db *gorm.DB) func(name string) (uint, bool) {
+	return func(name string) (uint, bool) {
+		var ch models.NotificationChannel
+		if err := db.Where("name = ?", name).First(&ch).Error; err != nil {
+			return 0, false
+		}
+		return ch.ID, true
+	}
+}
+
+// GroupResolver 按名称在 db 中查找设备分组，用于 ToTask
+func GroupResolver(db *gorm.DB) func(name string) (uint, bool) {
+	return func(name string) (uint, bool) {
+		var group models.BarkDeviceGroup
+		if err := db.Where("name = ?", name).First(&group).Error; err != nil {
+			return 0, false
+		}
+		return group.ID, true
+	}
+}
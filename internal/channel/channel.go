@@ -0,0 +1,190 @@
+package channel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"autobot/internal/models"
+)
+
+// Message 发送到渠道的通知内容，字段已完成 $placeholder 替换
+type Message struct {
+	Title    string
+	Subtitle string
+	Body     string
+	URL      string
+	Level    string // Bark 通知级别：active, timeSensitive, passive, critical，用于映射各渠道的优先级
+	Group    string
+	TaskID   uint
+	TaskName string
+	Result   map[string]interface{} // 脚本返回的 JSON 结果，供 Webhook 模板使用
+}
+
+// Text 纯文本形式：标题、副标题、正文和链接各占一段
+func (m Message) Text() string {
+	var parts []string
+	for _, part := range []string{m.Title, m.Subtitle, m.Body, m.URL} {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// Channel 通知渠道
+type Channel interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// factory 解析并校验渠道配置
+type factory func(config []byte) (Channel, error)
+
+// factories 渠道类型 -> 构造函数
+var factories = map[string]factory{
+	models.ChannelWebhook:  newWebhook,
+	models.ChannelEmail:    newEmail,
+	models.ChannelTelegram: newTelegram,
+	models.ChannelSlack:    newSlack,
+	models.ChannelDingTalk: newDingTalk,
+	models.ChannelWeCom:    newWeCom,
+	models.ChannelFeishu:   newFeishu,
+	models.ChannelNtfy:     newNtfy,
+	models.ChannelGotify:   newGotify,
+}
+
+// Types 支持的渠道类型
+func Types() []string {
+	types := make([]string, 0, len(factories))
+	for t := range factories {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// New 根据类型和 JSON 配置创建渠道，配置无效时返回错误
+func New(channelType, config string) (Channel, error) {
+	create, ok := factories[channelType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported channel type: %s", channelType)
+	}
+	if strings.TrimSpace(config) == "" {
+		config = "{}"
+	}
+	return create([]byte(config))
+}
+
+// Validate 校验渠道配置
+func Validate(channelType, config string) error {
+	_, err := New(channelType, config)
+	return err
+}
+
+// decode 解析渠道配置，拒绝未知字段以便发现拼写错误
+func decode(config []byte, target interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(config))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(target); err != nil {
+		return fmt.Errorf("invalid channel config: %v", err)
+	}
+	return nil
+}
+
+// httpClient 渠道请求使用的 HTTP 客户端
+var httpClient = &http.Client{Timeout: 15 * time.Second}
+
+// postJSON 发送 JSON 请求，非 2xx 时返回包含响应内容的错误
+func postJSON(ctx context.Context, url string, payload interface{}, headers map[string]string) ([]byte, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return doRequest(ctx, http.MethodPost, url, data, "application/json; charset=utf-8", headers)
+}
+
+// doRequest 发送 HTTP 请求并读取响应
+func doRequest(ctx context.Context, method, url string, body []byte, contentType string, headers map[string]string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return respBody, fmt.Errorf("HTTP %d: %s", resp.StatusCode, truncate(string(respBody), 200))
+	}
+	return respBody, nil
+}
+
+// checkErrcode 检查钉钉、企业微信、飞书风格的响应：errcode/code 不为 0 表示失败
+func checkErrcode(body []byte) error {
+	var resp struct {
+		ErrCode *int   `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+		Code    *int   `json:"code"`
+		Msg     string `json:"msg"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil
+	}
+	if resp.ErrCode != nil && *resp.ErrCode != 0 {
+		return fmt.Errorf("errcode %d: %s", *resp.ErrCode, resp.ErrMsg)
+	}
+	if resp.Code != nil && *resp.Code != 0 {
+		return fmt.Errorf("code %d: %s", *resp.Code, resp.Msg)
+	}
+	return nil
+}
+
+// requireURL 校验必填的 http(s) 地址
+func requireURL(name, value string) error {
+	if value == "" {
+		return fmt.Errorf("%s is required", name)
+	}
+	if !strings.HasPrefix(value, "http://") && !strings.HasPrefix(value, "https://") {
+		return fmt.Errorf("%s must start with http:// or https://", name)
+	}
+	return nil
+}
+
+// levelPriority 将 Bark 级别映射为 1~5 的优先级（ntfy/Gotify）
+func levelPriority(level string) int {
+	switch level {
+	case "critical":
+		return 5
+	case "timeSensitive":
+		return 4
+	case "passive":
+		return 2
+	default:
+		return 3
+	}
+}
+
+// truncate 截断过长的错误信息
+func truncate(s string, n int) string {
+	s = strings.TrimSpace(s)
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
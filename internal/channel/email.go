@@ -0,0 +1,151 @@
+package channel
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// emailConfig SMTP 邮件配置
+type emailConfig struct {
+	Host     string   `json:"host"`
+	Port     int      `json:"port"`     // 默认 587
+	Username string   `json:"username"` // 为空时不认证
+	Password string   `json:"password"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+	Security string   `json:"security"` // starttls（默认）、tls（465 端口）、none
+}
+
+type email struct {
+	config emailConfig
+}
+
+func newEmail(data []byte) (Channel, error) {
+	var config emailConfig
+	if err := decode(data, &config); err != nil {
+		return nil, err
+	}
+	if config.Host == "" {
+		return nil, fmt.Errorf("host is required")
+	}
+	if config.Port == 0 {
+		config.Port = 587
+	}
+	if config.From == "" {
+		config.From = config.Username
+	}
+	if config.From == "" {
+		return nil, fmt.Errorf("from is required")
+	}
+	if len(config.To) == 0 {
+		return nil, fmt.Errorf("at least one recipient is required")
+	}
+	switch config.Security {
+	case "":
+		config.Security = "starttls"
+	case "starttls", "tls", "none":
+	default:
+		return nil, fmt.Errorf("unsupported security: %s (use starttls, tls or none)", config.Security)
+	}
+	return &email{config: config}, nil
+}
+
+// Send 发送纯文本邮件
+func (e *email) Send(ctx context.Context, msg Message) error {
+	addr := net.JoinHostPort(e.config.Host, strconv.Itoa(e.config.Port))
+	dialer := &net.Dialer{Timeout: 15 * time.Second}
+
+	var conn net.Conn
+	var err error
+	if e.config.Security == "tls" {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: e.config.Host}}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %v", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else {
+		conn.SetDeadline(time.Now().Add(30 * time.Second))
+	}
+
+	client, err := smtp.NewClient(conn, e.config.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %v", err)
+	}
+	defer client.Close()
+
+	if e.config.Security == "starttls" {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("SMTP server does not support STARTTLS")
+		}
+		if err := client.StartTLS(&tls.Config{ServerName: e.config.Host}); err != nil {
+			return fmt.Errorf("STARTTLS failed: %v", err)
+		}
+	}
+	if e.config.Username != "" {
+		auth := smtp.PlainAuth("", e.config.Username, e.config.Password, e.config.Host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %v", err)
+		}
+	}
+
+	if err := client.Mail(e.config.From); err != nil {
+		return err
+	}
+	for _, to := range e.config.To {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("recipient %s rejected: %v", to, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(e.buildMessage(msg)); err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// buildMessage 构造邮件内容：标题作为主题，其余作为正文
+func (e *email) buildMessage(msg Message) []byte {
+	subject := msg.Title
+	if subject == "" {
+		subject = "autobot: " + msg.TaskName
+	}
+
+	var body []string
+	for _, part := range []string{msg.Subtitle, msg.Body, msg.URL} {
+		if part = strings.TrimSpace(part); part != "" {
+			body = append(body, part)
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("From: " + e.config.From + "\r\n")
+	b.WriteString("To: " + strings.Join(e.config.To, ", ") + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.Join(body, "\n\n"), "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return []byte(b.String())
+}
@@ -0,0 +1,135 @@
+package channel
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// 自托管推送服务：ntfy、Gotify
+
+// ntfyConfig ntfy 配置
+type ntfyConfig struct {
+	ServerURL string `json:"server_url"` // 默认 https://ntfy.sh
+	Topic     string `json:"topic"`
+	Token     string `json:"token"`    // 访问令牌，可选
+	Priority  int    `json:"priority"` // 1~5，为 0 时按 Bark 级别映射
+}
+
+type ntfy struct {
+	config ntfyConfig
+}
+
+func newNtfy(data []byte) (Channel, error) {
+	var config ntfyConfig
+	if err := decode(data, &config); err != nil {
+		return nil, err
+	}
+	if config.ServerURL == "" {
+		config.ServerURL = "https://ntfy.sh"
+	}
+	if err := requireURL("server_url", config.ServerURL); err != nil {
+		return nil, err
+	}
+	if config.Topic == "" {
+		return nil, fmt.Errorf("topic is required")
+	}
+	if config.Priority < 0 || config.Priority > 5 {
+		return nil, fmt.Errorf("priority must be between 1 and 5")
+	}
+	return &ntfy{config: config}, nil
+}
+
+// Send 使用 JSON 发布接口
+func (n *ntfy) Send(ctx context.Context, msg Message) error {
+	priority := n.config.Priority
+	if priority == 0 {
+		priority = levelPriority(msg.Level)
+	}
+
+	message := strings.TrimSpace(strings.Join([]string{msg.Subtitle, msg.Body}, "\n"))
+	if message == "" {
+		message = msg.Title
+	}
+	payload := map[string]interface{}{
+		"topic":    n.config.Topic,
+		"title":    msg.Title,
+		"message":  message,
+		"priority": priority,
+	}
+	if msg.URL != "" {
+		payload["click"] = msg.URL
+	}
+	if msg.Group != "" {
+		payload["tags"] = []string{msg.Group}
+	}
+
+	headers := map[string]string{}
+	if n.config.Token != "" {
+		headers["Authorization"] = "Bearer " + n.config.Token
+	}
+	if _, err := postJSON(ctx, strings.TrimRight(n.config.ServerURL, "/"), payload, headers); err != nil {
+		return fmt.Errorf("ntfy: %v", err)
+	}
+	return nil
+}
+
+// gotifyConfig Gotify 配置
+type gotifyConfig struct {
+	ServerURL string `json:"server_url"`
+	AppToken  string `json:"app_token"`
+	Priority  int    `json:"priority"` // 0~10，为 0 时按 Bark 级别映射
+}
+
+type gotify struct {
+	config gotifyConfig
+}
+
+func newGotify(data []byte) (Channel, error) {
+	var config gotifyConfig
+	if err := decode(data, &config); err != nil {
+		return nil, err
+	}
+	if err := requireURL("server_url", config.ServerURL); err != nil {
+		return nil, err
+	}
+	if config.AppToken == "" {
+		return nil, fmt.Errorf("app_token is required")
+	}
+	if config.Priority < 0 || config.Priority > 10 {
+		return nil, fmt.Errorf("priority must be between 0 and 10")
+	}
+	return &gotify{config: config}, nil
+}
+
+// Send 调用 /message 接口
+func (g *gotify) Send(ctx context.Context, msg Message) error {
+	priority := g.config.Priority
+	if priority == 0 {
+		// Gotify 优先级为 0~10，按 ntfy 的 1~5 放大
+		priority = levelPriority(msg.Level) * 2
+	}
+
+	message := strings.TrimSpace(strings.Join([]string{msg.Subtitle, msg.Body}, "\n"))
+	if message == "" {
+		message = msg.Title
+	}
+	payload := map[string]interface{}{
+		"title":    msg.Title,
+		"message":  message,
+		"priority": priority,
+	}
+	if msg.URL != "" {
+		payload["extras"] = map[string]interface{}{
+			"client::notification": map[string]interface{}{
+				"click": map[string]string{"url": msg.URL},
+			},
+		}
+	}
+
+	headers := map[string]string{"X-Gotify-Key": g.config.AppToken}
+	if _, err := postJSON(ctx, strings.TrimRight(g.config.ServerURL, "/")+"/message", payload, headers); err != nil {
+		return fmt.Errorf("gotify: %v", err)
+	}
+	return nil
+}
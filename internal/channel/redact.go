@@ -0,0 +1,110 @@
+package channel
+
+import (
+	"encoding/json"
+	"strings"
+
+	"autobot/internal/models"
+)
+
+// Mask 接口返回配置时替代密钥的占位符，更新时原样提交表示保留原值
+const Mask = "******"
+
+// secretFields 各渠道类型配置中的密钥字段
+var secretFields = map[string][]string{
+	models.ChannelEmail:    {"password"},
+	models.ChannelTelegram: {"bot_token"},
+	models.ChannelDingTalk: {"secret"},
+	models.ChannelFeishu:   {"secret"},
+	models.ChannelNtfy:     {"token"},
+	models.ChannelGotify:   {"app_token"},
+}
+
+// secretMaps 值全部视为密钥的对象字段，例如 Webhook 的认证请求头
+var secretMaps = map[string][]string{
+	models.ChannelWebhook: {"headers"},
+}
+
+// Redact 将配置中的密钥替换为 Mask，并返回是否配置了任何密钥
+// 配置无法解析时返回空对象，避免原样泄露
+func Redact(channelType, config string) (string, bool) {
+	fields, ok := parseConfig(config)
+	if !ok {
+		return "{}", false
+	}
+
+	hasSecret := false
+	for _, key := range secretFields[channelType] {
+		if value, _ := fields[key].(string); value != "" {
+			fields[key] = Mask
+			hasSecret = true
+		}
+	}
+	for _, key := range secretMaps[channelType] {
+		values, _ := fields[key].(map[string]interface{})
+		for name, value := range values {
+			if value, _ := value.(string); value != "" {
+				values[name] = Mask
+				hasSecret = true
+			}
+		}
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return "{}", false
+	}
+	return string(data), hasSecret
+}
+
+// MergeSecrets 更新配置时，incoming 中为空或为 Mask 的密钥沿用 stored 中的值
+// incoming 无法解析时原样返回，由 Validate 报告错误
+func MergeSecrets(channelType, stored, incoming string) string {
+	fields, ok := parseConfig(incoming)
+	if !ok {
+		return incoming
+	}
+	previous, _ := parseConfig(stored)
+
+	for _, key := range secretFields[channelType] {
+		if value, _ := fields[key].(string); value == "" || value == Mask {
+			if old, _ := previous[key].(string); old != "" {
+				fields[key] = old
+			} else if value == Mask {
+				delete(fields, key)
+			}
+		}
+	}
+	for _, key := range secretMaps[channelType] {
+		values, _ := fields[key].(map[string]interface{})
+		oldValues, _ := previous[key].(map[string]interface{})
+		for name, value := range values {
+			if value != Mask {
+				continue
+			}
+			if old, ok := oldValues[name]; ok {
+				values[name] = old
+			} else {
+				delete(values, name)
+			}
+		}
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return incoming
+	}
+	return string(data)
+}
+
+// parseConfig 将渠道配置解析为通用对象
+func parseConfig(config string) (map[string]interface{}, bool) {
+	if strings.TrimSpace(config) == "" {
+		return map[string]interface{}{}, true
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(config), &fields); err != nil || fields == nil {
+		return nil, false
+	}
+	return fields, true
+}
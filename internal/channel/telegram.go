@@ -0,0 +1,65 @@
+package channel
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// telegramConfig Telegram Bot 配置
+type telegramConfig struct {
+	BotToken  string `json:"bot_token"`
+	ChatID    string `json:"chat_id"`
+	APIURL    string `json:"api_url"`    // 默认 https://api.telegram.org，可指向自建 Bot API 或代理
+	ParseMode string `json:"parse_mode"` // 可选：MarkdownV2、HTML
+}
+
+type telegram struct {
+	config telegramConfig
+}
+
+func newTelegram(data []byte) (Channel, error) {
+	var config telegramConfig
+	if err := decode(data, &config); err != nil {
+		return nil, err
+	}
+	if config.BotToken == "" || config.ChatID == "" {
+		return nil, fmt.Errorf("bot_token and chat_id are required")
+	}
+	if config.APIURL == "" {
+		config.APIURL = "https://api.telegram.org"
+	}
+	if err := requireURL("api_url", config.APIURL); err != nil {
+		return nil, err
+	}
+	return &telegram{config: config}, nil
+}
+
+// Send 调用 sendMessage
+func (t *telegram) Send(ctx context.Context, msg Message) error {
+	payload := map[string]interface{}{
+		"chat_id":              t.config.ChatID,
+		"text":                 msg.Text(),
+		"disable_notification": msg.Level == "passive",
+	}
+	if t.config.ParseMode != "" {
+		payload["parse_mode"] = t.config.ParseMode
+	}
+
+	url := strings.TrimRight(t.config.APIURL, "/") + "/bot" + t.config.BotToken + "/sendMessage"
+	body, err := postJSON(ctx, url, payload, nil)
+	if err != nil {
+		// 错误信息中不包含令牌
+		return fmt.Errorf("telegram: %s", strings.ReplaceAll(err.Error(), t.config.BotToken, "***"))
+	}
+
+	var resp struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	if json.Unmarshal(body, &resp) == nil && !resp.OK {
+		return fmt.Errorf("telegram: %s", resp.Description)
+	}
+	return nil
+}
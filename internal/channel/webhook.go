@@ -0,0 +1,109 @@
+package channel
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// webhookConfig 通用 Webhook 配置
+// Body 为请求体模板，$key 替换为消息字段（title, subtitle, body, url, level, group, task_id, task_name）
+// 或脚本结果中的字段，值按 JSON 字符串转义，模板中应写在引号内，例如 {"text": "$title: $body"}
+type webhookConfig struct {
+	URL         string            `json:"url"`
+	Method      string            `json:"method"`       // 默认 POST
+	Headers     map[string]string `json:"headers"`      // 额外请求头
+	ContentType string            `json:"content_type"` // 默认 application/json
+	Body        string            `json:"body"`         // 请求体模板，为空时发送全部消息字段
+}
+
+type webhook struct {
+	config webhookConfig
+}
+
+func newWebhook(data []byte) (Channel, error) {
+	var config webhookConfig
+	if err := decode(data, &config); err != nil {
+		return nil, err
+	}
+	if err := requireURL("url", config.URL); err != nil {
+		return nil, err
+	}
+	config.Method = strings.ToUpper(config.Method)
+	switch config.Method {
+	case "":
+		config.Method = http.MethodPost
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+	default:
+		return nil, fmt.Errorf("unsupported webhook method: %s (use POST, PUT or PATCH)", config.Method)
+	}
+	if config.ContentType == "" {
+		config.ContentType = "application/json"
+	}
+	return &webhook{config: config}, nil
+}
+
+// Send 发送 Webhook 请求
+func (w *webhook) Send(ctx context.Context, msg Message) error {
+	vars := messageVars(msg)
+
+	var body []byte
+	if w.config.Body == "" {
+		data, err := json.Marshal(vars)
+		if err != nil {
+			return err
+		}
+		body = data
+	} else {
+		body = []byte(renderTemplate(w.config.Body, vars))
+	}
+
+	_, err := doRequest(ctx, w.config.Method, w.config.URL, body, w.config.ContentType, w.config.Headers)
+	return err
+}
+
+// messageVars 模板变量：脚本结果字段，再由消息字段覆盖同名字段
+func messageVars(msg Message) map[string]interface{} {
+	vars := make(map[string]interface{}, len(msg.Result)+8)
+	for key, value := range msg.Result {
+		vars[key] = value
+	}
+	vars["title"] = msg.Title
+	vars["subtitle"] = msg.Subtitle
+	vars["body"] = msg.Body
+	vars["url"] = msg.URL
+	vars["level"] = msg.Level
+	vars["group"] = msg.Group
+	vars["task_id"] = msg.TaskID
+	vars["task_name"] = msg.TaskName
+	return vars
+}
+
+// templatePlaceholder 匹配模板中的 $key
+var templatePlaceholder = regexp.MustCompile(`\$([a-zA-Z_][a-zA-Z0-9_]*)`)
+
+// renderTemplate 将 $key 替换为 JSON 字符串转义后的值，未定义的占位符替换为空
+func renderTemplate(template string, vars map[string]interface{}) string {
+	return templatePlaceholder.ReplaceAllStringFunc(template, func(match string) string {
+		value, ok := vars[match[1:]]
+		if !ok || value == nil {
+			return ""
+		}
+		var text string
+		switch v := value.(type) {
+		case string:
+			text = v
+		default:
+			data, err := json.Marshal(v)
+			if err != nil {
+				return ""
+			}
+			text = string(data)
+		}
+		escaped, _ := json.Marshal(text)
+		return string(escaped[1 : len(escaped)-1])
+	})
+}
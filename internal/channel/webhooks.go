@@ -0,0 +1,167 @@
+package channel
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 群机器人类 Incoming Webhook：Slack、钉钉、企业微信、飞书
+
+// incomingWebhookConfig 群机器人配置，secret 为钉钉/飞书的加签密钥
+type incomingWebhookConfig struct {
+	WebhookURL string `json:"webhook_url"`
+	Secret     string `json:"secret"`
+}
+
+func parseIncomingWebhook(data []byte) (incomingWebhookConfig, error) {
+	var config incomingWebhookConfig
+	if err := decode(data, &config); err != nil {
+		return config, err
+	}
+	return config, requireURL("webhook_url", config.WebhookURL)
+}
+
+// slack Slack Incoming Webhook
+type slack struct {
+	config incomingWebhookConfig
+}
+
+func newSlack(data []byte) (Channel, error) {
+	config, err := parseIncomingWebhook(data)
+	if err != nil {
+		return nil, err
+	}
+	return &slack{config: config}, nil
+}
+
+// Send 标题加粗，正文为 mrkdwn 文本
+func (s *slack) Send(ctx context.Context, msg Message) error {
+	text := msg.Text()
+	if msg.Title != "" {
+		text = "*" + msg.Title + "*" + strings.TrimPrefix(text, msg.Title)
+	}
+	_, err := postJSON(ctx, s.config.WebhookURL, map[string]string{"text": text}, nil)
+	if err != nil {
+		return fmt.Errorf("slack: %v", err)
+	}
+	return nil
+}
+
+// dingTalk 钉钉群机器人
+type dingTalk struct {
+	config incomingWebhookConfig
+}
+
+func newDingTalk(data []byte) (Channel, error) {
+	config, err := parseIncomingWebhook(data)
+	if err != nil {
+		return nil, err
+	}
+	return &dingTalk{config: config}, nil
+}
+
+// Send 发送 text 消息，配置了 secret 时按钉钉规则加签
+func (d *dingTalk) Send(ctx context.Context, msg Message) error {
+	target := d.config.WebhookURL
+	if d.config.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+		mac := hmac.New(sha256.New, []byte(d.config.Secret))
+		mac.Write([]byte(timestamp + "\n" + d.config.Secret))
+		sign := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+		target = appendQuery(target, url.Values{"timestamp": {timestamp}, "sign": {sign}})
+	}
+
+	payload := map[string]interface{}{
+		"msgtype": "text",
+		"text":    map[string]string{"content": msg.Text()},
+	}
+	body, err := postJSON(ctx, target, payload, nil)
+	if err == nil {
+		err = checkErrcode(body)
+	}
+	if err != nil {
+		return fmt.Errorf("dingtalk: %v", err)
+	}
+	return nil
+}
+
+// weCom 企业微信群机器人
+type weCom struct {
+	config incomingWebhookConfig
+}
+
+func newWeCom(data []byte) (Channel, error) {
+	config, err := parseIncomingWebhook(data)
+	if err != nil {
+		return nil, err
+	}
+	return &weCom{config: config}, nil
+}
+
+// Send 发送 text 消息
+func (w *weCom) Send(ctx context.Context, msg Message) error {
+	payload := map[string]interface{}{
+		"msgtype": "text",
+		"text":    map[string]string{"content": msg.Text()},
+	}
+	body, err := postJSON(ctx, w.config.WebhookURL, payload, nil)
+	if err == nil {
+		err = checkErrcode(body)
+	}
+	if err != nil {
+		return fmt.Errorf("wecom: %v", err)
+	}
+	return nil
+}
+
+// feishu 飞书群机器人
+type feishu struct {
+	config incomingWebhookConfig
+}
+
+func newFeishu(data []byte) (Channel, error) {
+	config, err := parseIncomingWebhook(data)
+	if err != nil {
+		return nil, err
+	}
+	return &feishu{config: config}, nil
+}
+
+// Send 发送 text 消息，配置了 secret 时按飞书规则签名
+func (f *feishu) Send(ctx context.Context, msg Message) error {
+	payload := map[string]interface{}{
+		"msg_type": "text",
+		"content":  map[string]string{"text": msg.Text()},
+	}
+	if f.config.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		mac := hmac.New(sha256.New, []byte(timestamp+"\n"+f.config.Secret))
+		payload["timestamp"] = timestamp
+		payload["sign"] = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	}
+
+	body, err := postJSON(ctx, f.config.WebhookURL, payload, nil)
+	if err == nil {
+		err = checkErrcode(body)
+	}
+	if err != nil {
+		return fmt.Errorf("feishu: %v", err)
+	}
+	return nil
+}
+
+// appendQuery 在地址后追加查询参数
+func appendQuery(rawURL string, values url.Values) string {
+	separator := "?"
+	if strings.Contains(rawURL, "?") {
+		separator = "&"
+	}
+	return rawURL + separator + values.Encode()
+}
@@ -1,13 +1,23 @@
 package database
 
 import (
+	"autobot/internal/metrics"
 	"autobot/internal/models"
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"log"
+	"log/slog"
+	"net/url"
 	"os"
 	"strings"
 	"time"
 
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -17,9 +27,52 @@ import (
 
 var DB *gorm.DB
 
+// 支持的数据库驱动
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+	DriverMySQL    = "mysql"
+)
+
+// sqliteParams SQLite 连接参数，用于改善并发性能
+// modernc.org/sqlite 驱动通过 _pragma 参数在每个连接上执行 PRAGMA
+// busy_timeout(60000): 60秒超时，给长时间操作足够时间
+// journal_mode(WAL): WAL模式，支持多读单写
+// synchronous(NORMAL): 平衡性能和安全性
+// cache_size(2000): 缓存大小
+// foreign_keys(1): 启用外键约束
+// temp_store(MEMORY): 临时表存储在内存中
+// _txlock=immediate: 立即获取写锁，避免死锁
+const sqliteParams = "_pragma=busy_timeout(60000)&_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)&_pragma=cache_size(2000)&_pragma=foreign_keys(1)&_pragma=temp_store(MEMORY)&_txlock=immediate"
+
+// Config 数据库连接配置
+type Config struct {
+	Driver string // sqlite, postgres, mysql
+	DSN    string // SQLite 为文件路径，其他驱动为标准连接串
+}
+
+// DefaultConfig 默认配置：当前目录下的 autobot.db
+func DefaultConfig() Config {
+	return Config{
+		Driver: DriverSQLite,
+		DSN:    "autobot.db",
+	}
+}
+
+// 当前使用的数据库驱动
+var currentDriver = DriverSQLite
+
+// Driver 获取当前使用的数据库驱动
+func Driver() string {
+	return currentDriver
+}
+
 // InitDB 初始化数据库连接
-func InitDB() error {
+func InitDB(cfg Config) error {
 	var err error
+	if cfg.Driver == "" {
+		cfg.Driver = DriverSQLite
+	}
 
 	// 创建一个完全静默的logger
 	silentLogger := logger.New(
@@ -32,64 +85,137 @@ func InitDB() error {
 		},
 	)
 
-	// 使用 SQLite 数据库，通过 modernc.org/sqlite 驱动
-	// 添加 SQLite 配置参数以改善并发性能
-	// _busy_timeout=60000: 60秒超时，给长时间操作足够时间
-	// _journal_mode=WAL: WAL模式，支持多读单写
-	// _synchronous=NORMAL: 平衡性能和安全性
-	// _cache_size=2000: 缓存大小
-	// _foreign_keys=true: 启用外键约束
-	// _temp_store=memory: 临时表存储在内存中
-	// _txlock=immediate: 立即获取写锁，避免死锁
-	dsn := "autobot.db?_busy_timeout=60000&_journal_mode=WAL&_synchronous=NORMAL&_cache_size=2000&_foreign_keys=true&_temp_store=memory&_txlock=immediate"
-	sqlDB, err := sql.Open("sqlite", dsn)
+	dialector, err := openDialector(cfg)
 	if err != nil {
 		return err
 	}
 
-	// 配置连接池 - 针对并发任务执行优化
-	// SQLite with WAL mode 可以支持多个读连接和1个写连接
-	// 设置足够的连接数以支持并发任务执行
-	sqlDB.SetMaxOpenConns(20)              // 最多20个连接，足够支持10+个并发任务
-	sqlDB.SetMaxIdleConns(5)               // 保持5个空闲连接，减少连接创建开销
-	sqlDB.SetConnMaxLifetime(10 * time.Minute) // 连接生命周期10分钟
-
-	DB, err = gorm.Open(sqlite.Dialector{
-		DriverName: "sqlite",
-		DSN:        dsn,
-		Conn:       sqlDB,
-	}, &gorm.Config{
+	DB, err = gorm.Open(dialector, &gorm.Config{
 		Logger: silentLogger,
 	})
 
 	if err != nil {
 		return err
 	}
+	currentDriver = cfg.Driver
 
-	// 自动迁移数据表
-	err = DB.AutoMigrate(
-		&models.Task{},
-		&models.TaskLog{},
-		&models.BarkServer{},
-		&models.BarkDevice{},
-		&models.BarkRecord{},
-		&models.User{},
-	)
-
+	// 配置连接池 - 针对并发任务执行优化
+	// SQLite with WAL mode 可以支持多个读连接和1个写连接
+	// 设置足够的连接数以支持并发任务执行
+	sqlDB, err := DB.DB()
 	if err != nil {
 		return err
 	}
+	sqlDB.SetMaxOpenConns(20)                  // 最多20个连接，足够支持10+个并发任务
+	sqlDB.SetMaxIdleConns(5)                   // 保持5个空闲连接，减少连接创建开销
+	sqlDB.SetConnMaxLifetime(10 * time.Minute) // 连接生命周期10分钟
 
+	// 表结构由 migrations 包管理，不再使用 AutoMigrate
 	return nil
 }
 
+// openDialector 根据驱动创建 GORM Dialector
+func openDialector(cfg Config) (gorm.Dialector, error) {
+	switch cfg.Driver {
+	case DriverSQLite:
+		// 使用 SQLite 数据库，通过 modernc.org/sqlite 驱动
+		dsn := cfg.DSN
+		if dsn == "" {
+			dsn = DefaultConfig().DSN
+		}
+		dsn = withSQLiteDefaults(dsn)
+		sqlDB, err := sql.Open("sqlite", dsn)
+		if err != nil {
+			return nil, err
+		}
+		return sqlite.Dialector{
+			DriverName: "sqlite",
+			DSN:        dsn,
+			Conn:       sqlDB,
+		}, nil
+	case DriverPostgres:
+		// 例如 host=localhost user=autobot password=secret dbname=autobot port=5432 sslmode=disable
+		return postgres.Open(cfg.DSN), nil
+	case DriverMySQL:
+		// 例如 autobot:secret@tcp(127.0.0.1:3306)/autobot?charset=utf8mb4&parseTime=True&loc=Local
+		return mysql.Open(cfg.DSN), nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %s", cfg.Driver)
+	}
+}
+
+// withSQLiteDefaults 为 DSN 补充用户未设置的默认连接参数，用户设置的参数保持不变
+// _pragma 按 PRAGMA 名称判断是否已设置
+func withSQLiteDefaults(dsn string) string {
+	path, query, _ := strings.Cut(dsn, "?")
+	userParams, err := url.ParseQuery(query)
+	if err != nil {
+		userParams = url.Values{}
+	}
+	userPragmas := map[string]bool{}
+	for _, pragma := range userParams["_pragma"] {
+		userPragmas[pragmaName(pragma)] = true
+	}
+
+	params := []string{}
+	if query != "" {
+		params = append(params, query)
+	}
+	for _, param := range strings.Split(sqliteParams, "&") {
+		key, value, _ := strings.Cut(param, "=")
+		if key == "_pragma" && userPragmas[pragmaName(value)] || key != "_pragma" && userParams.Has(key) {
+			continue
+		}
+		params = append(params, param)
+	}
+	return path + "?" + strings.Join(params, "&")
+}
+
+// pragmaName 取 _pragma 参数中的 PRAGMA 名称，例如 busy_timeout(5000) 和 busy_timeout=5000 都返回 busy_timeout
+func pragmaName(pragma string) string {
+	name, _, _ := strings.Cut(pragma, "(")
+	name, _, _ = strings.Cut(name, "=")
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
 // GetDB 获取数据库实例
 func GetDB() *gorm.DB {
 	return DB
 }
 
-// WithRetry 执行数据库操作，如果遇到 SQLITE_BUSY 错误则重试
-// 针对SQLite的写锁竞争问题，使用更激进的重试策略
+// Ping 检查数据库连接是否可用
+func Ping(ctx context.Context) error {
+	if DB == nil {
+		return errors.New("database is not initialized")
+	}
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// GetSetting 读取系统配置，不存在时返回默认值
+func GetSetting(key string, defaultValue string) string {
+	var setting models.SystemSetting
+	err := WithRetry(func(db *gorm.DB) error {
+		return db.Where("setting_key = ?", key).First(&setting).Error
+	})
+	if err != nil {
+		return defaultValue
+	}
+	return setting.Value
+}
+
+// SetSetting 写入系统配置
+func SetSetting(key string, value string) error {
+	return WithRetry(func(db *gorm.DB) error {
+		return db.Save(&models.SystemSetting{Key: key, Value: value}).Error
+	})
+}
+
+// WithRetry 执行数据库操作，如果遇到可重试的并发错误则重试
+// SQLite: 写锁竞争（SQLITE_BUSY）；PostgreSQL: 序列化失败、死锁；MySQL: 死锁、锁等待超时
 func WithRetry(operation func(*gorm.DB) error) error {
 	const maxRetries = 15  // 增加到15次重试，应对高并发写操作
 	const initialDelay = 10 * time.Millisecond
@@ -107,11 +233,7 @@ func WithRetry(operation func(*gorm.DB) error) error {
 			return nil
 		}
 
-		// 检查是否是 SQLITE_BUSY 或事务错误
-		errMsg := err.Error()
-		if strings.Contains(errMsg, "database is locked") || 
-			strings.Contains(errMsg, "SQLITE_BUSY") ||
-			strings.Contains(errMsg, "cannot start a transaction within a transaction") {
+		if isRetryable(err) {
 			if i < maxRetries-1 { // 不是最后一次重试
 				// 使用指数退避策略，但限制最大延迟
 				// 延迟序列：10ms, 20ms, 40ms, 80ms, 160ms, 320ms, 640ms, 1.28s, 2s, 2s...
@@ -119,10 +241,12 @@ func WithRetry(operation func(*gorm.DB) error) error {
 				if delay > maxDelay {
 					delay = maxDelay
 				}
-				log.Printf("Database error, retrying in %v (attempt %d/%d): %v", delay, i+1, maxRetries, err)
+				slog.Warn("Database error, retrying", "delay", delay, "attempt", i+1, "max_attempts", maxRetries, "error", err)
+				metrics.DBRetried()
 				time.Sleep(delay)
 				continue
 			}
+			metrics.DBRetryExhausted()
 		}
 
 		// 非可重试错误或已达到最大重试次数
@@ -131,3 +255,29 @@ func WithRetry(operation func(*gorm.DB) error) error {
 
 	return err
 }
+
+// isRetryable 判断错误是否为当前驱动下可重试的并发冲突
+func isRetryable(err error) bool {
+	switch currentDriver {
+	case DriverPostgres:
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			// 40001: serialization_failure, 40P01: deadlock_detected
+			return pgErr.Code == "40001" || pgErr.Code == "40P01"
+		}
+		return false
+	case DriverMySQL:
+		var mysqlErr *mysqldriver.MySQLError
+		if errors.As(err, &mysqlErr) {
+			// 1213: ER_LOCK_DEADLOCK, 1205: ER_LOCK_WAIT_TIMEOUT
+			return mysqlErr.Number == 1213 || mysqlErr.Number == 1205
+		}
+		return false
+	default:
+		// 检查是否是 SQLITE_BUSY 或事务错误
+		errMsg := err.Error()
+		return strings.Contains(errMsg, "database is locked") ||
+			strings.Contains(errMsg, "SQLITE_BUSY") ||
+			strings.Contains(errMsg, "cannot start a transaction within a transaction")
+	}
+}
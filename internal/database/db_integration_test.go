@@ -0,0 +1,307 @@
+package database_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"autobot/internal/database"
+	"autobot/internal/logmanager"
+	"autobot/internal/migrations"
+	"autobot/internal/models"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// 集成测试：SQLite 总是运行；设置 AUTOBOT_TEST_PG_DSN 时同时在 PostgreSQL 上运行，例如
+//   docker run -d -p 5432:5432 -e POSTGRES_PASSWORD=secret postgres:16
+//   AUTOBOT_TEST_PG_DSN="host=localhost user=postgres password=secret dbname=postgres sslmode=disable" go test ./internal/database/
+// PostgreSQL 测试会执行迁移并写入数据，请使用专门的测试数据库
+
+func TestWithSQLiteDefaults(t *testing.T) {
+	tests := []struct {
+		dsn     string
+		want    []string // 必须包含的参数
+		notWant []string // 不能包含的参数
+	}{
+		{dsn: "autobot.db", want: []string{"_pragma=journal_mode(WAL)", "_pragma=busy_timeout(60000)", "_txlock=immediate"}},
+		{dsn: "file.db?cache=shared", want: []string{"cache=shared", "_pragma=journal_mode(WAL)", "_txlock=immediate"}},
+		{dsn: "file.db?_pragma=busy_timeout(5000)", want: []string{"_pragma=busy_timeout(5000)", "_pragma=journal_mode(WAL)"}, notWant: []string{"busy_timeout(60000)"}},
+		{dsn: "file.db?_txlock=deferred", want: []string{"_txlock=deferred"}, notWant: []string{"_txlock=immediate"}},
+		{dsn: "file.db?", want: []string{"_pragma=foreign_keys(1)"}},
+	}
+	for _, tt := range tests {
+		got := database.WithSQLiteDefaults(tt.dsn)
+		path, _, _ := strings.Cut(tt.dsn, "?")
+		if !strings.HasPrefix(got, path+"?") || strings.Contains(got, "?&") || strings.Count(got, "?") != 1 {
+			t.Errorf("WithSQLiteDefaults(%q) = %q, malformed", tt.dsn, got)
+		}
+		for _, param := range tt.want {
+			if !strings.Contains(got, param) {
+				t.Errorf("WithSQLiteDefaults(%q) = %q, missing %s", tt.dsn, got, param)
+			}
+		}
+		for _, param := range tt.notWant {
+			if strings.Contains(got, param) {
+				t.Errorf("WithSQLiteDefaults(%q) = %q, should not contain %s", tt.dsn, got, param)
+			}
+		}
+	}
+}
+
+func TestIntegration(t *testing.T) {
+	configs := map[string]database.Config{
+		database.DriverSQLite: {Driver: database.DriverSQLite, DSN: filepath.Join(t.TempDir(), "autobot.db")},
+	}
+	if dsn := os.Getenv("AUTOBOT_TEST_PG_DSN"); dsn != "" {
+		configs[database.DriverPostgres] = database.Config{Driver: database.DriverPostgres, DSN: dsn}
+	} else {
+		t.Log("AUTOBOT_TEST_PG_DSN not set, skipping PostgreSQL")
+	}
+
+	// 驱动是全局状态，按固定顺序依次运行
+	for _, driver := range []string{database.DriverSQLite, database.DriverPostgres} {
+		cfg, ok := configs[driver]
+		if !ok {
+			continue
+		}
+		t.Run(driver, func(t *testing.T) {
+			if err := database.InitDB(cfg); err != nil {
+				t.Fatalf("InitDB: %v", err)
+			}
+			if driver == database.DriverSQLite {
+				t.Run("pragmas", testSQLitePragmas)
+			}
+			t.Run("migrations", testMigrations)
+			t.Run("crud", testCRUD)
+			t.Run("recovery", testRecovery)
+			t.Run("retry", testRetry)
+		})
+	}
+}
+
+func testSQLitePragmas(t *testing.T) {
+	var journalMode string
+	var busyTimeout int
+	database.GetDB().Raw("PRAGMA journal_mode").Scan(&journalMode)
+	database.GetDB().Raw("PRAGMA busy_timeout").Scan(&busyTimeout)
+	if strings.ToLower(journalMode) != "wal" || busyTimeout != 60000 {
+		t.Errorf("journal_mode = %q, busy_timeout = %d; want wal, 60000", journalMode, busyTimeout)
+	}
+}
+
+func testMigrations(t *testing.T) {
+	db := database.GetDB()
+
+	// 模拟多个实例同时启动：并发执行的 Up 都应成功，每个迁移只执行一次
+	var wg sync.WaitGroup
+	results := make([][]migrations.Migration, 3)
+	errs := make([]error, 3)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = migrations.Up(db)
+		}(i)
+	}
+	wg.Wait()
+	applied := map[int]int{}
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("concurrent Up %d: %v", i, err)
+		}
+		for _, m := range results[i] {
+			applied[m.Version]++
+		}
+	}
+	for version, n := range applied {
+		if n > 1 {
+			t.Errorf("migration %d applied %d times", version, n)
+		}
+	}
+
+	// 再次执行不应有任何迁移
+	ran, err := migrations.Up(db)
+	if err != nil {
+		t.Fatalf("second Up: %v", err)
+	}
+	if len(ran) != 0 {
+		t.Errorf("second Up ran %d migrations, want 0", len(ran))
+	}
+	version, err := migrations.CurrentVersion(db)
+	if err != nil {
+		t.Fatalf("CurrentVersion: %v", err)
+	}
+	if version != migrations.Latest() {
+		t.Errorf("version = %d, want %d", version, migrations.Latest())
+	}
+}
+
+func testCRUD(t *testing.T) {
+	name := fmt.Sprintf("it-server-%s", t.Name())
+	server := models.BarkServer{Name: name, URL: "https://api.day.app", Status: "active"}
+	if err := database.WithRetry(func(db *gorm.DB) error { return db.Create(&server).Error }); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	t.Cleanup(func() {
+		database.WithRetry(func(db *gorm.DB) error { return db.Delete(&models.BarkServer{}, server.ID).Error })
+	})
+
+	err := database.WithRetry(func(db *gorm.DB) error {
+		return db.Model(&models.BarkServer{}).Where("id = ?", server.ID).Update("description", "updated").Error
+	})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	var loaded models.BarkServer
+	if err := database.WithRetry(func(db *gorm.DB) error { return db.First(&loaded, server.ID).Error }); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if loaded.Name != name || loaded.Description != "updated" || loaded.Health != models.ServerHealthUnknown {
+		t.Errorf("loaded = %+v", loaded)
+	}
+
+	if err := database.SetSetting("it-key", "v1"); err != nil {
+		t.Fatalf("SetSetting: %v", err)
+	}
+	t.Cleanup(func() {
+		database.WithRetry(func(db *gorm.DB) error { return db.Delete(&models.SystemSetting{Key: "it-key"}).Error })
+	})
+	if err := database.SetSetting("it-key", "v2"); err != nil {
+		t.Fatalf("SetSetting overwrite: %v", err)
+	}
+	if got := database.GetSetting("it-key", ""); got != "v2" {
+		t.Errorf("GetSetting = %q, want v2", got)
+	}
+
+	if err := database.WithRetry(func(db *gorm.DB) error { return db.Delete(&models.BarkServer{}, server.ID).Error }); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	var count int64
+	database.GetDB().Model(&models.BarkServer{}).Where("id = ?", server.ID).Count(&count)
+	if count != 0 {
+		t.Errorf("server still exists after delete")
+	}
+}
+
+func testRecovery(t *testing.T) {
+	task := models.Task{Name: "it-recovery", Script: "print(1)", CronExpr: "@daily"}
+	if err := database.GetDB().Create(&task).Error; err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+	t.Cleanup(func() {
+		database.GetDB().Where("task_id = ?", task.ID).Delete(&models.TaskLog{})
+		database.GetDB().Delete(&models.Task{}, task.ID)
+		database.GetDB().Where("1 = 1").Delete(&models.InstanceHeartbeat{})
+	})
+
+	now := time.Now()
+	heartbeats := []models.InstanceHeartbeat{
+		{InstanceID: "self", StartedAt: now, LastSeen: now},
+		{InstanceID: "alive", StartedAt: now, LastSeen: now},
+		{InstanceID: "dead", StartedAt: now, LastSeen: now.Add(-time.Hour)},
+	}
+	if err := database.GetDB().Create(&heartbeats).Error; err != nil {
+		t.Fatalf("create heartbeats: %v", err)
+	}
+	logs := map[string]*models.TaskLog{}
+	for _, instance := range []string{"self", "alive", "dead", "gone", ""} {
+		log := &models.TaskLog{TaskID: task.ID, StartTime: now, Status: "running", Instance: instance}
+		if err := database.GetDB().Create(log).Error; err != nil {
+			t.Fatalf("create log: %v", err)
+		}
+		logs[instance] = log
+	}
+	status := func() map[string]string {
+		result := map[string]string{}
+		for instance, log := range logs {
+			var loaded models.TaskLog
+			database.GetDB().First(&loaded, log.ID)
+			result[instance] = loaded.Status
+		}
+		return result
+	}
+
+	// Leader 定期处理：只处理心跳失效或不存在的其他实例
+	lm := logmanager.NewLogManager(0, 0)
+	liveSince := now.Add(-time.Minute)
+	if n, err := lm.RecoverOrphanedLogs("self", liveSince); err != nil || n != 2 {
+		t.Fatalf("RecoverOrphanedLogs = %d, %v; want 2", n, err)
+	}
+	want := map[string]string{"self": "running", "alive": "running", "dead": "interrupted", "gone": "interrupted", "": "running"}
+	if got := status(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("after RecoverOrphanedLogs = %v, want %v", got, want)
+	}
+
+	// 启动时处理：另外包括本实例和没有实例ID的日志
+	if n, err := lm.RecoverInterruptedLogs("self", liveSince); err != nil || n != 2 {
+		t.Fatalf("RecoverInterruptedLogs = %d, %v; want 2", n, err)
+	}
+	want["self"], want[""] = "interrupted", "interrupted"
+	if got := status(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("after RecoverInterruptedLogs = %v, want %v", got, want)
+	}
+}
+
+func testRetry(t *testing.T) {
+	// 真实的主键冲突不可重试，WithRetry 只执行一次
+	calls := 0
+	err := database.WithRetry(func(db *gorm.DB) error {
+		calls++
+		return db.Create(&migrations.SchemaMigration{Version: 1, Name: "duplicate"}).Error
+	})
+	if err == nil {
+		t.Fatal("duplicate schema_migrations insert succeeded")
+	}
+	if database.IsRetryable(err) || calls != 1 {
+		t.Errorf("duplicate key: retryable = %v, calls = %d; want false, 1", database.IsRetryable(err), calls)
+	}
+
+	// 当前驱动的并发冲突错误可重试
+	var transient []error
+	switch database.Driver() {
+	case database.DriverPostgres:
+		transient = []error{
+			&pgconn.PgError{Code: "40001"},
+			fmt.Errorf("wrapped: %w", &pgconn.PgError{Code: "40P01"}),
+		}
+		// 其他驱动的冲突错误不应被误判
+		if database.IsRetryable(&mysqldriver.MySQLError{Number: 1213}) || database.IsRetryable(errors.New("database is locked")) {
+			t.Error("errors of other drivers classified as retryable")
+		}
+	default:
+		transient = []error{
+			errors.New("database is locked (5) (SQLITE_BUSY)"),
+			errors.New("cannot start a transaction within a transaction"),
+		}
+		if database.IsRetryable(&pgconn.PgError{Code: "40001"}) {
+			t.Error("PostgreSQL error classified as retryable on SQLite")
+		}
+	}
+	for _, e := range transient {
+		if !database.IsRetryable(e) {
+			t.Errorf("IsRetryable(%v) = false, want true", e)
+		}
+	}
+
+	// 可重试错误在重试后成功
+	calls = 0
+	err = database.WithRetry(func(db *gorm.DB) error {
+		calls++
+		if calls < 3 {
+			return transient[0]
+		}
+		return db.Exec("SELECT 1").Error
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("WithRetry = %v after %d calls, want nil after 3", err, calls)
+	}
+}
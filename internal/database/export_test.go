@@ -0,0 +1,7 @@
+package database
+
+// 供外部测试包使用的内部函数
+var (
+	IsRetryable        = isRetryable
+	WithSQLiteDefaults = withSQLiteDefaults
+)
@@ -0,0 +1,156 @@
+// Package digest 通知汇总：计算汇总分组和发送时间，渲染汇总消息
+//
+// 汇总模板按 Go text/template 渲染，辅助函数与通知字段模板相同。模板数据：
+//
+//	.Count  汇总的通知数
+//	.Items  汇总的通知（.Title、.Subtitle、.Body、.URL、.TaskID、.TaskName、.Time）
+//	.Tasks  涉及的任务名称（去重，按首次出现排序）
+//	.Group  汇总分组，按任务汇总时为空
+//	.Since  第一条通知的时间
+//	.Until  最后一条通知的时间
+//	.Now    渲染时间
+package digest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"autobot/internal/models"
+	"autobot/internal/msgtemplate"
+	"autobot/internal/timeutils"
+)
+
+const (
+	// DefaultInterval 未配置间隔和固定时间时的汇总间隔（分钟）
+	DefaultInterval = 60
+	// MaxInterval 最大汇总间隔（分钟）
+	MaxInterval = 24 * 60
+
+	// DefaultTitle 默认汇总标题模板
+	DefaultTitle = `{{if eq (len .Tasks) 1}}{{index .Tasks 0}}：{{end}}{{.Count}} 条通知汇总`
+	// DefaultBody 默认汇总内容模板，每条通知一行
+	DefaultBody = `{{range .Items}}{{.Time | date "15:04"}} {{.Title}}{{if .Body}}：{{.Body | truncate 100}}{{end}}
+{{end}}`
+)
+
+// Item 被汇总的单条通知
+type Item struct {
+	TaskID   uint
+	TaskName string
+	Title    string
+	Subtitle string
+	Body     string
+	URL      string
+	Time     time.Time
+}
+
+// Data 汇总模板数据
+type Data struct {
+	Count int
+	Items []Item
+	Tasks []string
+	Group string
+	Since time.Time
+	Until time.Time
+	Now   time.Time
+}
+
+// NewData 由按时间排序的通知创建模板数据
+func NewData(group string, items []Item) Data {
+	data := Data{Count: len(items), Items: items, Tasks: []string{}, Group: group, Now: time.Now()}
+	seen := make(map[string]bool)
+	for _, item := range items {
+		if item.TaskName != "" && !seen[item.TaskName] {
+			seen[item.TaskName] = true
+			data.Tasks = append(data.Tasks, item.TaskName)
+		}
+	}
+	if len(items) > 0 {
+		data.Since = items[0].Time
+		data.Until = items[len(items)-1].Time
+	}
+	return data
+}
+
+// Key 通知所属的汇总分组，配置了分组名时多个任务共用
+func Key(config *models.BarkDigestConfig, taskID uint) string {
+	if config.Group != "" {
+		return "group:" + config.Group
+	}
+	return "task:" + strconv.FormatUint(uint64(taskID), 10)
+}
+
+// Validate 检查汇总配置，未启用时不检查
+func Validate(config *models.BarkDigestConfig) error {
+	if !config.Enabled {
+		return nil
+	}
+	if config.Interval < 0 || config.Interval > MaxInterval {
+		return fmt.Errorf("interval must be between 0 and %d minutes", MaxInterval)
+	}
+	for _, t := range config.Times {
+		if _, err := timeutils.ParseClock(t); err != nil {
+			return err
+		}
+	}
+	for name, text := range map[string]string{"title": config.Title, "body": config.Body} {
+		if text == "" {
+			continue
+		}
+		if err := msgtemplate.Validate(text); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// NextAt 从 now 开始的一个汇总窗口的发送时间
+// 配置了固定时间时取之后最近的一个，否则为 now 加上汇总间隔
+func NextAt(config *models.BarkDigestConfig, now time.Time) time.Time {
+	var next time.Time
+	for _, t := range config.Times {
+		minutes, err := timeutils.ParseClock(t)
+		if err != nil {
+			continue
+		}
+		at := time.Date(now.Year(), now.Month(), now.Day(), 0, minutes, 0, 0, now.Location())
+		if !at.After(now) {
+			at = at.AddDate(0, 0, 1)
+		}
+		if next.IsZero() || at.Before(next) {
+			next = at
+		}
+	}
+	if !next.IsZero() {
+		return next
+	}
+
+	interval := config.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	return now.Add(time.Duration(interval) * time.Minute)
+}
+
+// Render 渲染汇总标题和内容，模板为空时使用默认模板
+func Render(config *models.BarkDigestConfig, data Data) (string, string, error) {
+	titleTmpl, bodyTmpl := config.Title, config.Body
+	if titleTmpl == "" {
+		titleTmpl = DefaultTitle
+	}
+	if bodyTmpl == "" {
+		bodyTmpl = DefaultBody
+	}
+
+	title, err := msgtemplate.Execute(titleTmpl, data)
+	if err != nil {
+		return "", "", fmt.Errorf("title: %v", err)
+	}
+	body, err := msgtemplate.Execute(bodyTmpl, data)
+	if err != nil {
+		return "", "", fmt.Errorf("body: %v", err)
+	}
+	return strings.TrimSpace(title), strings.TrimSpace(body), nil
+}
@@ -2,19 +2,27 @@ package executor
 
 import (
 	"autobot/internal/database"
+	"autobot/internal/logging"
+	"autobot/internal/metrics"
 	"autobot/internal/models"
 	"autobot/internal/notifier"
+	"autobot/internal/tracing"
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
-	"log"
+	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
 	"gorm.io/gorm"
 )
 
@@ -29,8 +37,184 @@ func SetLogCleanupCallback(callback LogCleanupCallback) {
 	logCleanupCallback = callback
 }
 
+// 执行配置，可通过 SetPythonBinary / SetTimeout 修改
+var (
+	pythonBinary = "python3"
+	execTimeout  = 10 * time.Minute
+)
+
+// SetPythonBinary 设置 Python 解释器
+func SetPythonBinary(binary string) {
+	if binary != "" {
+		pythonBinary = binary
+	}
+}
+
+// PythonBinary 当前使用的 Python 解释器
+func PythonBinary() string {
+	return pythonBinary
+}
+
+// CheckPython 检查 Python 解释器是否可用
+func CheckPython() error {
+	_, err := exec.LookPath(pythonBinary)
+	return err
+}
+
+// SetTimeout 设置单次执行超时
+func SetTimeout(timeout time.Duration) {
+	if timeout > 0 {
+		execTimeout = timeout
+	}
+}
+
+// 当前实例ID，记录到执行日志中，用于重启后只恢复本实例遗留的执行
+var instanceID string
+
+// SetInstanceID 设置当前实例ID
+func SetInstanceID(id string) {
+	instanceID = id
+}
+
+// errInterrupted 执行因停机被取消
+var errInterrupted = errors.New("script execution interrupted by shutdown")
+
+var (
+	// baseCtx 所有脚本进程的父上下文，停机超时后取消以终止仍在运行的进程
+	baseCtx, cancelAll = context.WithCancel(context.Background())
+	// inflight 正在进行的执行及其后续通知
+	inflight sync.WaitGroup
+	// drainMutex 保证 draining 置位后不会再有 inflight.Add
+	drainMutex sync.Mutex
+	draining   bool
+)
+
+// RunningExecution 正在运行的执行
+type RunningExecution struct {
+	LogID     uint      `json:"log_id"`
+	TaskID    uint      `json:"task_id"`
+	TaskName  string    `json:"task_name"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// ExecutionError 最近一次失败的执行
+type ExecutionError struct {
+	LogID    uint      `json:"log_id"`
+	TaskID   uint      `json:"task_id"`
+	TaskName string    `json:"task_name"`
+	Status   string    `json:"status"`
+	Message  string    `json:"message"`
+	Time     time.Time `json:"time"`
+}
+
+var (
+	// running 按日志ID记录正在运行的执行
+	running      = make(map[uint]RunningExecution)
+	runningMutex sync.Mutex
+	// lastError 最近一次失败的执行
+	lastError *ExecutionError
+)
+
+// RunningExecutions 列出本实例正在运行的执行，按开始时间排序
+func RunningExecutions() []RunningExecution {
+	runningMutex.Lock()
+	defer runningMutex.Unlock()
+
+	result := make([]RunningExecution, 0, len(running))
+	for _, execution := range running {
+		result = append(result, execution)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].StartedAt.Before(result[j].StartedAt) })
+	return result
+}
+
+// LastError 本实例最近一次失败的执行，没有时返回 nil
+func LastError() *ExecutionError {
+	runningMutex.Lock()
+	defer runningMutex.Unlock()
+	if lastError == nil {
+		return nil
+	}
+	copied := *lastError
+	return &copied
+}
+
+// Accepting 是否仍在接受新的任务执行
+func Accepting() bool {
+	drainMutex.Lock()
+	defer drainMutex.Unlock()
+	return !draining
+}
+
+// beginExecution 登记一次执行，停机中返回 false
+func beginExecution() bool {
+	drainMutex.Lock()
+	defer drainMutex.Unlock()
+	if draining {
+		return false
+	}
+	inflight.Add(1)
+	return true
+}
+
+// cancelGrace 停机超时终止脚本后，等待执行收尾的最长时间
+const cancelGrace = 5 * time.Second
+
+// waitDelay 脚本进程被终止后等待输出管道关闭的最长时间，需小于 cancelGrace
+const waitDelay = 2 * time.Second
+
+// Shutdown 停止接受新的执行，并等待正在运行的执行完成
+// 如果 ctx 在全部完成前到期，则终止剩余的脚本进程，对应日志标记为 interrupted
+func Shutdown(ctx context.Context) error {
+	drainMutex.Lock()
+	draining = true
+	drainMutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		inflight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		slog.Warn("Shutdown deadline reached, cancelling running executions")
+		cancelAll()
+		// 等待被取消的执行写回日志状态；发送通知等不受取消控制的工作最多再等 cancelGrace
+		select {
+		case <-done:
+		case <-time.After(cancelGrace):
+			slog.Warn("Executions still running after cancel, giving up", "grace", cancelGrace)
+		}
+		return ctx.Err()
+	}
+}
+
 // ExecuteTask 执行任务
-func ExecuteTask(task *models.Task) {
+// ctx 携带调用方的追踪上下文和 logger（定时触发或手动执行的请求），不用于取消执行
+func ExecuteTask(ctx context.Context, task *models.Task) {
+	ctx, span := tracing.Start(ctx, "executor.execute_task",
+		attribute.Int64("task.id", int64(task.ID)),
+		attribute.String("task.name", task.Name),
+	)
+	defer span.End()
+
+	logger := logging.FromContext(ctx).With("task_id", task.ID, "task_name", task.Name)
+	if traceID := tracing.TraceID(ctx); traceID != "" {
+		logger = logger.With("trace_id", traceID)
+	}
+	if !beginExecution() {
+		logger.Warn("Executor is shutting down, skipping task")
+		span.SetAttributes(attribute.String("executor.skipped", "shutting_down"))
+		return
+	}
+	defer inflight.Done()
+
+	metrics.ExecutionStarted()
+	defer metrics.ExecutionFinished()
+
 	startTime := time.Now()
 
 	// 创建任务日志记录
@@ -38,6 +222,7 @@ func ExecuteTask(task *models.Task) {
 		TaskID:    task.ID,
 		StartTime: startTime,
 		Status:    "running",
+		Instance:  instanceID,
 	}
 
 	// 保存日志记录到数据库 - 使用重试机制确保数据一致性
@@ -46,14 +231,31 @@ func ExecuteTask(task *models.Task) {
 	})
 
 	if err != nil {
-		log.Printf("Failed to create task log after retries: %v", err)
+		logger.Error("Failed to create task log after retries", "error", err)
+		tracing.RecordError(span, err)
 		return
 	}
+	logger = logger.With("log_id", taskLog.ID)
+	span.SetAttributes(attribute.Int64("task.log_id", int64(taskLog.ID)))
+
+	runningMutex.Lock()
+	running[taskLog.ID] = RunningExecution{
+		LogID:     taskLog.ID,
+		TaskID:    task.ID,
+		TaskName:  task.Name,
+		StartedAt: startTime,
+	}
+	runningMutex.Unlock()
+	defer func() {
+		runningMutex.Lock()
+		delete(running, taskLog.ID)
+		runningMutex.Unlock()
+	}()
 
-	// log.Printf("Starting execution of task: %s (ID: %d)", task.Name, task.ID) // 减少执行开始日志
+	logger.Debug("Task execution started")
 
 	// 执行 Python 脚本
-	output, errorOutput, err := executePythonScript(task.Script)
+	output, errorOutput, err := executePythonScript(ctx, logger, task.Script)
 
 	endTime := time.Now()
 	duration := endTime.Sub(startTime).Milliseconds()
@@ -67,7 +269,10 @@ func ExecuteTask(task *models.Task) {
 	// 解析 Python 脚本的 JSON 结果
 	var result map[string]interface{}
 	if err == nil && output != "" {
-		result = parseJSONResult(output)
+		_, parseSpan := tracing.Start(ctx, "executor.parse_result")
+		result = parseJSONResult(logger, output)
+		parseSpan.SetAttributes(attribute.Bool("result.parsed", result != nil))
+		parseSpan.End()
 		if result != nil {
 			// 将结果序列化为 JSON 字符串存储
 			if resultJSON, jsonErr := json.Marshal(result); jsonErr == nil {
@@ -76,15 +281,28 @@ func ExecuteTask(task *models.Task) {
 		}
 	}
 
-	if err != nil {
+	if errors.Is(err, errInterrupted) {
+		taskLog.Status = "interrupted"
+		logger.Warn("Task execution interrupted")
+	} else if err != nil {
 		taskLog.Status = "execution_failed"
-		log.Printf("Task execution failed: %s (ID: %d), Error: %v", task.Name, task.ID, err)
+		logger.Error("Task execution failed", "error", err, "duration_ms", duration)
 	} else if result != nil && result["error"] != nil {
 		taskLog.Status = "script_failed"
-		log.Printf("Task script error: %s (ID: %d), Error: %v", task.Name, task.ID, result["error"])
+		logger.Warn("Task script reported an error", "error", result["error"], "duration_ms", duration)
 	} else {
 		taskLog.Status = "success"
-		log.Printf("Task execution completed: %s (ID: %d), Duration: %dms", task.Name, task.ID, duration)
+		logger.Info("Task execution completed", "duration_ms", duration)
+	}
+
+	metrics.ObserveExecution(task.ID, taskLog.Status, endTime.Sub(startTime))
+	span.SetAttributes(
+		attribute.String("task.status", taskLog.Status),
+		attribute.Int64("task.duration_ms", duration),
+	)
+	tracing.RecordError(span, err)
+	if taskLog.Status != "success" {
+		recordError(task, &taskLog, err)
 	}
 
 	// 保存更新后的日志 - 使用重试机制确保数据一致性
@@ -93,7 +311,7 @@ func ExecuteTask(task *models.Task) {
 	})
 
 	if err != nil {
-		log.Printf("Failed to update task log after retries: %v", err)
+		logger.Error("Failed to update task log after retries", "error", err)
 		return
 	}
 
@@ -101,8 +319,15 @@ func ExecuteTask(task *models.Task) {
 	// 新逻辑：不基于任务状态，而是基于JSON解析和占位符验证
 	// 改为异步执行，避免阻塞任务执行和持有数据库锁
 	// 注意：taskLog 已经完全保存到数据库，异步执行是安全的
-	if task.BarkConfig != "" {
-		go sendBarkNotification(task)
+	// 通知计入 inflight，停机时等待其发送完成
+	if task.BarkConfig != "" && taskLog.Status != "interrupted" {
+		inflight.Add(1)
+		metrics.NotificationStarted()
+		go func() {
+			defer inflight.Done()
+			defer metrics.NotificationFinished()
+			sendBarkNotification(ctx, logger, task)
+		}()
 	}
 
 	// 调用日志清理回调函数（如果设置了）
@@ -112,8 +337,36 @@ func ExecuteTask(task *models.Task) {
 	}
 }
 
+// recordError 记录最近一次失败的执行
+func recordError(task *models.Task, taskLog *models.TaskLog, err error) {
+	message := taskLog.Error
+	if err != nil {
+		message = err.Error()
+	} else if taskLog.Result != "" {
+		message = taskLog.Result
+	}
+
+	runningMutex.Lock()
+	lastError = &ExecutionError{
+		LogID:    taskLog.ID,
+		TaskID:   task.ID,
+		TaskName: task.Name,
+		Status:   taskLog.Status,
+		Message:  message,
+		Time:     taskLog.EndTime,
+	}
+	runningMutex.Unlock()
+}
+
 // executePythonScript 执行 Python 脚本
-func executePythonScript(script string) (output string, errorOutput string, err error) {
+// ctx 只用于追踪，取消由 baseCtx 和执行超时控制
+func executePythonScript(ctx context.Context, logger *slog.Logger, script string) (output string, errorOutput string, err error) {
+	traceCtx, span := tracing.Start(ctx, "executor.python", attribute.String("process.executable.name", pythonBinary))
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
 	// 创建临时目录
 	tempDir, err := ioutil.TempDir("", "autobot_task_")
 	if err != nil {
@@ -128,13 +381,9 @@ func executePythonScript(script string) (output string, errorOutput string, err
 	fullScript := script
 	if !containsMainCall(script) {
 		fullScript += "\n\nif __name__ == '__main__':\n    main()\n"
-		// log.Printf("Added main() call to script") // 减少脚本处理日志
-	} else {
-		// log.Printf("Script already contains main() call") // 减少脚本处理日志
+		logger.Debug("Added main() call to script")
 	}
-
-	// log.Printf("Writing script to file: %s", scriptFile) // 减少文件操作日志
-	// log.Printf("Full script content:\n%s", fullScript) // 减少脚本内容日志
+	logger.Debug("Writing script to file", "file", scriptFile, "bytes", len(fullScript))
 
 	// 同时写入调试文件
 	debugFile := filepath.Join(tempDir, "debug.log")
@@ -145,45 +394,49 @@ func executePythonScript(script string) (output string, errorOutput string, err
 		return "", "", fmt.Errorf("failed to write script file: %v", err)
 	}
 
+	// 设置执行超时，停机时 baseCtx 被取消也会终止进程
+	runCtx, cancel := context.WithTimeout(baseCtx, execTimeout)
+	defer cancel()
+
 	// 执行 Python 脚本（添加 -u 参数强制无缓冲输出）
-	cmd := exec.Command("python3", "-u", scriptFile)
+	// 脚本可通过 TRACEPARENT / AUTOBOT_TRACE_ID 环境变量关联到本次执行的 trace
+	cmd := exec.CommandContext(runCtx, pythonBinary, "-u", scriptFile)
 	cmd.Dir = tempDir
+	cmd.Env = append(os.Environ(), tracing.Environ(traceCtx)...)
+	// 超时或停机时终止整个进程组；仍有进程持有输出管道时最多再等 waitDelay，保证执行能按时结束
+	setProcessGroup(cmd)
+	cmd.WaitDelay = waitDelay
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	// 设置执行超时（10分钟）
-	timeout := time.After(10 * time.Minute)
-	done := make(chan error, 1)
+	err = cmd.Run()
+	output = stdout.String()
+	errorOutput = stderr.String()
 
-	go func() {
-		done <- cmd.Run()
-	}()
+	if baseCtx.Err() != nil {
+		return output, errorOutput, errInterrupted
+	}
+	if cmd.ProcessState != nil {
+		span.SetAttributes(attribute.Int("process.exit.code", cmd.ProcessState.ExitCode()))
+	}
+	if errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+		return "", "", fmt.Errorf("script execution timeout (%v)", execTimeout)
+	}
 
-	select {
-	case err := <-done:
-		output = stdout.String()
-		errorOutput = stderr.String()
-
-		// log.Printf("Script execution completed") // 减少执行完成日志
-		// log.Printf("Stdout length: %d", len(output)) // 减少输出长度日志
-		// log.Printf("Stderr length: %d", len(errorOutput)) // 减少错误长度日志
-		// log.Printf("Stdout content: %q", output) // 减少输出内容日志
-		// log.Printf("Stderr content: %q", errorOutput) // 减少错误内容日志
-
-		if err != nil {
-			return output, errorOutput, fmt.Errorf("script execution failed: %v", err)
-		}
-		return output, errorOutput, nil
+	logger.Debug("Script process exited", "stdout_bytes", len(output), "stderr_bytes", len(errorOutput))
 
-	case <-timeout:
-		// 超时，杀死进程
-		if cmd.Process != nil {
-			cmd.Process.Kill()
-		}
-		return "", "", fmt.Errorf("script execution timeout (10 minutes)")
+	// 脚本正常退出，但其启动的后台进程仍持有输出管道
+	if errors.Is(err, exec.ErrWaitDelay) {
+		logger.Warn("Script left child processes holding its output open", "wait_delay", waitDelay)
+		err = nil
+	}
+
+	if err != nil {
+		return output, errorOutput, fmt.Errorf("script execution failed: %v", err)
 	}
+	return output, errorOutput, nil
 }
 
 // containsMainCall 检查脚本是否包含 main() 函数调用
@@ -211,7 +464,7 @@ func ValidatePythonScript(script string) error {
 	}
 
 	// 使用 python -m py_compile 检查语法
-	cmd := exec.Command("python3", "-m", "py_compile", scriptFile)
+	cmd := exec.Command(pythonBinary, "-m", "py_compile", scriptFile)
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
@@ -225,7 +478,7 @@ func ValidatePythonScript(script string) error {
 // parseJSONResult 解析 Python 脚本输出的最后一行 JSON
 // 如果最后一行是有效的 JSON，返回解析后的 map[string]interface{}
 // 否则返回 nil
-func parseJSONResult(output string) map[string]interface{} {
+func parseJSONResult(logger *slog.Logger, output string) map[string]interface{} {
 	lines := strings.Split(strings.TrimSpace(output), "\n")
 	if len(lines) == 0 {
 		return nil
@@ -247,22 +500,22 @@ func parseJSONResult(output string) map[string]interface{} {
 	// 将单引号替换为双引号
 	jsonLine := strings.ReplaceAll(lastLine, "'", "\"")
 	if err := json.Unmarshal([]byte(jsonLine), &result); err == nil {
-		log.Printf("Successfully parsed Python dict format")
+		logger.Debug("Parsed result in Python dict format")
 		return result
 	} else {
 		// 都失败了，记录错误并返回 nil
-		// log.Printf("Failed to parse result as JSON, original output: %s", lastLine)
+		logger.Debug("Last output line is not a JSON result", "line", lastLine)
 		return nil
 	}
 }
 
 // sendBarkNotification 发送 Bark 通知
-func sendBarkNotification(task *models.Task) {
+func sendBarkNotification(ctx context.Context, logger *slog.Logger, task *models.Task) {
 	// 创建通知器实例
-	barkNotifier := notifier.New(database.GetDB())
+	barkNotifier := notifier.New(database.GetDB()).WithLogger(logger)
 
 	// 处理并发送 Bark 通知（从最新执行记录中获取 result）
-	if err := barkNotifier.ProcessBarkNotification(task); err != nil {
-		log.Printf("Failed to send Bark notification for task %d: %v", task.ID, err)
+	if err := barkNotifier.ProcessBarkNotification(ctx, task); err != nil {
+		logger.Error("Failed to send Bark notification", "error", err)
 	}
 }
@@ -0,0 +1,17 @@
+//go:build !windows
+
+package executor
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup 让脚本进程成为新进程组的组长，取消时终止整个进程组
+// 脚本启动的子进程会继承 stdout/stderr，只终止 python 进程时管道不会关闭
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
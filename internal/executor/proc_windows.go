@@ -0,0 +1,8 @@
+//go:build windows
+
+package executor
+
+import "os/exec"
+
+// setProcessGroup Windows 上只终止脚本进程，残留子进程占用的管道由 WaitDelay 兜底
+func setProcessGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,142 @@
+package gitsync
+
+import (
+	"autobot/internal/bundle"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// headerDelimiter 任务文件头部的起止行
+//
+// 任务文件是普通的 Python 脚本，开头用注释写 YAML 格式的任务定义，例如：
+//
+//	# ---
+//	# name: 每日签到
+//	# cron_expr: "0 0 8 * * *"
+//	# bark:
+//	#   title: 签到结果
+//	#   devices: [iPhone]
+//	# ---
+//	def main():
+//	    ...
+//
+// 头部字段与任务包（bundle）中的任务定义一致，name 默认为文件名，status 默认为 active。
+// 整个文件（包括头部注释）作为任务脚本保存。
+const headerDelimiter = "# ---"
+
+// taskFileExt 任务文件扩展名
+const taskFileExt = ".py"
+
+// taskFile 从仓库读取的任务文件
+type taskFile struct {
+	Path string // 相对同步目录的路径，使用 / 分隔
+	Spec bundle.TaskSpec
+	Err  error // 解析失败的原因
+}
+
+// loadTaskFiles 递归读取目录下的全部任务文件，按路径排序
+func loadTaskFiles(dir string) ([]taskFile, error) {
+	var files []taskFile
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			// 跳过 .git 等隐藏目录
+			if path != dir && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) != taskFileExt {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		file := taskFile{Path: filepath.ToSlash(rel)}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			file.Err = err
+		} else {
+			file.Spec, file.Err = parseTaskFile(file.Path, data)
+		}
+		files = append(files, file)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read task directory %s: %v", dir, err)
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+// parseTaskFile 解析任务文件的 YAML 头部
+func parseTaskFile(path string, data []byte) (bundle.TaskSpec, error) {
+	var spec bundle.TaskSpec
+
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+
+	// 头部之前允许空行和 #! 行
+	start := -1
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#!") {
+			continue
+		}
+		if trimmed == headerDelimiter {
+			start = i
+		}
+		break
+	}
+	if start < 0 {
+		return spec, fmt.Errorf("missing task header (file must start with %q)", headerDelimiter)
+	}
+
+	var header []string
+	end := -1
+	for i := start + 1; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if trimmed == headerDelimiter {
+			end = i
+			break
+		}
+		if trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			return spec, fmt.Errorf("line %d: task header lines must be comments", i+1)
+		}
+		line := strings.TrimPrefix(trimmed, "#")
+		line = strings.TrimPrefix(line, " ")
+		header = append(header, line)
+	}
+	if end < 0 {
+		return spec, fmt.Errorf("task header is not closed with %q", headerDelimiter)
+	}
+
+	converted, err := bundle.YAMLToJSON([]byte(strings.Join(header, "\n")))
+	if err != nil {
+		return spec, fmt.Errorf("invalid task header: %v", err)
+	}
+	if string(converted) != "null" {
+		if err := json.Unmarshal(converted, &spec); err != nil {
+			return spec, fmt.Errorf("invalid task header: %v", err)
+		}
+	}
+
+	if spec.Name == "" {
+		spec.Name = strings.TrimSuffix(filepath.Base(path), taskFileExt)
+	}
+	if spec.Status == "" {
+		spec.Status = "active"
+	}
+	spec.Script = string(data)
+
+	return spec, nil
+}
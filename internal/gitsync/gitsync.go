@@ -0,0 +1,482 @@
+package gitsync
+
+import (
+	"autobot/internal/bundle"
+	"autobot/internal/database"
+	"autobot/internal/models"
+	"context"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 同步动作
+const (
+	ActionCreate     = "create"     // 仓库中新增的任务
+	ActionUpdate     = "update"     // 数据库与仓库不一致，按仓库更新
+	ActionUnchanged  = "unchanged"  // 已一致
+	ActionDelete     = "delete"     // 仓库中已删除，prune 时删除任务
+	ActionDeactivate = "deactivate" // 仓库中已删除，未开启 prune 时停用任务
+	ActionError      = "error"      // 文件无法解析或与已有任务冲突
+)
+
+// gitTimeout 单次 git 命令的超时
+const gitTimeout = time.Minute
+
+// Options 同步配置
+type Options struct {
+	Repo     string        // 本地 git 仓库路径
+	Dir      string        // 任务文件所在的子目录，为空时使用仓库根目录
+	Interval time.Duration // 定时同步间隔，0 表示只手动同步
+	Pull     bool          // 同步前执行 git pull --ff-only
+	Prune    bool          // 删除仓库中已不存在的任务（否则只停用）
+}
+
+// TaskUpdater 同步后更新调度器
+type TaskUpdater interface {
+	UpdateTask(task *models.Task) error
+	RemoveTask(taskID uint)
+}
+
+// Item 单个任务文件（或孤立任务）的同步结果
+type Item struct {
+	Path    string   `json:"path"`
+	TaskID  uint     `json:"task_id,omitempty"`
+	Name    string   `json:"name,omitempty"`
+	Action  string   `json:"action"`
+	Fields  []string `json:"fields,omitempty"` // 与仓库不一致的字段
+	Message string   `json:"message,omitempty"`
+}
+
+// Result 一次同步（或漂移检查）的结果
+type Result struct {
+	DryRun     bool      `json:"dry_run"`
+	Revision   string    `json:"revision"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Items      []Item    `json:"items"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Drifted 数据库是否与仓库不一致
+func (r *Result) Drifted() bool {
+	for _, item := range r.Items {
+		if item.Action != ActionUnchanged && item.Action != ActionError {
+			return true
+		}
+	}
+	return false
+}
+
+// Syncer 将 git 仓库中的任务文件同步到任务表
+type Syncer struct {
+	opts     Options
+	updater  TaskUpdater
+	isLeader func() bool
+
+	mutex    sync.Mutex // 防止并发同步
+	last     *Result
+	lastLock sync.RWMutex
+	stop     chan struct{}
+
+	// 上次同步的脚本语法检查结果，脚本未变化时不再启动 python 检查
+	scripts     bundle.ScriptErrors
+	scriptsLock sync.Mutex
+	done        chan struct{}
+}
+
+// NewSyncer 创建同步器
+// isLeader 用于多实例部署时只让 Leader 执行定时同步，为 nil 时总是执行
+func NewSyncer(opts Options, updater TaskUpdater, isLeader func() bool) *Syncer {
+	return &Syncer{
+		opts:     opts,
+		updater:  updater,
+		isLeader: isLeader,
+	}
+}
+
+// Options 同步配置
+func (s *Syncer) Options() Options {
+	return s.opts
+}
+
+// Start 启动定时同步，启动时先同步一次
+func (s *Syncer) Start() {
+	if s.opts.Interval <= 0 {
+		return
+	}
+
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(s.opts.Interval)
+		defer ticker.Stop()
+
+		for {
+			if s.isLeader == nil || s.isLeader() {
+				if _, err := s.Sync(); err != nil {
+					slog.Error("Git sync failed", "error", err)
+				}
+			}
+
+			select {
+			case <-ticker.C:
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止定时同步，等待正在进行的同步完成
+func (s *Syncer) Stop() {
+	if s.stop == nil {
+		return
+	}
+	close(s.stop)
+	<-s.done
+}
+
+// LastResult 最近一次同步的结果，尚未同步过时为 nil
+func (s *Syncer) LastResult() *Result {
+	s.lastLock.RLock()
+	defer s.lastLock.RUnlock()
+	return s.last
+}
+
+// Sync 拉取仓库（如果配置了）并将任务文件同步到数据库
+func (s *Syncer) Sync() (*Result, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	result, err := s.run(false)
+	if result != nil {
+		if err != nil {
+			result.Error = err.Error()
+		}
+		s.lastLock.Lock()
+		s.last = result
+		s.lastLock.Unlock()
+	}
+	return result, err
+}
+
+// Drift 对比仓库与数据库，只报告差异，不拉取也不写入
+func (s *Syncer) Drift() (*Result, error) {
+	return s.run(true)
+}
+
+// run 执行一次同步，dryRun 时在事务结束时回滚
+func (s *Syncer) run(dryRun bool) (*Result, error) {
+	result := &Result{DryRun: dryRun, StartedAt: time.Now(), Items: []Item{}}
+
+	if !dryRun && s.opts.Pull {
+		if _, err := s.git("pull", "--ff-only"); err != nil {
+			result.FinishedAt = time.Now()
+			return result, fmt.Errorf("git pull failed: %v", err)
+		}
+	}
+
+	revision, err := s.git("rev-parse", "HEAD")
+	if err != nil {
+		// 允许使用非 git 目录，此时不记录提交
+		slog.Warn("Git sync: failed to read revision", "repo", s.opts.Repo, "error", err)
+	}
+	result.Revision = revision
+
+	files, err := loadTaskFiles(filepath.Join(s.opts.Repo, s.opts.Dir))
+	if err != nil {
+		result.FinishedAt = time.Now()
+		return result, err
+	}
+
+	// 脚本检查会启动 python 进程，在事务外完成，避免长时间占用写锁
+	scripts := s.validateScripts(files)
+
+	// errDryRun 用于在漂移检查结束时回滚事务
+	errDryRun := fmt.Errorf("dry run")
+
+	var changed []models.Task
+	var removed []uint
+	err = database.WithRetry(func(db *gorm.DB) error {
+		result.Items = result.Items[:0]
+		changed, removed = nil, nil
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			r := &reconciler{tx: tx, revision: revision, prune: s.opts.Prune, result: result, scripts: scripts}
+			if err := r.run(files); err != nil {
+				return err
+			}
+			changed, removed = r.changed, r.removed
+			if dryRun {
+				return errDryRun
+			}
+			return nil
+		})
+		if err == errDryRun {
+			return nil
+		}
+		return err
+	})
+	result.FinishedAt = time.Now()
+	if err != nil {
+		return result, err
+	}
+
+	if !dryRun {
+		s.applySchedule(changed, removed)
+		if result.Drifted() {
+			slog.Info("Git sync applied changes", "revision", shortRevision(revision))
+		}
+	}
+	return result, nil
+}
+
+// validateScripts 检查任务文件中的脚本语法，复用上次同步中相同脚本的结果
+func (s *Syncer) validateScripts(files []taskFile) bundle.ScriptErrors {
+	specs := make([]bundle.TaskSpec, 0, len(files))
+	for _, file := range files {
+		if file.Err == nil {
+			specs = append(specs, file.Spec)
+		}
+	}
+
+	s.scriptsLock.Lock()
+	defer s.scriptsLock.Unlock()
+	s.scripts = bundle.ValidateScripts(specs, s.scripts)
+	return s.scripts
+}
+
+// applySchedule 同步调度器
+func (s *Syncer) applySchedule(changed []models.Task, removed []uint) {
+	if s.updater == nil {
+		return
+	}
+	for i := range changed {
+		task := changed[i]
+		if err := s.updater.UpdateTask(&task); err != nil {
+			slog.Error("Failed to schedule synced task", "task_id", task.ID, "error", err)
+		}
+	}
+	for _, id := range removed {
+		s.updater.RemoveTask(id)
+	}
+}
+
+// git 在仓库目录中执行 git 命令，返回去掉首尾空白的输出
+func (s *Syncer) git(args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), gitTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", s.opts.Repo}, args...)...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%v: %s", err, strings.TrimSpace(string(output)))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// shortRevision 提交的短格式
+func shortRevision(revision string) string {
+	if len(revision) > 12 {
+		return revision[:12]
+	}
+	return revision
+}
+
+// reconciler 单次同步的状态
+type reconciler struct {
+	tx       *gorm.DB
+	revision string
+	prune    bool
+	result   *Result
+	scripts  bundle.ScriptErrors
+
+	changed []models.Task
+	removed []uint
+}
+
+func (r *reconciler) run(files []taskFile) error {
+	var existing []models.Task
+	if err := r.tx.Where("source = ?", models.TaskSourceGit).Find(&existing).Error; err != nil {
+		return err
+	}
+	byPath := make(map[string]models.Task, len(existing))
+	for _, task := range existing {
+		byPath[task.SourcePath] = task
+	}
+
+	seen := make(map[string]bool, len(files))
+	for _, file := range files {
+		// 解析失败的文件也视为存在，避免误删对应的任务
+		seen[file.Path] = true
+		task, found := byPath[file.Path]
+		if err := r.syncFile(file, task, found); err != nil {
+			return err
+		}
+	}
+
+	for _, task := range existing {
+		if seen[task.SourcePath] {
+			continue
+		}
+		if err := r.removeTask(task); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncFile 将一个任务文件同步到数据库
+func (r *reconciler) syncFile(file taskFile, task models.Task, found bool) error {
+	item := Item{Path: file.Path, Name: file.Spec.Name}
+	if found {
+		item.TaskID = task.ID
+	}
+
+	if file.Err != nil {
+		item.Action = ActionError
+		item.Message = file.Err.Error()
+		r.result.Items = append(r.result.Items, item)
+		return nil
+	}
+
+	desired, warning, err := file.Spec.ToTask(r.scripts[file.Spec.Script], func(name string) (uint, bool) {
+		var device models.BarkDevice
+		if err := r.tx.Where("name = ?", name).First(&device).Error; err != nil {
+			return 0, false
+		}
+		return device.ID, true
+	}, bundle.GroupResolver(r.tx), bundle.ChannelResolver(r.tx))
+	if err != nil {
+		item.Action = ActionError
+		item.Message = bundle.JoinMessages(err.Error(), warning)
+		r.result.Items = append(r.result.Items, item)
+		return nil
+	}
+	item.Message = warning
+
+	// 不接管界面创建的同名任务
+	var conflict int64
+	query := r.tx.Model(&models.Task{}).Where("name = ? AND (source IS NULL OR source <> ?)", desired.Name, models.TaskSourceGit)
+	if err := query.Count(&conflict).Error; err != nil {
+		return err
+	}
+	if conflict > 0 {
+		item.Action = ActionError
+		item.Message = "a task with the same name already exists and is not managed by git sync"
+		r.result.Items = append(r.result.Items, item)
+		return nil
+	}
+
+	if !found {
+		desired.Source = models.TaskSourceGit
+		desired.SourcePath = file.Path
+		desired.SourceRevision = r.revision
+		if err := r.tx.Create(&desired).Error; err != nil {
+			return err
+		}
+		item.TaskID = desired.ID
+		item.Action = ActionCreate
+		r.changed = append(r.changed, desired)
+		r.result.Items = append(r.result.Items, item)
+		return nil
+	}
+
+	item.Fields = diffFields(&task, &desired)
+	if len(item.Fields) == 0 {
+		item.Action = ActionUnchanged
+		if task.SourceRevision != r.revision {
+			// 内容未变，只记录最新提交
+			if err := r.tx.Model(&task).Update("source_revision", r.revision).Error; err != nil {
+				return err
+			}
+		}
+		r.result.Items = append(r.result.Items, item)
+		return nil
+	}
+
+	task.Name = desired.Name
+	task.Description = desired.Description
+	task.Script = desired.Script
+	task.CronExpr = desired.CronExpr
+	task.Status = desired.Status
+	task.BarkConfig = desired.BarkConfig
+	task.TimeExclusionConfig = desired.TimeExclusionConfig
+	task.SourceRevision = r.revision
+	if err := r.tx.Save(&task).Error; err != nil {
+		return err
+	}
+	item.Action = ActionUpdate
+	r.changed = append(r.changed, task)
+	r.result.Items = append(r.result.Items, item)
+	return nil
+}
+
+// removeTask 处理仓库中已删除的任务
+func (r *reconciler) removeTask(task models.Task) error {
+	item := Item{Path: task.SourcePath, TaskID: task.ID, Name: task.Name}
+
+	if r.prune {
+		if err := r.tx.Where("task_id = ?", task.ID).Delete(&models.TaskLog{}).Error; err != nil {
+			return err
+		}
+		if err := r.tx.Delete(&models.Task{}, task.ID).Error; err != nil {
+			return err
+		}
+		item.Action = ActionDelete
+		r.removed = append(r.removed, task.ID)
+		r.result.Items = append(r.result.Items, item)
+		return nil
+	}
+
+	// 未开启 prune 时只停用，保留执行日志
+	if task.Status == "inactive" {
+		item.Action = ActionUnchanged
+		item.Message = "task file was removed from the repository, task is inactive"
+		r.result.Items = append(r.result.Items, item)
+		return nil
+	}
+	task.Status = "inactive"
+	if err := r.tx.Save(&task).Error; err != nil {
+		return err
+	}
+	item.Action = ActionDeactivate
+	item.Fields = []string{"status"}
+	item.Message = "task file was removed from the repository"
+	r.changed = append(r.changed, task)
+	r.result.Items = append(r.result.Items, item)
+	return nil
+}
+
+// diffFields 列出与仓库定义不一致的字段
+func diffFields(current *models.Task, desired *models.Task) []string {
+	var fields []string
+	compare := []struct {
+		name    string
+		current string
+		desired string
+	}{
+		{"name", current.Name, desired.Name},
+		{"description", current.Description, desired.Description},
+		{"script", current.Script, desired.Script},
+		{"cron_expr", current.CronExpr, desired.CronExpr},
+		{"status", current.Status, desired.Status},
+		{"bark_config", current.BarkConfig, desired.BarkConfig},
+		{"time_exclusion_config", current.TimeExclusionConfig, desired.TimeExclusionConfig},
+	}
+	for _, field := range compare {
+		if field.current != field.desired {
+			fields = append(fields, field.name)
+		}
+	}
+	return fields
+}
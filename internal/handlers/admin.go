@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"autobot/internal/backup"
+	"autobot/internal/config"
+	"autobot/internal/leader"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 全局 Leader 选举器实例
+var globalElector *leader.Elector
+
+// SetElector 设置全局 Leader 选举器
+func SetElector(e *leader.Elector) {
+	globalElector = e
+}
+
+// 全局运行时配置
+var globalConfig *config.Config
+
+// SetConfig 设置全局运行时配置
+func SetConfig(cfg *config.Config) {
+	globalConfig = cfg
+}
+
+// 全局备份管理器
+var globalBackupManager *backup.Manager
+
+// SetBackupManager 设置全局备份管理器
+func SetBackupManager(m *backup.Manager) {
+	globalBackupManager = m
+}
+
+// 管理相关API
+
+// GetConfig 获取当前生效的配置（隐藏敏感信息）
+func GetConfig(c *gin.Context) {
+	if globalConfig == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "配置未初始化"})
+		return
+	}
+
+	c.JSON(http.StatusOK, globalConfig.Redacted())
+}
+
+// GetSchedulerState 获取调度器状态
+func GetSchedulerState(c *gin.Context) {
+	if globalScheduler == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "调度器未初始化"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"paused":          globalScheduler.IsPaused(),
+		"running":         globalScheduler.IsRunning(),
+		"scheduled_tasks": globalScheduler.GetScheduledTasks(),
+	})
+}
+
+// GetClusterStatus 获取多实例 Leader 状态
+func GetClusterStatus(c *gin.Context) {
+	if globalElector == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Leader 选举未初始化"})
+		return
+	}
+
+	lease, err := globalElector.CurrentLease()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取租约信息失败"})
+		return
+	}
+
+	response := gin.H{
+		"instance_id": globalElector.InstanceID(),
+		"is_leader":   globalElector.IsLeader(),
+		"leader_id":   nil,
+	}
+	if lease != nil {
+		response["leader_id"] = lease.HolderID
+		response["lease_expires_at"] = lease.ExpiresAt
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// PauseScheduler 全局暂停调度（不修改任务激活状态）
+func PauseScheduler(c *gin.Context) {
+	if globalScheduler == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "调度器未初始化"})
+		return
+	}
+
+	if err := globalScheduler.Pause(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "暂停调度失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "调度已暂停", "paused": true})
+}
+
+// ResumeScheduler 恢复全局调度
+func ResumeScheduler(c *gin.Context) {
+	if globalScheduler == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "调度器未初始化"})
+		return
+	}
+
+	if err := globalScheduler.Resume(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "恢复调度失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "调度已恢复", "paused": false})
+}
+
+// CreateBackup 创建在线备份
+func CreateBackup(c *gin.Context) {
+	if globalBackupManager == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "备份管理器未初始化"})
+		return
+	}
+
+	file, err := globalBackupManager.Create()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建备份失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, file)
+}
+
+// GetBackups 获取备份列表
+func GetBackups(c *gin.Context) {
+	if globalBackupManager == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "备份管理器未初始化"})
+		return
+	}
+
+	files, err := globalBackupManager.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取备份列表失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"backups": files,
+		"dir":     globalBackupManager.Dir(),
+	})
+}
+
+// DownloadBackup 下载备份文件
+func DownloadBackup(c *gin.Context) {
+	if globalBackupManager == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "备份管理器未初始化"})
+		return
+	}
+
+	file, err := globalBackupManager.Get(c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "备份不存在"})
+		return
+	}
+
+	c.FileAttachment(file.Path, file.Name)
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"autobot/internal/database"
+	"autobot/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetBarkDigestRecords 获取汇总消息合并的通知记录
+func GetBarkDigestRecords(c *gin.Context) {
+	recordID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的记录ID"})
+		return
+	}
+
+	var digest models.BarkRecord
+	var records []models.BarkRecord
+	err = database.WithRetry(func(db *gorm.DB) error {
+		if err := db.First(&digest, recordID).Error; err != nil {
+			return err
+		}
+		return db.Where("digest_id = ?", digest.ID).Order("created_at asc").Find(&records).Error
+	})
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "记录不存在"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取汇总记录失败"})
+		return
+	}
+	if digest.DigestCount == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "该记录不是汇总消息"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"digest":  digest,
+		"records": records,
+	})
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"autobot/internal/database"
+	"autobot/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Bark 设备分组管理API
+
+// CreateBarkDeviceGroup 创建设备分组
+func CreateBarkDeviceGroup(c *gin.Context) {
+	var req models.CreateBarkDeviceGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if msg := checkGroupName(req.Name, 0); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	devices, msg := findGroupDevices(req.DeviceIDs)
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	group := models.BarkDeviceGroup{
+		Name:        req.Name,
+		Description: req.Description,
+		Devices:     devices,
+	}
+	err := database.WithRetry(func(db *gorm.DB) error {
+		return db.Create(&group).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建设备分组失败"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, group)
+}
+
+// GetBarkDeviceGroups 获取设备分组列表，包含成员设备
+func GetBarkDeviceGroups(c *gin.Context) {
+	var groups []models.BarkDeviceGroup
+	err := database.WithRetry(func(db *gorm.DB) error {
+		return db.Preload("Devices", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id, name, device_key, status").Order("name asc")
+		}).Order("name asc").Find(&groups).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取设备分组列表失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"groups": groups,
+		"total":  len(groups),
+	})
+}
+
+// GetBarkDeviceGroup 获取单个设备分组
+func GetBarkDeviceGroup(c *gin.Context) {
+	group, ok := findDeviceGroup(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, group)
+}
+
+// UpdateBarkDeviceGroup 更新设备分组，提供 device_ids 时替换全部成员
+func UpdateBarkDeviceGroup(c *gin.Context) {
+	group, ok := findDeviceGroup(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateBarkDeviceGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Name != "" && req.Name != group.Name {
+		if msg := checkGroupName(req.Name, group.ID); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+		group.Name = req.Name
+	}
+	if req.Description != nil {
+		group.Description = *req.Description
+	}
+	var devices []models.BarkDevice
+	if req.DeviceIDs != nil {
+		var msg string
+		if devices, msg = findGroupDevices(req.DeviceIDs); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+	}
+
+	err := database.WithRetry(func(db *gorm.DB) error {
+		return db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Omit("Devices").Save(group).Error; err != nil {
+				return err
+			}
+			if req.DeviceIDs == nil {
+				return nil
+			}
+			return tx.Model(group).Association("Devices").Replace(devices)
+		})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新设备分组失败"})
+		return
+	}
+
+	if group, ok = findDeviceGroup(c); ok {
+		c.JSON(http.StatusOK, group)
+	}
+}
+
+// DeleteBarkDeviceGroup 删除设备分组，引用该分组的任务不再向其发送
+func DeleteBarkDeviceGroup(c *gin.Context) {
+	group, ok := findDeviceGroup(c)
+	if !ok {
+		return
+	}
+
+	err := database.WithRetry(func(db *gorm.DB) error {
+		return db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(group).Association("Devices").Clear(); err != nil {
+				return err
+			}
+			return tx.Delete(&models.BarkDeviceGroup{}, group.ID).Error
+		})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除设备分组失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "设备分组删除成功",
+		"group_name": group.Name,
+	})
+}
+
+// findDeviceGroup 按路径参数 id 查找设备分组，失败时已写入响应
+func findDeviceGroup(c *gin.Context) (*models.BarkDeviceGroup, bool) {
+	groupID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的分组ID"})
+		return nil, false
+	}
+
+	var group models.BarkDeviceGroup
+	if err := database.GetDB().Preload("Devices").First(&group, groupID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "设备分组不存在"})
+		return nil, false
+	}
+	return &group, true
+}
+
+// checkGroupName 分组名称需唯一（导入导出按名称引用分组），返回错误信息
+func checkGroupName(name string, excludeID uint) string {
+	var count int64
+	database.GetDB().Model(&models.BarkDeviceGroup{}).Where("name = ? AND id <> ?", name, excludeID).Count(&count)
+	if count > 0 {
+		return "分组名称已存在"
+	}
+	return ""
+}
+
+// findGroupDevices 查找分组成员，有不存在的设备时返回错误信息
+func findGroupDevices(ids []uint) ([]models.BarkDevice, string) {
+	devices := []models.BarkDevice{}
+	if len(ids) == 0 {
+		return devices, ""
+	}
+	if err := database.GetDB().Where("id IN ?", ids).Find(&devices).Error; err != nil {
+		return nil, "获取设备失败"
+	}
+
+	found := make(map[uint]bool, len(devices))
+	for _, device := range devices {
+		found[device.ID] = true
+	}
+	for _, id := range ids {
+		if !found[id] {
+			return nil, fmt.Sprintf("设备不存在: %d", id)
+		}
+	}
+	return devices, ""
+}
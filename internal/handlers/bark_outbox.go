@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"autobot/internal/database"
+	"autobot/internal/models"
+	"autobot/internal/notifier"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Bark 投递队列API
+
+// GetBarkOutbox 获取等待投递和进入死信的推送
+func GetBarkOutbox(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 500 {
+		limit = 50
+	}
+
+	status := c.Query("status")
+	switch status {
+	case "", models.OutboxPending, models.OutboxSending, models.OutboxDead:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的状态，可选 pending、sending、dead"})
+		return
+	}
+
+	var entries []models.BarkOutbox
+	var total int64
+	err = database.WithRetry(func(db *gorm.DB) error {
+		query := db.Model(&models.BarkOutbox{})
+		if status != "" {
+			query = query.Where("status = ?", status)
+		}
+		if err := query.Count(&total).Error; err != nil {
+			return err
+		}
+		return query.Order("next_attempt_at asc").Limit(limit).Find(&entries).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取投递队列失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries": entries,
+		"total":   total,
+	})
+}
+
+// ResendBarkRecord 重发失败的 Bark 发送记录
+func ResendBarkRecord(c *gin.Context) {
+	recordID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的记录ID"})
+		return
+	}
+
+	record, err := notifier.New(database.GetDB()).Resend(c.Request.Context(), uint(recordID))
+	switch {
+	case errors.Is(err, notifier.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "记录不存在"})
+		return
+	case errors.Is(err, notifier.ErrNotResendable):
+		c.JSON(http.StatusConflict, gin.H{"error": "只能重发发送失败的 Bark 设备记录"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "重发失败: " + err.Error()})
+		return
+	}
+
+	message := "已重新发送"
+	switch record.Status {
+	case "pending":
+		message = "重发未成功，已加入重试队列"
+	case "failed":
+		message = "重发失败: " + record.ErrorMessage
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"record":  record,
+	})
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"autobot/internal/barkhistory"
+	"autobot/internal/database"
+	"autobot/internal/digest"
+	"autobot/internal/models"
+	"autobot/internal/msgtemplate"
+	"autobot/internal/notifier"
+	"autobot/internal/rules"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Bark 通知预览和测试API
+
+// PreviewBarkConfig 按最近一次执行结果（或传入的结果）渲染任务的通知配置，不实际发送
+// bark_config 为空时使用任务已保存的配置；result 可以是 JSON 对象或 JSON 字符串
+func PreviewBarkConfig(c *gin.Context) {
+	taskID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的任务ID"})
+		return
+	}
+
+	var req struct {
+		BarkConfig string          `json:"bark_config"`
+		Result     json.RawMessage `json:"result"`
+	}
+	// 请求体可以为空
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	var task models.Task
+	if err := database.GetDB().First(&task, taskID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		return
+	}
+
+	configJSON := req.BarkConfig
+	if configJSON == "" {
+		configJSON = task.BarkConfig
+	}
+	if configJSON == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "任务未配置 Bark 通知"})
+		return
+	}
+	var barkConfig models.BarkConfig
+	if err := json.Unmarshal([]byte(configJSON), &barkConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的 Bark 配置格式"})
+		return
+	}
+	if err := rules.Validate(barkConfig.Rules); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "通知规则无效: " + err.Error()})
+		return
+	}
+	if err := rules.ValidateRoutes(barkConfig.Routes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "设备路由无效: " + err.Error()})
+		return
+	}
+	if err := msgtemplate.ValidateConfig(&barkConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "通知模板无效: " + err.Error()})
+		return
+	}
+	if err := digest.Validate(&barkConfig.Digest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "通知汇总配置无效: " + err.Error()})
+		return
+	}
+	if err := barkhistory.ValidateDeduplication(&barkConfig.Deduplication); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "去重配置无效: " + err.Error()})
+		return
+	}
+
+	result, err := parsePreviewResult(req.Result)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的结果 JSON，需为对象"})
+		return
+	}
+
+	preview, err := notifier.New(database.GetDB()).Preview(c.Request.Context(), &task, &barkConfig, result)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "预览失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, preview)
+}
+
+// parsePreviewResult 解析预览使用的结果，未提供时返回 nil
+func parsePreviewResult(raw json.RawMessage) (map[string]interface{}, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+
+	// 兼容以字符串形式传入的结果
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		if text == "" {
+			return nil, nil
+		}
+		raw = json.RawMessage(text)
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, err
+	}
+	if result == nil {
+		result = map[string]interface{}{}
+	}
+	return result, nil
+}
+
+// TestBarkDevice 向设备发送一条测试推送，结果记录到发送历史
+func TestBarkDevice(c *gin.Context) {
+	deviceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的设备ID"})
+		return
+	}
+
+	var device models.BarkDevice
+	if err := database.GetDB().First(&device, deviceID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "设备不存在"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	record, err := notifier.New(database.GetDB()).SendTest(ctx, &device)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":  "测试推送发送失败: " + err.Error(),
+			"record": record,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "测试推送已发送",
+		"record":  record,
+	})
+}
+
+// CheckBarkServer 立即检查服务器健康状态并保存结果
+func CheckBarkServer(c *gin.Context) {
+	serverID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的服务器ID"})
+		return
+	}
+
+	var server models.BarkServer
+	if err := database.GetDB().First(&server, serverID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "服务器不存在"})
+		return
+	}
+
+	checked, err := notifier.CheckBarkServer(c.Request.Context(), &server)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存检查结果失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, checked)
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"autobot/internal/barkhistory"
+	"autobot/internal/database"
+	"autobot/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Bark 发送记录筛选与导出
+
+// maxExportRecords 单次导出的最大记录数
+const maxExportRecords = 100000
+
+// parseRecordFilter 从查询参数解析发送记录筛选条件，返回错误信息
+// 参数：task_id、device_id 或 device_key、channel_id、status（逗号分隔）、
+// from/to（YYYY-MM-DD 或 RFC3339，按日期指定时 to 包含当天）、q（搜索标题和内容）
+func parseRecordFilter(c *gin.Context) (barkhistory.RecordFilter, string) {
+	var filter barkhistory.RecordFilter
+
+	if value := c.Query("task_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return filter, "无效的任务ID"
+		}
+		filter.TaskID = uint(id)
+	}
+	if value := c.Query("channel_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return filter, "无效的渠道ID"
+		}
+		filter.ChannelID = uint(id)
+	}
+
+	filter.DeviceKey = c.Query("device_key")
+	if value := c.Query("device_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return filter, "无效的设备ID"
+		}
+		var device models.BarkDevice
+		if err := database.GetDB().Select("id, device_key").First(&device, id).Error; err != nil {
+			return filter, "设备不存在"
+		}
+		filter.DeviceKey = device.DeviceKey
+	}
+
+	if value := c.Query("status"); value != "" {
+		for _, status := range strings.Split(value, ",") {
+			if status = strings.TrimSpace(status); status != "" {
+				filter.Statuses = append(filter.Statuses, status)
+			}
+		}
+	}
+
+	var err error
+	if filter.From, err = parseTimeParam(c.Query("from"), false); err != nil {
+		return filter, "无效的开始时间"
+	}
+	if filter.To, err = parseTimeParam(c.Query("to"), true); err != nil {
+		return filter, "无效的结束时间"
+	}
+	filter.Query = c.Query("q")
+
+	if err := filter.Validate(); err != nil {
+		return filter, "无效的筛选条件: " + err.Error()
+	}
+	return filter, ""
+}
+
+// parseTimeParam 解析 YYYY-MM-DD（本地时区）或 RFC3339 时间，endOfDay 时日期取次日零点
+func parseTimeParam(value string, endOfDay bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		if endOfDay {
+			t = t.AddDate(0, 0, 1)
+		}
+		return &t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// ExportBarkRecords 导出筛选后的Bark发送记录
+// 参数：format=csv|json（默认 csv），筛选参数与记录列表相同
+func ExportBarkRecords(c *gin.Context) {
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的导出格式，可选 csv 或 json"})
+		return
+	}
+	filter, msg := parseRecordFilter(c)
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	historyManager := barkhistory.NewBarkHistoryManager()
+	records, err := historyManager.ExportBarkRecords(filter, maxExportRecords)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取Bark记录失败"})
+		return
+	}
+
+	var buf bytes.Buffer
+	contentType := "text/csv; charset=utf-8"
+	if format == "json" {
+		contentType = "application/json; charset=utf-8"
+		err = json.NewEncoder(&buf).Encode(records)
+	} else {
+		err = barkhistory.WriteCSV(&buf, records)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "序列化导出数据失败"})
+		return
+	}
+
+	if len(records) == maxExportRecords {
+		c.Header("X-Export-Truncated", "true")
+	}
+	filename := fmt.Sprintf("autobot-bark-records-%s.%s", time.Now().Format("20060102-150405"), format)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"autobot/internal/bundle"
+	"autobot/internal/logging"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 任务导入导出API
+
+// ExportTasks 导出任务包
+// 参数：format=yaml|json（默认 yaml），task_ids=1,2,3（默认全部任务）
+func ExportTasks(c *gin.Context) {
+	format := c.DefaultQuery("format", "yaml")
+	if format != "yaml" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的格式，仅支持 yaml 或 json"})
+		return
+	}
+
+	var taskIDs []uint
+	if ids := c.Query("task_ids"); ids != "" {
+		for _, id := range strings.Split(ids, ",") {
+			taskID, err := strconv.ParseUint(strings.TrimSpace(id), 10, 32)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "无效的任务ID: " + id})
+				return
+			}
+			taskIDs = append(taskIDs, uint(taskID))
+		}
+	}
+
+	// 设备加密密钥默认不导出，需显式指定 include_secrets=true
+	exported, err := bundle.Export(bundle.ExportOptions{
+		TaskIDs:        taskIDs,
+		IncludeSecrets: c.Query("include_secrets") == "true",
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "导出任务失败: " + err.Error()})
+		return
+	}
+
+	data, err := exported.Marshal(format)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "序列化导出数据失败"})
+		return
+	}
+
+	contentType := "application/json; charset=utf-8"
+	if format == "yaml" {
+		contentType = "application/yaml; charset=utf-8"
+	}
+	filename := fmt.Sprintf("autobot-tasks-%s.%s", time.Now().Format("20060102-150405"), format)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// ImportTasks 导入任务包
+// 参数：dry_run=true 只报告冲突；on_conflict=skip|overwrite|rename（默认 skip）
+// 请求体为 YAML 或 JSON 格式的任务包，可用 format 参数或 Content-Type 指定格式
+func ImportTasks(c *gin.Context) {
+	dryRun, _ := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	onConflict := c.DefaultQuery("on_conflict", bundle.OnConflictSkip)
+
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil || len(data) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求体为空"})
+		return
+	}
+
+	format := c.Query("format")
+	if format == "" {
+		format = bundle.DetectFormat(c.ContentType(), data)
+	}
+
+	parsed, err := bundle.Parse(data, format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "解析任务包失败: " + err.Error()})
+		return
+	}
+
+	report, err := bundle.Import(parsed, bundle.ImportOptions{
+		DryRun:     dryRun,
+		OnConflict: onConflict,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "导入失败: " + err.Error()})
+		return
+	}
+
+	// 有错误时整体不导入，返回报告说明原因
+	if report.HasErrors() {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":  "任务包中有无法导入的内容，未做任何修改",
+			"report": report,
+		})
+		return
+	}
+
+	// 同步调度器
+	if !dryRun && globalScheduler != nil {
+		for i := range report.ChangedTasks {
+			task := report.ChangedTasks[i]
+			if err := globalScheduler.UpdateTask(&task); err != nil {
+				logging.FromContext(c.Request.Context()).Error("Failed to schedule imported task", "task_id", task.ID, "error", err)
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"autobot/internal/channel"
+	"autobot/internal/database"
+	"autobot/internal/models"
+	"autobot/internal/notifier"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 通知渠道管理API
+
+// GetChannelTypes 获取支持的通知渠道类型
+func GetChannelTypes(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"types": channel.Types()})
+}
+
+// CreateChannel 创建通知渠道
+func CreateChannel(c *gin.Context) {
+	var req models.CreateChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := channel.Validate(req.Type, req.Config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "渠道配置无效: " + err.Error()})
+		return
+	}
+
+	ch := models.NotificationChannel{
+		Name:        req.Name,
+		Type:        req.Type,
+		Config:      req.Config,
+		Description: req.Description,
+		Status:      "active",
+	}
+
+	err := database.WithRetry(func(db *gorm.DB) error {
+		return db.Create(&ch).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建渠道失败"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, redactChannel(ch))
+}
+
+// GetChannels 获取通知渠道列表
+func GetChannels(c *gin.Context) {
+	var channels []models.NotificationChannel
+
+	query := database.GetDB().Model(&models.NotificationChannel{})
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if channelType := c.Query("type"); channelType != "" {
+		query = query.Where("type = ?", channelType)
+	}
+
+	if err := query.Order("created_at desc").Find(&channels).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取渠道列表失败"})
+		return
+	}
+
+	for i := range channels {
+		channels[i] = redactChannel(channels[i])
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"channels": channels,
+		"total":    len(channels),
+	})
+}
+
+// GetChannelsForSelection 获取用于任务选择的通知渠道列表（不含配置）
+func GetChannelsForSelection(c *gin.Context) {
+	var channels []models.NotificationChannel
+
+	if err := database.GetDB().
+		Select("id, name, type, description").
+		Where("status = ?", "active").
+		Order("name asc").
+		Find(&channels).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取渠道列表失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"channels": channels,
+	})
+}
+
+// GetChannel 获取单个通知渠道
+func GetChannel(c *gin.Context) {
+	ch, ok := findChannel(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, redactChannel(*ch))
+}
+
+// UpdateChannel 更新通知渠道
+func UpdateChannel(c *gin.Context) {
+	ch, ok := findChannel(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Config != "" {
+		// 接口返回的配置中密钥已被替换，未修改的密钥沿用已保存的值
+		config := channel.MergeSecrets(ch.Type, ch.Config, req.Config)
+		if err := channel.Validate(ch.Type, config); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "渠道配置无效: " + err.Error()})
+			return
+		}
+		ch.Config = config
+	}
+	if req.Name != "" {
+		ch.Name = req.Name
+	}
+	if req.Description != "" {
+		ch.Description = req.Description
+	}
+	if req.Status != "" {
+		if req.Status != "active" && req.Status != "inactive" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的状态，只能是 active 或 inactive"})
+			return
+		}
+		ch.Status = req.Status
+	}
+
+	err := database.WithRetry(func(db *gorm.DB) error {
+		return db.Save(ch).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新渠道失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, redactChannel(*ch))
+}
+
+// DeleteChannel 删除通知渠道
+func DeleteChannel(c *gin.Context) {
+	ch, ok := findChannel(c)
+	if !ok {
+		return
+	}
+
+	err := database.WithRetry(func(db *gorm.DB) error {
+		return db.Delete(&models.NotificationChannel{}, ch.ID).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除渠道失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "渠道删除成功",
+		"channel_name": ch.Name,
+	})
+}
+
+// TestChannel 通过渠道发送一条测试通知
+func TestChannel(c *gin.Context) {
+	ch, ok := findChannel(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	msg := channel.Message{
+		Title: "autobot 测试通知",
+		Body:  "渠道 " + ch.Name + " 配置正确，发送时间 " + time.Now().Format("2006-01-02 15:04:05"),
+		Level: "active",
+	}
+	if err := notifier.SendToChannel(ctx, ch, msg); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "测试通知发送失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "测试通知已发送"})
+}
+
+// redactChannel 返回密钥已替换为占位符的渠道，用于接口响应
+func redactChannel(ch models.NotificationChannel) models.NotificationChannel {
+	ch.Config, ch.HasSecret = channel.Redact(ch.Type, ch.Config)
+	return ch
+}
+
+// findChannel 按路径参数 id 查找渠道，失败时已写入响应
+func findChannel(c *gin.Context) (*models.NotificationChannel, bool) {
+	channelID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的渠道ID"})
+		return nil, false
+	}
+
+	var ch models.NotificationChannel
+	if err := database.GetDB().First(&ch, channelID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "渠道不存在"})
+		return nil, false
+	}
+	return &ch, true
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"autobot/internal/gitsync"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 全局 Git 同步器，未配置仓库时为 nil
+var globalGitSyncer *gitsync.Syncer
+
+// SetGitSyncer 设置全局 Git 同步器
+func SetGitSyncer(s *gitsync.Syncer) {
+	globalGitSyncer = s
+}
+
+// Git 同步API
+
+// GetGitSyncStatus 获取同步配置、最近一次同步结果和当前漂移
+func GetGitSyncStatus(c *gin.Context) {
+	if globalGitSyncer == nil {
+		c.JSON(http.StatusOK, gin.H{"enabled": false})
+		return
+	}
+
+	drift, err := globalGitSyncer.Drift()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "检查仓库差异失败: " + err.Error()})
+		return
+	}
+
+	opts := globalGitSyncer.Options()
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":   true,
+		"repo":      opts.Repo,
+		"dir":       opts.Dir,
+		"interval":  opts.Interval.String(),
+		"pull":      opts.Pull,
+		"prune":     opts.Prune,
+		"last_sync": globalGitSyncer.LastResult(),
+		"drifted":   drift.Drifted(),
+		"drift":     drift,
+	})
+}
+
+// TriggerGitSync 立即同步
+func TriggerGitSync(c *gin.Context) {
+	if globalGitSyncer == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "未启用 Git 同步"})
+		return
+	}
+
+	result, err := globalGitSyncer.Sync()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":  "同步失败: " + err.Error(),
+			"result": result,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
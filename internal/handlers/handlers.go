@@ -1,13 +1,20 @@
 package handlers
 
 import (
+	"autobot/internal/barkcrypto"
 	"autobot/internal/barkhistory"
 	"autobot/internal/database"
+	"autobot/internal/digest"
 	"autobot/internal/executor"
 	"autobot/internal/logmanager"
 	"autobot/internal/models"
+	"autobot/internal/msgtemplate"
+	"autobot/internal/rules"
 	"autobot/internal/scheduler"
+	"autobot/internal/throttle"
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
 
@@ -66,6 +73,12 @@ func EditTaskHandler(c *gin.Context) {
 		return
 	}
 
+	// 由 Git 仓库管理的任务只读，转到详情页
+	if task.IsReadOnly() {
+		c.Redirect(http.StatusFound, "/tasks/"+id)
+		return
+	}
+
 	c.HTML(http.StatusOK, "task_form.html", gin.H{
 		"title": "编辑任务",
 		"task":  task,
@@ -244,6 +257,12 @@ func UpdateTask(c *gin.Context) {
 		return
 	}
 
+	// 由 Git 仓库管理的任务只读
+	if task.IsReadOnly() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "该任务由 Git 仓库管理，请在仓库中修改"})
+		return
+	}
+
 	// 验证 cron 表达式（如果有更新）
 	if req.CronExpr != "" {
 		parser := cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
@@ -311,6 +330,12 @@ func DeleteTask(c *gin.Context) {
 		return
 	}
 
+	// 由 Git 仓库管理的任务只读
+	if task.IsReadOnly() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "该任务由 Git 仓库管理，请在仓库中修改"})
+		return
+	}
+
 	// 从调度器中移除任务
 	if globalScheduler != nil {
 		globalScheduler.RemoveTask(uint(taskID))
@@ -425,9 +450,16 @@ func RunTaskNow(c *gin.Context) {
 		return
 	}
 
-	// 异步执行任务
+	// 停机中不再接受新的执行
+	if !executor.Accepting() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "服务正在停止，暂不接受新的执行"})
+		return
+	}
+
+	// 异步执行任务：不随请求结束而取消，但保留请求的 logger（request_id）
+	ctx := context.WithoutCancel(c.Request.Context())
 	go func() {
-		executor.ExecuteTask(&task)
+		executor.ExecuteTask(ctx, &task)
 	}()
 
 	c.JSON(http.StatusOK, gin.H{"message": "任务已开始执行"})
@@ -497,20 +529,15 @@ func DeleteTaskLogs(c *gin.Context) {
 	})
 }
 
-// GetBarkRecords 获取Bark发送记录
+// GetBarkRecords 获取Bark发送记录，支持按任务、设备、状态、时间范围筛选和搜索
 func GetBarkRecords(c *gin.Context) {
-	taskIDStr := c.Query("task_id")
 	pageStr := c.DefaultQuery("page", "1")
 	pageSizeStr := c.DefaultQuery("page_size", "20")
 
-	var taskID uint64 = 0
-	if taskIDStr != "" {
-		var err error
-		taskID, err = strconv.ParseUint(taskIDStr, 10, 32)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的任务ID"})
-			return
-		}
+	filter, msg := parseRecordFilter(c)
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
 	}
 
 	page, err := strconv.Atoi(pageStr)
@@ -524,7 +551,7 @@ func GetBarkRecords(c *gin.Context) {
 	}
 
 	historyManager := barkhistory.NewBarkHistoryManager()
-	records, total, err := historyManager.GetBarkRecords(uint(taskID), page, pageSize)
+	records, total, err := historyManager.GetBarkRecords(filter, page, pageSize)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取Bark记录失败"})
 		return
@@ -538,10 +565,21 @@ func GetBarkRecords(c *gin.Context) {
 	})
 }
 
-// GetBarkStats 获取Bark发送统计信息
+// GetBarkStats 获取Bark发送统计信息，筛选参数与记录列表相同
+// days 为未指定开始时间时按天统计的天数（默认 30，最多 366）
 func GetBarkStats(c *gin.Context) {
+	filter, msg := parseRecordFilter(c)
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days < 1 || days > 366 {
+		days = 30
+	}
+
 	historyManager := barkhistory.NewBarkHistoryManager()
-	stats := historyManager.GetBarkStats()
+	stats := historyManager.GetBarkStats(filter, days)
 	c.JSON(http.StatusOK, stats)
 }
 
@@ -629,6 +667,26 @@ func UpdateBarkConfig(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的 Bark 配置格式"})
 		return
 	}
+	if err := rules.Validate(barkConfig.Rules); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "通知规则无效: " + err.Error()})
+		return
+	}
+	if err := rules.ValidateRoutes(barkConfig.Routes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "设备路由无效: " + err.Error()})
+		return
+	}
+	if err := msgtemplate.ValidateConfig(&barkConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "通知模板无效: " + err.Error()})
+		return
+	}
+	if err := digest.Validate(&barkConfig.Digest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "通知汇总配置无效: " + err.Error()})
+		return
+	}
+	if err := barkhistory.ValidateDeduplication(&barkConfig.Deduplication); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "去重配置无效: " + err.Error()})
+		return
+	}
 
 	// 更新任务
 	var task models.Task
@@ -637,6 +695,12 @@ func UpdateBarkConfig(c *gin.Context) {
 		return
 	}
 
+	// 由 Git 仓库管理的任务只读
+	if task.IsReadOnly() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "该任务由 Git 仓库管理，请在仓库中修改"})
+		return
+	}
+
 	task.BarkConfig = req.BarkConfig
 	// 使用重试机制保存Bark配置
 	err = database.WithRetry(func(db *gorm.DB) error {
@@ -714,6 +778,39 @@ func BarkManagementHandler(c *gin.Context) {
 
 // Bark服务器管理API
 
+// maxBarkServerTimeout 服务器推送超时上限（秒）
+const maxBarkServerTimeout = 300
+
+// validateBarkLimits 检查推送限额和免打扰配置，返回错误提示，通过时为空
+func validateBarkLimits(perHour, perDay int, quietStart, quietEnd, quietMode string) string {
+	if perHour < 0 || perDay < 0 {
+		return "推送限额不能为负数"
+	}
+	if err := throttle.ValidateQuietHours(quietStart, quietEnd); err != nil {
+		return "免打扰时段无效: " + err.Error()
+	}
+	if quietMode != "" && quietMode != models.QuietPassive && quietMode != models.QuietDelay {
+		return "免打扰模式需为 passive 或 delay"
+	}
+	return ""
+}
+
+// validateFallbackServer 检查备用服务器存在且不是服务器自身，返回错误提示，通过时为空
+func validateFallbackServer(serverID, fallbackID uint) string {
+	if fallbackID == 0 {
+		return ""
+	}
+	if fallbackID == serverID {
+		return "备用服务器不能是服务器自身"
+	}
+	var count int64
+	database.GetDB().Model(&models.BarkServer{}).Where("id = ?", fallbackID).Count(&count)
+	if count == 0 {
+		return "备用服务器不存在"
+	}
+	return ""
+}
+
 // CreateBarkServer 创建Bark服务器
 func CreateBarkServer(c *gin.Context) {
 	var req models.CreateBarkServerRequest
@@ -721,6 +818,18 @@ func CreateBarkServer(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if req.Timeout < 0 || req.Timeout > maxBarkServerTimeout {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("超时时间需在 0-%d 秒之间", maxBarkServerTimeout)})
+		return
+	}
+	if msg := validateBarkLimits(req.RateLimitHour, req.RateLimitDay, "", "", ""); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	if msg := validateFallbackServer(0, req.FallbackServerID); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
 
 	// 如果设置为默认服务器，先取消其他默认服务器
 	if req.IsDefault {
@@ -732,7 +841,14 @@ func CreateBarkServer(c *gin.Context) {
 		URL:         req.URL,
 		Description: req.Description,
 		IsDefault:   req.IsDefault,
+		Timeout:     req.Timeout,
 		Status:      "active",
+
+		RateLimitHour: req.RateLimitHour,
+		RateLimitDay:  req.RateLimitDay,
+
+		FallbackServerID: req.FallbackServerID,
+		Health:           models.ServerHealthUnknown,
 	}
 
 	// 使用重试机制创建服务器
@@ -813,6 +929,10 @@ func UpdateBarkServer(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if req.Timeout != nil && (*req.Timeout < 0 || *req.Timeout > maxBarkServerTimeout) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("超时时间需在 0-%d 秒之间", maxBarkServerTimeout)})
+		return
+	}
 
 	var server models.BarkServer
 	if err := database.GetDB().First(&server, serverID).Error; err != nil {
@@ -829,8 +949,16 @@ func UpdateBarkServer(c *gin.Context) {
 	if req.Name != "" {
 		server.Name = req.Name
 	}
-	if req.URL != "" {
+	// 健康检查结果由检查器单独更新，地址变化时才重置为未检查
+	omitHealth := []string{"Health", "HealthLatency", "HealthError", "HealthFailures", "HealthCheckedAt"}
+	if req.URL != "" && req.URL != server.URL {
 		server.URL = req.URL
+		server.Health = models.ServerHealthUnknown
+		server.HealthLatency = 0
+		server.HealthError = ""
+		server.HealthFailures = 0
+		server.HealthCheckedAt = nil
+		omitHealth = nil
 	}
 	if req.Description != "" {
 		server.Description = req.Description
@@ -838,11 +966,31 @@ func UpdateBarkServer(c *gin.Context) {
 	if req.Status != "" {
 		server.Status = req.Status
 	}
+	if req.Timeout != nil {
+		server.Timeout = *req.Timeout
+	}
+	if req.RateLimitHour != nil {
+		server.RateLimitHour = *req.RateLimitHour
+	}
+	if req.RateLimitDay != nil {
+		server.RateLimitDay = *req.RateLimitDay
+	}
+	if req.FallbackServerID != nil {
+		server.FallbackServerID = *req.FallbackServerID
+	}
 	server.IsDefault = req.IsDefault
+	if msg := validateBarkLimits(server.RateLimitHour, server.RateLimitDay, "", "", ""); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	if msg := validateFallbackServer(server.ID, server.FallbackServerID); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
 
 	// 使用重试机制保存服务器
 	err = database.WithRetry(func(db *gorm.DB) error {
-		return db.Save(&server).Error
+		return db.Omit(omitHealth...).Save(&server).Error
 	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新服务器失败"})
@@ -877,9 +1025,14 @@ func DeleteBarkServer(c *gin.Context) {
 		return
 	}
 
-	// 删除服务器 - 使用重试机制
+	// 删除服务器并取消以它为备用服务器的设置 - 使用重试机制
 	err = database.WithRetry(func(db *gorm.DB) error {
-		return db.Delete(&models.BarkServer{}, serverID).Error
+		return db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&models.BarkServer{}).Where("fallback_server_id = ?", serverID).Update("fallback_server_id", 0).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&models.BarkServer{}, serverID).Error
+		})
 	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除服务器失败"})
@@ -902,6 +1055,15 @@ func CreateBarkDevice(c *gin.Context) {
 		return
 	}
 
+	if err := barkcrypto.Validate(req.EncryptionAlgorithm, req.EncryptionMode, req.EncryptionKey, req.EncryptionIV); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "加密配置无效: " + err.Error()})
+		return
+	}
+	if msg := validateBarkLimits(req.RateLimitHour, req.RateLimitDay, req.QuietStart, req.QuietEnd, req.QuietMode); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
 	// 检查设备密钥是否已存在
 	var existingDevice models.BarkDevice
 	if err := database.GetDB().Where("device_key = ?", req.DeviceKey).First(&existingDevice).Error; err == nil {
@@ -921,7 +1083,19 @@ func CreateBarkDevice(c *gin.Context) {
 		ServerID:    req.ServerID,
 		IsDefault:   req.IsDefault,
 		Status:      "active",
+
+		EncryptionAlgorithm: req.EncryptionAlgorithm,
+		EncryptionMode:      req.EncryptionMode,
+		EncryptionKey:       req.EncryptionKey,
+		EncryptionIV:        req.EncryptionIV,
+
+		RateLimitHour: req.RateLimitHour,
+		RateLimitDay:  req.RateLimitDay,
+		QuietStart:    req.QuietStart,
+		QuietEnd:      req.QuietEnd,
+		QuietMode:     req.QuietMode,
 	}
+	device.NormalizeQuietHours()
 
 	// 使用重试机制创建设备
 	err := database.WithRetry(func(db *gorm.DB) error {
@@ -1048,6 +1222,49 @@ func UpdateBarkDevice(c *gin.Context) {
 	}
 	device.IsDefault = req.IsDefault
 
+	// 加密配置，未提供的字段保持不变
+	if req.EncryptionAlgorithm != nil {
+		device.EncryptionAlgorithm = *req.EncryptionAlgorithm
+	}
+	if req.EncryptionMode != nil {
+		device.EncryptionMode = *req.EncryptionMode
+	}
+	if req.EncryptionKey != nil {
+		device.EncryptionKey = *req.EncryptionKey
+	}
+	if req.EncryptionIV != nil {
+		device.EncryptionIV = *req.EncryptionIV
+	}
+	if !device.Encrypted() {
+		device.EncryptionMode, device.EncryptionKey, device.EncryptionIV = "", "", ""
+	}
+	if err := barkcrypto.Validate(device.EncryptionAlgorithm, device.EncryptionMode, device.EncryptionKey, device.EncryptionIV); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "加密配置无效: " + err.Error()})
+		return
+	}
+
+	// 限额和免打扰，未提供的字段保持不变
+	if req.RateLimitHour != nil {
+		device.RateLimitHour = *req.RateLimitHour
+	}
+	if req.RateLimitDay != nil {
+		device.RateLimitDay = *req.RateLimitDay
+	}
+	if req.QuietStart != nil {
+		device.QuietStart = *req.QuietStart
+	}
+	if req.QuietEnd != nil {
+		device.QuietEnd = *req.QuietEnd
+	}
+	if req.QuietMode != nil {
+		device.QuietMode = *req.QuietMode
+	}
+	if msg := validateBarkLimits(device.RateLimitHour, device.RateLimitDay, device.QuietStart, device.QuietEnd, device.QuietMode); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	device.NormalizeQuietHours()
+
 	// 使用重试机制保存设备
 	err = database.WithRetry(func(db *gorm.DB) error {
 		return db.Save(&device).Error
@@ -1079,9 +1296,14 @@ func DeleteBarkDevice(c *gin.Context) {
 		return
 	}
 
-	// 删除设备 - 使用重试机制
+	// 删除设备并移出所在分组 - 使用重试机制
 	err = database.WithRetry(func(db *gorm.DB) error {
-		return db.Delete(&models.BarkDevice{}, deviceID).Error
+		return db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec("DELETE FROM bark_device_group_members WHERE bark_device_id = ?", deviceID).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&models.BarkDevice{}, deviceID).Error
+		})
 	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除设备失败"})
@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"autobot/internal/metrics"
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MetricsHandler Prometheus 指标，token 不为空时要求 Bearer 鉴权
+func MetricsHandler(token string) gin.HandlerFunc {
+	handler := metrics.Handler()
+	return func(c *gin.Context) {
+		if token != "" {
+			expected := "Bearer " + token
+			if subtle.ConstantTimeCompare([]byte(c.GetHeader("Authorization")), []byte(expected)) != 1 {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的指标令牌"})
+				return
+			}
+		}
+		handler.ServeHTTP(c.Writer, c.Request)
+	}
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"autobot/internal/database"
+	"autobot/internal/executor"
+	"autobot/internal/models"
+	"autobot/internal/scheduler"
+	"autobot/internal/version"
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 健康检查相关API
+
+// readinessTimeout 就绪检查中数据库 ping 的超时时间
+const readinessTimeout = 2 * time.Second
+
+// Healthz 存活检查，进程能响应即返回 200
+func Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readyz 就绪检查：数据库可访问、调度器正常、Python 可用、未在停机
+// 任一检查失败返回 503，checks 中给出每项结果
+func Readyz(c *gin.Context) {
+	checks := gin.H{}
+	ready := true
+	check := func(name string, err error) {
+		if err != nil {
+			checks[name] = err.Error()
+			ready = false
+			return
+		}
+		checks[name] = "ok"
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), readinessTimeout)
+	defer cancel()
+	check("database", database.Ping(ctx))
+	check("scheduler", schedulerReady())
+	check("python", executor.CheckPython())
+	if !executor.Accepting() {
+		check("executor", errShuttingDown)
+	} else {
+		check("executor", nil)
+	}
+
+	status := http.StatusOK
+	response := gin.H{"status": "ok", "checks": checks}
+	if !ready {
+		status = http.StatusServiceUnavailable
+		response["status"] = "unavailable"
+	}
+	c.JSON(status, response)
+}
+
+// 就绪检查失败原因
+var (
+	errSchedulerMissing = errors.New("scheduler not initialized")
+	errSchedulerStopped = errors.New("scheduler is not running on the leader")
+	errShuttingDown     = errors.New("shutting down")
+)
+
+// schedulerReady 调度器只在 Leader 上运行，备用实例不运行调度器也视为正常
+func schedulerReady() error {
+	if globalScheduler == nil {
+		return errSchedulerMissing
+	}
+	if globalElector != nil && globalElector.IsLeader() && !globalScheduler.IsRunning() {
+		return errSchedulerStopped
+	}
+	return nil
+}
+
+// GetStatus 获取实例运行状态：版本、运行时长、调度条目、正在运行的执行和最近一次错误
+func GetStatus(c *gin.Context) {
+	if globalScheduler == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "调度器未初始化"})
+		return
+	}
+
+	entries := globalScheduler.ScheduledEntries()
+	names, err := taskNames(entries)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取任务信息失败"})
+		return
+	}
+
+	scheduled := make([]gin.H, 0, len(entries))
+	for _, entry := range entries {
+		scheduled = append(scheduled, gin.H{
+			"task_id":   entry.TaskID,
+			"task_name": names[entry.TaskID],
+			"cron_expr": entry.CronExpr,
+			"next_run":  entry.Next,
+			"prev_run":  nullableTime(entry.Prev),
+		})
+	}
+
+	response := gin.H{
+		"version":    version.Version,
+		"revision":   version.Revision(),
+		"started_at": version.StartedAt(),
+		"uptime":     version.Uptime().Round(time.Second).String(),
+		"scheduler": gin.H{
+			"running": globalScheduler.IsRunning(),
+			"paused":  globalScheduler.IsPaused(),
+			"entries": scheduled,
+		},
+		"running_executions": executor.RunningExecutions(),
+		"last_error":         executor.LastError(),
+	}
+	if globalElector != nil {
+		response["instance_id"] = globalElector.InstanceID()
+		response["is_leader"] = globalElector.IsLeader()
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// taskNames 查询调度条目对应的任务名称
+func taskNames(entries []scheduler.ScheduledEntry) (map[uint]string, error) {
+	names := make(map[uint]string, len(entries))
+	if len(entries) == 0 {
+		return names, nil
+	}
+
+	ids := make([]uint, 0, len(entries))
+	for _, entry := range entries {
+		ids = append(ids, entry.TaskID)
+	}
+
+	var tasks []models.Task
+	err := database.WithRetry(func(db *gorm.DB) error {
+		return db.Select("id", "name").Where("id IN ?", ids).Find(&tasks).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, task := range tasks {
+		names[task.ID] = task.Name
+	}
+	return names, nil
+}
+
+// nullableTime 零值时间返回 nil
+func nullableTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
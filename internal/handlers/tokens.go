@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"autobot/internal/database"
+	"autobot/internal/middleware"
+	"autobot/internal/models"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// API 令牌管理
+
+// CreateAPIToken 为当前用户创建 API 令牌，明文只在响应中返回一次
+func CreateAPIToken(c *gin.Context) {
+	userID, ok := middleware.GetCurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	var req models.CreateAPITokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresIn != "" {
+		duration, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil || duration <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的有效期，例如 720h"})
+			return
+		}
+		t := time.Now().Add(duration)
+		expiresAt = &t
+	}
+
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成令牌失败"})
+		return
+	}
+	plain := middleware.APITokenPrefix + hex.EncodeToString(secret)
+
+	token := models.APIToken{
+		UserID:    userID,
+		Name:      req.Name,
+		TokenHash: middleware.HashAPIToken(plain),
+		Prefix:    plain[:len(middleware.APITokenPrefix)+6],
+		ExpiresAt: expiresAt,
+	}
+	err := database.WithRetry(func(db *gorm.DB) error {
+		return db.Create(&token).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建令牌失败"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"token":     plain,
+		"api_token": token,
+	})
+}
+
+// GetAPITokens 获取当前用户的 API 令牌列表
+func GetAPITokens(c *gin.Context) {
+	userID, ok := middleware.GetCurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	var tokens []models.APIToken
+	err := database.WithRetry(func(db *gorm.DB) error {
+		return db.Where("user_id = ?", userID).Order("created_at desc").Find(&tokens).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取令牌列表失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tokens": tokens})
+}
+
+// DeleteAPIToken 吊销当前用户的 API 令牌
+func DeleteAPIToken(c *gin.Context) {
+	userID, ok := middleware.GetCurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	tokenID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的令牌ID"})
+		return
+	}
+
+	var deleted int64
+	err = database.WithRetry(func(db *gorm.DB) error {
+		result := db.Where("id = ? AND user_id = ?", tokenID, userID).Delete(&models.APIToken{})
+		deleted = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "吊销令牌失败"})
+		return
+	}
+	if deleted == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "令牌不存在"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "令牌已吊销"})
+}
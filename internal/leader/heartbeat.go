@@ -0,0 +1,100 @@
+package leader
+
+import (
+	"autobot/internal/database"
+	"autobot/internal/models"
+	"log/slog"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LiveSince 心跳晚于该时间的实例视为存活
+func LiveSince() time.Time {
+	return time.Now().Add(-DefaultLeaseDuration)
+}
+
+// Heartbeat 定期更新本实例的心跳，所有实例（包括非 Leader）都会运行
+// 与选举分开启停：停机时先放弃 Leader，等执行全部结束后再停止心跳，
+// 避免其他实例把仍在收尾的执行当作遗留日志处理
+type Heartbeat struct {
+	instanceID string
+	startedAt  time.Time
+	interval   time.Duration
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewHeartbeat 创建心跳
+func NewHeartbeat(instanceID string) *Heartbeat {
+	return &Heartbeat{
+		instanceID: instanceID,
+		startedAt:  time.Now(),
+		interval:   DefaultRenewInterval,
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+// Start 立即写入一次心跳并启动定时更新
+func (h *Heartbeat) Start() {
+	h.beat()
+
+	go func() {
+		defer close(h.done)
+		ticker := time.NewTicker(h.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				h.beat()
+			case <-h.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止心跳并删除本实例的记录
+func (h *Heartbeat) Stop() {
+	h.stopOnce.Do(func() {
+		close(h.stop)
+		<-h.done
+
+		err := database.WithRetry(func(db *gorm.DB) error {
+			return db.Where("instance_id = ?", h.instanceID).Delete(&models.InstanceHeartbeat{}).Error
+		})
+		if err != nil {
+			slog.Warn("Failed to remove instance heartbeat", "error", err)
+		}
+	})
+}
+
+// beat 更新本实例的心跳
+func (h *Heartbeat) beat() {
+	err := database.WithRetry(func(db *gorm.DB) error {
+		return db.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "instance_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"last_seen"}),
+		}).Create(&models.InstanceHeartbeat{
+			InstanceID: h.instanceID,
+			StartedAt:  h.startedAt,
+			LastSeen:   time.Now(),
+		}).Error
+	})
+	if err != nil {
+		slog.Error("Failed to update instance heartbeat", "error", err)
+	}
+}
+
+// PruneHeartbeats 删除已失效实例的心跳记录，由 Leader 在处理完其遗留日志后调用
+func PruneHeartbeats() error {
+	return database.WithRetry(func(db *gorm.DB) error {
+		return db.Where("last_seen < ?", LiveSince()).Delete(&models.InstanceHeartbeat{}).Error
+	})
+}
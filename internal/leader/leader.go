@@ -0,0 +1,217 @@
+package leader
+
+import (
+	"autobot/internal/database"
+	"autobot/internal/models"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// LeaseName 调度器租约名称
+	LeaseName = "scheduler"
+	// DefaultLeaseDuration 租约有效期，Leader 宕机后最长经过该时间完成故障转移
+	DefaultLeaseDuration = 30 * time.Second
+	// DefaultRenewInterval 续约/抢占间隔
+	DefaultRenewInterval = 10 * time.Second
+)
+
+// DefaultInstanceID 未配置实例ID时使用主机名加进程号和随机后缀
+// 主机名相同（或无法获取主机名）的多个实例也不会共用一个ID而同时认为自己持有租约
+func DefaultInstanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "autobot"
+	}
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return fmt.Sprintf("%s-%d-%d", hostname, os.Getpid(), time.Now().UnixNano())
+	}
+	return fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), hex.EncodeToString(suffix))
+}
+
+// Elector 基于数据库租约的 Leader 选举
+// 所有实例都提供 Web 和 API 服务，只有 Leader 运行调度器
+type Elector struct {
+	instanceID    string
+	leaseDuration time.Duration
+	renewInterval time.Duration
+
+	onElected    func() // 成为 Leader 时调用
+	onRevoked    func() // 失去 Leader 身份时调用
+	onLeaderTick func() // 作为 Leader 每次续约成功后调用
+
+	isLeader  atomic.Bool
+	lastRenew time.Time // 最近一次成功续约时间，仅在选举循环中访问
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewElector 创建选举器
+func NewElector(instanceID string, onElected func(), onRevoked func()) *Elector {
+	return &Elector{
+		instanceID:    instanceID,
+		leaseDuration: DefaultLeaseDuration,
+		renewInterval: DefaultRenewInterval,
+		onElected:     onElected,
+		onRevoked:     onRevoked,
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+}
+
+// SetLeaderTick 设置作为 Leader 时每次续约成功后执行的回调，需在 Start 之前调用
+func (e *Elector) SetLeaderTick(fn func()) {
+	e.onLeaderTick = fn
+}
+
+// Start 启动选举循环，立即尝试获取一次租约
+func (e *Elector) Start() {
+	e.tick()
+
+	go func() {
+		defer close(e.done)
+		ticker := time.NewTicker(e.renewInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				e.tick()
+			case <-e.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止选举循环，如果是 Leader 则停止调度并主动释放租约，便于其他实例尽快接管
+func (e *Elector) Stop() {
+	e.stopOnce.Do(func() {
+		close(e.stop)
+		<-e.done
+
+		if e.isLeader.Load() {
+			e.revoke()
+			if err := e.release(); err != nil {
+				slog.Warn("Failed to release scheduler lease", "error", err)
+			}
+		}
+	})
+}
+
+// IsLeader 当前实例是否为 Leader
+func (e *Elector) IsLeader() bool {
+	return e.isLeader.Load()
+}
+
+// InstanceID 当前实例ID
+func (e *Elector) InstanceID() string {
+	return e.instanceID
+}
+
+// CurrentLease 获取当前租约信息，没有任何实例持有过租约时返回 nil
+func (e *Elector) CurrentLease() (*models.SchedulerLease, error) {
+	var lease models.SchedulerLease
+	err := database.WithRetry(func(db *gorm.DB) error {
+		return db.Where("name = ?", LeaseName).First(&lease).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &lease, nil
+}
+
+// tick 尝试获取或续约租约，并根据结果切换角色
+func (e *Elector) tick() {
+	acquired, err := e.tryAcquire()
+	if err != nil {
+		slog.Error("Failed to acquire scheduler lease", "error", err)
+		// 无法确认租约时，在租约可能过期之前主动放弃调度，避免双主
+		if e.isLeader.Load() && time.Since(e.lastRenew) >= e.leaseDuration-e.renewInterval {
+			slog.Warn("Scheduler lease could not be renewed in time, stepping down")
+			e.revoke()
+		}
+		return
+	}
+
+	if acquired {
+		e.lastRenew = time.Now()
+		if !e.isLeader.Load() {
+			slog.Info("Became scheduler leader", "instance", e.instanceID)
+			e.isLeader.Store(true)
+			if e.onElected != nil {
+				e.onElected()
+			}
+		}
+		if e.onLeaderTick != nil {
+			e.onLeaderTick()
+		}
+		return
+	}
+
+	if e.isLeader.Load() {
+		slog.Warn("Lost scheduler lease", "instance", e.instanceID)
+		e.revoke()
+	}
+}
+
+// revoke 放弃 Leader 身份
+func (e *Elector) revoke() {
+	e.isLeader.Store(false)
+	if e.onRevoked != nil {
+		e.onRevoked()
+	}
+}
+
+// tryAcquire 续约自己持有的租约，或抢占已过期的租约
+func (e *Elector) tryAcquire() (bool, error) {
+	now := time.Now()
+	expiresAt := now.Add(e.leaseDuration)
+
+	var acquired bool
+	err := database.WithRetry(func(db *gorm.DB) error {
+		// 首次运行时创建租约记录（已存在则忽略）
+		if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.SchedulerLease{
+			Name:      LeaseName,
+			HolderID:  e.instanceID,
+			ExpiresAt: expiresAt,
+		}).Error; err != nil {
+			return err
+		}
+
+		result := db.Model(&models.SchedulerLease{}).
+			Where("name = ? AND (holder_id = ? OR expires_at < ?)", LeaseName, e.instanceID, now).
+			Updates(map[string]interface{}{
+				"holder_id":  e.instanceID,
+				"expires_at": expiresAt,
+			})
+		acquired = result.RowsAffected > 0
+		return result.Error
+	})
+	return acquired, err
+}
+
+// release 释放租约
+func (e *Elector) release() error {
+	return database.WithRetry(func(db *gorm.DB) error {
+		return db.Model(&models.SchedulerLease{}).
+			Where("name = ? AND holder_id = ?", LeaseName, e.instanceID).
+			Update("expires_at", time.Now()).Error
+	})
+}
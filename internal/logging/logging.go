@@ -0,0 +1,73 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// 日志格式
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Setup 按级别和格式设置默认 logger
+// 标准库 log 的输出也会转到新的 handler（级别为 info）
+func Setup(w io.Writer, levelName, format string) error {
+	lvl, err := ParseLevel(levelName)
+	if err != nil {
+		return err
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case "", FormatText:
+		handler = slog.NewTextHandler(w, opts)
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, opts)
+	default:
+		return fmt.Errorf("unsupported log format: %s (use text or json)", format)
+	}
+
+	slog.SetDefault(slog.New(handler))
+	log.SetFlags(0)
+	return nil
+}
+
+// ParseLevel 解析日志级别：debug, info, warn, error
+func ParseLevel(name string) (slog.Level, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(name)); err != nil {
+		return 0, fmt.Errorf("unsupported log level: %s (use debug, info, warn or error)", name)
+	}
+	return lvl, nil
+}
+
+// contextKey 在 context 中保存 logger 的键
+type contextKey struct{}
+
+// WithLogger 将 logger 放入 context
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext 取出 context 中的 logger（例如带 request_id 的请求 logger），没有时返回默认 logger
+func FromContext(ctx context.Context) *slog.Logger {
+	if ctx != nil {
+		if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+			return logger
+		}
+	}
+	return slog.Default()
+}
+
+// Fatal 记录错误并退出
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
@@ -3,29 +3,42 @@ package logmanager
 import (
 	"autobot/internal/database"
 	"autobot/internal/models"
-	"log"
+	"log/slog"
+	"time"
 
 	"gorm.io/gorm"
 )
 
 const (
-	// MaxLogsPerTask 每个任务最大日志条数
-	MaxLogsPerTask = 5000
-	// MaxTotalLogs 全局最大日志条数
-	MaxTotalLogs = 50000
+	// DefaultMaxLogsPerTask 每个任务默认最大日志条数
+	DefaultMaxLogsPerTask = 5000
+	// DefaultMaxTotalLogs 全局默认最大日志条数
+	DefaultMaxTotalLogs = 50000
 )
 
 // LogManager 日志管理器
-type LogManager struct{}
+type LogManager struct {
+	maxLogsPerTask int
+	maxTotalLogs   int
+}
 
-// NewLogManager 创建新的日志管理器
-func NewLogManager() *LogManager {
-	return &LogManager{}
+// NewLogManager 创建新的日志管理器，限制值不大于0时使用默认值
+func NewLogManager(maxLogsPerTask int, maxTotalLogs int) *LogManager {
+	if maxLogsPerTask <= 0 {
+		maxLogsPerTask = DefaultMaxLogsPerTask
+	}
+	if maxTotalLogs <= 0 {
+		maxTotalLogs = DefaultMaxTotalLogs
+	}
+	return &LogManager{
+		maxLogsPerTask: maxLogsPerTask,
+		maxTotalLogs:   maxTotalLogs,
+	}
 }
 
 // CleanupLogsAfterExecution 在任务执行后清理日志
-// 1. 首先检查单个任务是否超过5000条，如果超过则删除最旧的记录
-// 2. 然后检查全局是否超过50000条，如果超过则删除全局最旧的记录
+// 1. 首先检查单个任务是否超过每任务上限，如果超过则删除最旧的记录
+// 2. 然后检查全局是否超过全局上限，如果超过则删除全局最旧的记录
 func (lm *LogManager) CleanupLogsAfterExecution(taskID uint) {
 	// 1. 清理单个任务的旧日志
 	lm.cleanupTaskLogs(taskID)
@@ -34,6 +47,58 @@ func (lm *LogManager) CleanupLogsAfterExecution(taskID uint) {
 	lm.cleanupGlobalLogs()
 }
 
+// RecoverInterruptedLogs 将本实例上次退出时遗留的 running 日志标记为 interrupted
+// 应在调度器和心跳启动前调用，此时不可能有本进程发起的执行
+// 未配置实例ID时每次启动的ID都不同，因此同时处理心跳已失效（或没有心跳）的实例遗留的日志，
+// 不影响共享数据库中仍然存活的其他实例
+func (lm *LogManager) RecoverInterruptedLogs(instanceID string, liveSince time.Time) (int64, error) {
+	return lm.markInterrupted(func(db *gorm.DB) *gorm.DB {
+		return db.Where("instance = ? OR instance = '' OR instance IS NULL OR instance NOT IN (?)",
+			instanceID, liveInstances(db, liveSince))
+	})
+}
+
+// RecoverOrphanedLogs 将心跳已失效的其他实例遗留的 running 日志标记为 interrupted
+// 由 Leader 定期调用，处理实例崩溃后没有以同一ID重新启动的情况
+func (lm *LogManager) RecoverOrphanedLogs(instanceID string, liveSince time.Time) (int64, error) {
+	return lm.markInterrupted(func(db *gorm.DB) *gorm.DB {
+		return db.Where("instance <> ? AND instance <> '' AND instance NOT IN (?)",
+			instanceID, liveInstances(db, liveSince))
+	})
+}
+
+// liveInstances 心跳晚于 liveSince 的实例ID子查询
+func liveInstances(db *gorm.DB, liveSince time.Time) *gorm.DB {
+	return db.Session(&gorm.Session{NewDB: true}).Model(&models.InstanceHeartbeat{}).
+		Select("instance_id").Where("last_seen >= ?", liveSince)
+}
+
+// markInterrupted 将符合条件的 running 日志标记为 interrupted
+func (lm *LogManager) markInterrupted(scope func(db *gorm.DB) *gorm.DB) (int64, error) {
+	var rowsAffected int64
+	err := database.WithRetry(func(db *gorm.DB) error {
+		result := db.Model(&models.TaskLog{}).
+			Where("status = ?", "running").
+			Scopes(scope).
+			Updates(map[string]interface{}{
+				"status":   "interrupted",
+				"end_time": time.Now(),
+				"error":    "execution interrupted: autobot stopped before the task finished",
+			})
+		rowsAffected = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		slog.Error("Failed to recover interrupted logs", "error", err)
+		return 0, err
+	}
+
+	if rowsAffected > 0 {
+		slog.Warn("Marked orphaned running logs as interrupted", "count", rowsAffected)
+	}
+	return rowsAffected, nil
+}
+
 // cleanupTaskLogs 清理单个任务的旧日志
 func (lm *LogManager) cleanupTaskLogs(taskID uint) {
 	// 计算当前任务的日志数量
@@ -42,13 +107,13 @@ func (lm *LogManager) cleanupTaskLogs(taskID uint) {
 		return db.Model(&models.TaskLog{}).Where("task_id = ?", taskID).Count(&count).Error
 	})
 	if err != nil {
-		log.Printf("Failed to count logs for task %d: %v", taskID, err)
+		slog.Error("Failed to count task logs", "task_id", taskID, "error", err)
 		return
 	}
 
 	// 如果超过限制，删除最旧的日志
-	if count > MaxLogsPerTask {
-		excessCount := count - MaxLogsPerTask
+	if count > int64(lm.maxLogsPerTask) {
+		excessCount := count - int64(lm.maxLogsPerTask)
 
 		// 获取需要删除的最旧日志的ID
 		var logIDs []uint
@@ -60,7 +125,7 @@ func (lm *LogManager) cleanupTaskLogs(taskID uint) {
 				Pluck("id", &logIDs).Error
 		})
 		if err != nil {
-			log.Printf("Failed to get old log IDs for task %d: %v", taskID, err)
+			slog.Error("Failed to get old log IDs", "task_id", taskID, "error", err)
 			return
 		}
 
@@ -70,10 +135,10 @@ func (lm *LogManager) cleanupTaskLogs(taskID uint) {
 				return db.Where("id IN ?", logIDs).Delete(&models.TaskLog{}).Error
 			})
 			if err != nil {
-				log.Printf("Failed to delete old logs for task %d: %v", taskID, err)
+				slog.Error("Failed to delete old logs", "task_id", taskID, "error", err)
 				return
 			}
-			log.Printf("Cleaned up %d old logs for task %d (kept %d)", len(logIDs), taskID, MaxLogsPerTask)
+			slog.Info("Cleaned up old task logs", "task_id", taskID, "deleted", len(logIDs), "kept", lm.maxLogsPerTask)
 		}
 	}
 }
@@ -86,13 +151,13 @@ func (lm *LogManager) cleanupGlobalLogs() {
 		return db.Model(&models.TaskLog{}).Count(&count).Error
 	})
 	if err != nil {
-		log.Printf("Failed to count global logs: %v", err)
+		slog.Error("Failed to count global logs", "error", err)
 		return
 	}
 
 	// 如果超过全局限制，删除最旧的日志
-	if count > MaxTotalLogs {
-		excessCount := count - MaxTotalLogs
+	if count > int64(lm.maxTotalLogs) {
+		excessCount := count - int64(lm.maxTotalLogs)
 
 		// 获取需要删除的最旧日志的ID
 		var logIDs []uint
@@ -103,7 +168,7 @@ func (lm *LogManager) cleanupGlobalLogs() {
 				Pluck("id", &logIDs).Error
 		})
 		if err != nil {
-			log.Printf("Failed to get old log IDs for global cleanup: %v", err)
+			slog.Error("Failed to get old log IDs for global cleanup", "error", err)
 			return
 		}
 
@@ -113,10 +178,10 @@ func (lm *LogManager) cleanupGlobalLogs() {
 				return db.Where("id IN ?", logIDs).Delete(&models.TaskLog{}).Error
 			})
 			if err != nil {
-				log.Printf("Failed to delete old logs globally: %v", err)
+				slog.Error("Failed to delete old logs globally", "error", err)
 				return
 			}
-			log.Printf("Cleaned up %d old logs globally (kept %d)", len(logIDs), MaxTotalLogs)
+			slog.Info("Cleaned up old logs globally", "deleted", len(logIDs), "kept", lm.maxTotalLogs)
 		}
 	}
 }
@@ -129,13 +194,13 @@ func (lm *LogManager) GetLogStats() map[string]interface{} {
 		return db.Model(&models.TaskLog{}).Count(&totalLogs).Error
 	})
 	if err != nil {
-		log.Printf("Failed to count total logs: %v", err)
+		slog.Error("Failed to count total logs", "error", err)
 	}
 
 	stats := map[string]interface{}{
 		"total_logs":        totalLogs,
-		"max_total_logs":    MaxTotalLogs,
-		"max_logs_per_task": MaxLogsPerTask,
+		"max_total_logs":    lm.maxTotalLogs,
+		"max_logs_per_task": lm.maxLogsPerTask,
 	}
 
 	// 获取最新和最旧日志时间 - 使用重试机制
@@ -0,0 +1,292 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace 指标名前缀
+const namespace = "autobot"
+
+// registry 独立的注册表，只包含本程序的指标和 Go 运行时指标
+var registry = prometheus.NewRegistry()
+
+var (
+	// executionsTotal 任务执行次数
+	executionsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "task_executions_total",
+		Help:      "Task executions by task and final status.",
+	}, []string{"task_id", "status"})
+
+	// executionDuration 任务执行耗时
+	executionDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "task_execution_duration_seconds",
+		Help:      "Task execution duration in seconds.",
+		Buckets:   []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600},
+	}, []string{"task_id"})
+
+	// executionsInFlight 正在运行的执行数（执行队列深度）
+	executionsInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "task_executions_in_flight",
+		Help:      "Task executions currently running.",
+	})
+
+	// notificationsInFlight 正在发送的通知数
+	notificationsInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "notifications_in_flight",
+		Help:      "Notifications currently being sent.",
+	})
+
+	// barkSendsTotal Bark 发送次数
+	barkSendsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "bark_sends_total",
+		Help:      "Bark notifications by status (success, failed, skipped).",
+	}, []string{"status"})
+
+	// barkRetriesTotal Bark 投递失败后安排重试的次数
+	barkRetriesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "bark_retries_total",
+		Help:      "Failed Bark deliveries scheduled for retry.",
+	})
+
+	// barkServerUp Bark 服务器最近一次健康检查是否通过
+	barkServerUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "bark_server_up",
+		Help:      "Whether the last health check of the Bark server succeeded.",
+	}, []string{"server"})
+
+	// barkServerLatency Bark 服务器最近一次健康检查的响应时间
+	barkServerLatency = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "bark_server_latency_seconds",
+		Help:      "Response time of the last successful Bark server health check.",
+	}, []string{"server"})
+
+	// barkFailoversTotal 因服务器不健康改用备用服务器的次数
+	barkFailoversTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "bark_failovers_total",
+		Help:      "Bark pushes sent through a fallback server because the primary was unhealthy.",
+	})
+
+	// channelSendsTotal 通知渠道（Webhook、邮件、Telegram 等）发送次数
+	channelSendsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "channel_sends_total",
+		Help:      "Notifications sent through non-Bark channels, by channel type and status.",
+	}, []string{"type", "status"})
+
+	// dedupSkipsTotal 去重跳过次数
+	dedupSkipsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "bark_dedup_skips_total",
+		Help:      "Bark notifications skipped by deduplication, by mode.",
+	}, []string{"mode"})
+
+	// dbRetriesTotal WithRetry 的重试次数
+	dbRetriesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "db_retries_total",
+		Help:      "Database operations retried by WithRetry.",
+	})
+
+	// dbRetryFailuresTotal 重试耗尽后仍失败的次数
+	dbRetryFailuresTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "db_retry_exhausted_total",
+		Help:      "Database operations that still failed after all retries.",
+	})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		executionsTotal,
+		executionDuration,
+		executionsInFlight,
+		notificationsInFlight,
+		barkSendsTotal,
+		barkRetriesTotal,
+		barkServerUp,
+		barkServerLatency,
+		barkFailoversTotal,
+		channelSendsTotal,
+		dedupSkipsTotal,
+		dbRetriesTotal,
+		dbRetryFailuresTotal,
+	)
+}
+
+// ObserveExecution 记录一次完成的执行
+func ObserveExecution(taskID uint, status string, duration time.Duration) {
+	id := strconv.FormatUint(uint64(taskID), 10)
+	executionsTotal.WithLabelValues(id, status).Inc()
+	executionDuration.WithLabelValues(id).Observe(duration.Seconds())
+}
+
+// ExecutionStarted 执行开始
+func ExecutionStarted() {
+	executionsInFlight.Inc()
+}
+
+// ExecutionFinished 执行结束
+func ExecutionFinished() {
+	executionsInFlight.Dec()
+}
+
+// NotificationStarted 通知开始发送
+func NotificationStarted() {
+	notificationsInFlight.Inc()
+}
+
+// NotificationFinished 通知发送结束
+func NotificationFinished() {
+	notificationsInFlight.Dec()
+}
+
+// BarkSent 记录一次 Bark 发送结果
+func BarkSent(status string) {
+	barkSendsTotal.WithLabelValues(status).Inc()
+}
+
+// BarkRetried 记录一次 Bark 投递重试
+func BarkRetried() {
+	barkRetriesTotal.Inc()
+}
+
+// BarkServerHealth 记录一次 Bark 服务器健康检查结果
+func BarkServerHealth(server string, up bool, latency time.Duration) {
+	barkServerUp.WithLabelValues(server).Set(boolToFloat(up))
+	if up {
+		barkServerLatency.WithLabelValues(server).Set(latency.Seconds())
+	}
+}
+
+// BarkFailover 记录一次改用备用服务器的推送
+func BarkFailover() {
+	barkFailoversTotal.Inc()
+}
+
+// ChannelSent 记录一次通知渠道发送结果
+func ChannelSent(channelType, status string) {
+	channelSendsTotal.WithLabelValues(channelType, status).Inc()
+}
+
+// DedupSkipped 记录一次去重跳过
+func DedupSkipped(mode string) {
+	dedupSkipsTotal.WithLabelValues(mode).Inc()
+}
+
+// DBRetried 记录一次数据库重试
+func DBRetried() {
+	dbRetriesTotal.Inc()
+}
+
+// DBRetryExhausted 记录一次重试耗尽
+func DBRetryExhausted() {
+	dbRetryFailuresTotal.Inc()
+}
+
+// SchedulerSource 调度器状态，由 scheduler.Scheduler 实现
+type SchedulerSource interface {
+	GetScheduledTasks() int
+	IsRunning() bool
+	IsPaused() bool
+}
+
+// RegisterScheduler 注册调度器状态指标，抓取时读取
+func RegisterScheduler(s SchedulerSource) {
+	registry.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "scheduler_entries",
+			Help:      "Cron entries currently scheduled on this instance.",
+		}, func() float64 { return float64(s.GetScheduledTasks()) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "scheduler_running",
+			Help:      "Whether the scheduler is running on this instance (1 on the leader).",
+		}, func() float64 { return boolToFloat(s.IsRunning()) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "scheduler_paused",
+			Help:      "Whether scheduling is globally paused.",
+		}, func() float64 { return boolToFloat(s.IsPaused()) }),
+	)
+}
+
+// RegisterLogStats 注册执行日志表大小指标
+// stats 返回 LogManager.GetLogStats 的结果，抓取时调用
+func RegisterLogStats(stats func() map[string]interface{}) {
+	registry.MustRegister(&logStatsCollector{stats: stats})
+}
+
+// logStatsCollector 抓取时读取日志统计
+type logStatsCollector struct {
+	stats func() map[string]interface{}
+}
+
+var (
+	logTotalDesc = prometheus.NewDesc(namespace+"_task_logs", "Rows in the task log table.", nil, nil)
+	logLimitDesc = prometheus.NewDesc(namespace+"_task_logs_limit", "Configured task log retention limits.", []string{"scope"}, nil)
+)
+
+// Describe 实现 prometheus.Collector
+func (c *logStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- logTotalDesc
+	ch <- logLimitDesc
+}
+
+// Collect 实现 prometheus.Collector
+func (c *logStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.stats()
+	if v, ok := toFloat(stats["total_logs"]); ok {
+		ch <- prometheus.MustNewConstMetric(logTotalDesc, prometheus.GaugeValue, v)
+	}
+	if v, ok := toFloat(stats["max_total_logs"]); ok {
+		ch <- prometheus.MustNewConstMetric(logLimitDesc, prometheus.GaugeValue, v, "total")
+	}
+	if v, ok := toFloat(stats["max_logs_per_task"]); ok {
+		ch <- prometheus.MustNewConstMetric(logLimitDesc, prometheus.GaugeValue, v, "per_task")
+	}
+}
+
+// Handler /metrics 处理器
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// boolToFloat 布尔值转为 0/1
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// toFloat 将统计值转为 float64
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
@@ -3,7 +3,11 @@ package middleware
 import (
 	"autobot/internal/database"
 	"autobot/internal/models"
+	"crypto/sha256"
+	"encoding/hex"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -11,11 +15,65 @@ import (
 const (
 	SessionCookieName = "autobot_session"
 	UserIDKey         = "user_id"
+	// APITokenPrefix API 令牌的固定前缀
+	APITokenPrefix = "abt_"
 )
 
+// HashAPIToken 计算令牌的 SHA-256，数据库中只保存该值
+func HashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// bearerToken 从 Authorization 头中取出令牌
+func bearerToken(c *gin.Context) string {
+	header := c.GetHeader("Authorization")
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
+
+// authenticateToken 校验 API 令牌，返回令牌所属用户
+func authenticateToken(token string) (*models.User, bool) {
+	var apiToken models.APIToken
+	if err := database.GetDB().Where("token_hash = ?", HashAPIToken(token)).First(&apiToken).Error; err != nil {
+		return nil, false
+	}
+	now := time.Now()
+	if apiToken.ExpiresAt != nil && apiToken.ExpiresAt.Before(now) {
+		return nil, false
+	}
+
+	var user models.User
+	if err := database.GetDB().First(&user, apiToken.UserID).Error; err != nil {
+		return nil, false
+	}
+
+	// 最近使用时间只用于展示，每分钟最多更新一次
+	if apiToken.LastUsedAt == nil || now.Sub(*apiToken.LastUsedAt) > time.Minute {
+		database.GetDB().Model(&apiToken).Update("last_used_at", now)
+	}
+	return &user, true
+}
+
 // AuthMiddleware 身份鉴权中间件
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// 命令行客户端使用 Authorization: Bearer <token>
+		if token := bearerToken(c); token != "" {
+			user, ok := authenticateToken(token)
+			if !ok {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的 API 令牌"})
+				c.Abort()
+				return
+			}
+			c.Set(UserIDKey, user.ID)
+			c.Set("user", *user)
+			c.Next()
+			return
+		}
+
 		// 获取session cookie
 		sessionID, err := c.Cookie(SessionCookieName)
 		if err != nil {
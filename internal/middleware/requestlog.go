@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"autobot/internal/logging"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader 请求ID头，客户端传入时沿用，否则生成新的
+const RequestIDHeader = "X-Request-ID"
+
+// RequestIDKey gin 上下文中请求ID的键
+const RequestIDKey = "request_id"
+
+// RequestLogOptions 请求日志选项
+type RequestLogOptions struct {
+	Enabled   bool       // 为 false 时只生成请求ID，不记录请求日志
+	Level     slog.Level // 2xx/3xx 请求的级别，4xx 为 warn，5xx 为 error
+	SkipPaths []string   // 不记录的路径前缀
+}
+
+// RequestLogger 为每个请求分配请求ID并记录请求日志
+// 带 request_id 的 logger 放入请求 context，处理函数通过 logging.FromContext 获取
+func RequestLogger(opts RequestLogOptions) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		requestID := c.GetHeader(RequestIDHeader)
+		if requestID == "" || len(requestID) > 64 {
+			requestID = newRequestID()
+		}
+		c.Set(RequestIDKey, requestID)
+		c.Header(RequestIDHeader, requestID)
+
+		logger := slog.Default().With("request_id", requestID)
+		c.Request = c.Request.WithContext(logging.WithLogger(c.Request.Context(), logger))
+
+		c.Next()
+
+		path := c.Request.URL.Path
+		if !opts.Enabled || skipPath(path, opts.SkipPaths) {
+			return
+		}
+
+		status := c.Writer.Status()
+		level := opts.Level
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+
+		attrs := []any{
+			"method", c.Request.Method,
+			"path", path,
+			"status", status,
+			"duration_ms", time.Since(start).Milliseconds(),
+			"client_ip", c.ClientIP(),
+		}
+		if userID, ok := GetCurrentUserID(c); ok {
+			attrs = append(attrs, "user_id", userID)
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, "error", c.Errors.String())
+		}
+		logger.Log(c.Request.Context(), level, "HTTP request", attrs...)
+	}
+}
+
+// skipPath 路径是否匹配任一前缀
+func skipPath(path string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// newRequestID 生成 16 位十六进制请求ID
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
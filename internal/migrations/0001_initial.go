@@ -0,0 +1,143 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 0001 初始表结构，对应引入迁移之前 AutoMigrate 创建的表
+// 已有数据库执行该迁移时只会补齐缺失的表和列，不会修改数据
+
+type v1Task struct {
+	ID                  uint   `gorm:"primaryKey"`
+	Name                string `gorm:"not null"`
+	Description         string
+	Script              string `gorm:"type:text;not null"`
+	CronExpr            string `gorm:"not null"`
+	Status              string `gorm:"default:inactive"`
+	BarkConfig          string `gorm:"type:text"`
+	TimeExclusionConfig string `gorm:"type:text"`
+	LastRun             *time.Time
+	NextRun             *time.Time
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	DeletedAt           gorm.DeletedAt `gorm:"index"`
+}
+
+func (v1Task) TableName() string { return "tasks" }
+
+type v1TaskLog struct {
+	ID        uint   `gorm:"primaryKey"`
+	TaskID    uint   `gorm:"not null;index"`
+	Task      v1Task `gorm:"foreignKey:TaskID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	StartTime time.Time
+	EndTime   time.Time
+	Status    string
+	Output    string `gorm:"type:text"`
+	Error     string `gorm:"type:text"`
+	Result    string `gorm:"type:text"`
+	Duration  int64
+	CreatedAt time.Time
+}
+
+func (v1TaskLog) TableName() string { return "task_logs" }
+
+type v1BarkServer struct {
+	ID          uint   `gorm:"primaryKey"`
+	Name        string `gorm:"not null"`
+	URL         string `gorm:"not null"`
+	Description string
+	IsDefault   bool   `gorm:"default:false"`
+	Status      string `gorm:"default:active"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
+}
+
+func (v1BarkServer) TableName() string { return "bark_servers" }
+
+type v1BarkDevice struct {
+	ID          uint   `gorm:"primaryKey"`
+	Name        string `gorm:"not null"`
+	DeviceKey   string `gorm:"not null"`
+	Description string
+	ServerID    uint
+	Server      v1BarkServer `gorm:"foreignKey:ServerID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+	IsDefault   bool         `gorm:"default:false"`
+	Status      string       `gorm:"default:active"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
+}
+
+func (v1BarkDevice) TableName() string { return "bark_devices" }
+
+type v1BarkRecord struct {
+	ID             uint `gorm:"primaryKey"`
+	TaskID         uint
+	ContentHash    string `gorm:"index"`
+	DeviceKey      string
+	Title          string
+	Subtitle       string
+	Body           string
+	Level          string
+	Volume         string
+	Badge          string
+	Call           string
+	AutoCopy       string
+	Copy           string
+	Sound          string
+	Icon           string
+	Group          string
+	Ciphertext     string
+	IsArchive      string
+	URL            string
+	Action         string
+	NotificationID string
+	Delete         string
+	Status         string
+	ErrorMessage   string
+	ResponseData   string
+	CreatedAt      time.Time
+}
+
+func (v1BarkRecord) TableName() string { return "bark_records" }
+
+type v1User struct {
+	ID           uint   `gorm:"primaryKey"`
+	Username     string `gorm:"uniqueIndex;not null"`
+	PasswordHash string `gorm:"not null"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	DeletedAt    gorm.DeletedAt `gorm:"index"`
+}
+
+func (v1User) TableName() string { return "users" }
+
+func init() {
+	register(Migration{
+		Version: 1,
+		Name:    "initial",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(
+				&v1Task{},
+				&v1TaskLog{},
+				&v1BarkServer{},
+				&v1BarkDevice{},
+				&v1BarkRecord{},
+				&v1User{},
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(
+				&v1BarkRecord{},
+				&v1BarkDevice{},
+				&v1BarkServer{},
+				&v1TaskLog{},
+				&v1Task{},
+				&v1User{},
+			)
+		},
+	})
+}
@@ -0,0 +1,66 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 0002 调度器暂停状态、Leader 租约和执行日志的实例ID
+
+type v2SystemSetting struct {
+	Key       string `gorm:"column:setting_key;primaryKey"`
+	Value     string `gorm:"type:text"`
+	UpdatedAt time.Time
+}
+
+func (v2SystemSetting) TableName() string { return "system_settings" }
+
+type v2SchedulerLease struct {
+	Name      string `gorm:"primaryKey"`
+	HolderID  string
+	ExpiresAt time.Time `gorm:"index"`
+	UpdatedAt time.Time
+}
+
+func (v2SchedulerLease) TableName() string { return "scheduler_leases" }
+
+type v2TaskLog struct {
+	Instance string `gorm:"index"`
+}
+
+func (v2TaskLog) TableName() string { return "task_logs" }
+
+func init() {
+	register(Migration{
+		Version: 2,
+		Name:    "cluster",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(&v2SystemSetting{}, &v2SchedulerLease{}); err != nil {
+				return err
+			}
+			if !tx.Migrator().HasColumn(&v2TaskLog{}, "Instance") {
+				if err := tx.Migrator().AddColumn(&v2TaskLog{}, "Instance"); err != nil {
+					return err
+				}
+			}
+			if !tx.Migrator().HasIndex(&v2TaskLog{}, "Instance") {
+				return tx.Migrator().CreateIndex(&v2TaskLog{}, "Instance")
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			if tx.Migrator().HasIndex(&v2TaskLog{}, "Instance") {
+				if err := tx.Migrator().DropIndex(&v2TaskLog{}, "Instance"); err != nil {
+					return err
+				}
+			}
+			if tx.Migrator().HasColumn(&v2TaskLog{}, "Instance") {
+				if err := tx.Migrator().DropColumn(&v2TaskLog{}, "Instance"); err != nil {
+					return err
+				}
+			}
+			return tx.Migrator().DropTable(&v2SchedulerLease{}, &v2SystemSetting{})
+		},
+	})
+}
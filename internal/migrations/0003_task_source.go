@@ -0,0 +1,48 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 0003 任务来源，用于 Git 同步的任务
+
+type v3Task struct {
+	Source         string `gorm:"index"`
+	SourcePath     string
+	SourceRevision string
+}
+
+func (v3Task) TableName() string { return "tasks" }
+
+func init() {
+	register(Migration{
+		Version: 3,
+		Name:    "task_source",
+		Up: func(tx *gorm.DB) error {
+			for _, field := range []string{"Source", "SourcePath", "SourceRevision"} {
+				if !tx.Migrator().HasColumn(&v3Task{}, field) {
+					if err := tx.Migrator().AddColumn(&v3Task{}, field); err != nil {
+						return err
+					}
+				}
+			}
+			if !tx.Migrator().HasIndex(&v3Task{}, "Source") {
+				return tx.Migrator().CreateIndex(&v3Task{}, "Source")
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			if tx.Migrator().HasIndex(&v3Task{}, "Source") {
+				if err := tx.Migrator().DropIndex(&v3Task{}, "Source"); err != nil {
+					return err
+				}
+			}
+			for _, field := range []string{"SourceRevision", "SourcePath", "Source"} {
+				if tx.Migrator().HasColumn(&v3Task{}, field) {
+					if err := tx.Migrator().DropColumn(&v3Task{}, field); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 0004 API 访问令牌
+
+type v4APIToken struct {
+	ID         uint   `gorm:"primaryKey"`
+	UserID     uint   `gorm:"not null;index"`
+	Name       string `gorm:"not null"`
+	TokenHash  string `gorm:"size:64;uniqueIndex;not null"`
+	Prefix     string
+	LastUsedAt *time.Time
+	ExpiresAt  *time.Time
+	CreatedAt  time.Time
+}
+
+func (v4APIToken) TableName() string { return "api_tokens" }
+
+func init() {
+	register(Migration{
+		Version: 4,
+		Name:    "api_tokens",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&v4APIToken{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&v4APIToken{})
+		},
+	})
+}
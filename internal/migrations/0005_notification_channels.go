@@ -0,0 +1,63 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 0005 Bark 以外的通知渠道，发送记录关联渠道
+
+type v5NotificationChannel struct {
+	ID          uint   `gorm:"primaryKey"`
+	Name        string `gorm:"not null"`
+	Type        string `gorm:"not null;index"`
+	Config      string `gorm:"type:text"`
+	Description string
+	Status      string `gorm:"default:active"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
+}
+
+func (v5NotificationChannel) TableName() string { return "notification_channels" }
+
+type v5BarkRecord struct {
+	ChannelID uint `gorm:"index"`
+}
+
+func (v5BarkRecord) TableName() string { return "bark_records" }
+
+func init() {
+	register(Migration{
+		Version: 5,
+		Name:    "notification_channels",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(&v5NotificationChannel{}); err != nil {
+				return err
+			}
+			if !tx.Migrator().HasColumn(&v5BarkRecord{}, "ChannelID") {
+				if err := tx.Migrator().AddColumn(&v5BarkRecord{}, "ChannelID"); err != nil {
+					return err
+				}
+			}
+			if !tx.Migrator().HasIndex(&v5BarkRecord{}, "ChannelID") {
+				return tx.Migrator().CreateIndex(&v5BarkRecord{}, "ChannelID")
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			if tx.Migrator().HasIndex(&v5BarkRecord{}, "ChannelID") {
+				if err := tx.Migrator().DropIndex(&v5BarkRecord{}, "ChannelID"); err != nil {
+					return err
+				}
+			}
+			if tx.Migrator().HasColumn(&v5BarkRecord{}, "ChannelID") {
+				if err := tx.Migrator().DropColumn(&v5BarkRecord{}, "ChannelID"); err != nil {
+					return err
+				}
+			}
+			return tx.Migrator().DropTable(&v5NotificationChannel{})
+		},
+	})
+}
@@ -0,0 +1,45 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 0006 Bark 设备推送加密配置
+
+type v6BarkDevice struct {
+	EncryptionAlgorithm string
+	EncryptionMode      string
+	EncryptionKey       string
+	EncryptionIV        string
+}
+
+func (v6BarkDevice) TableName() string { return "bark_devices" }
+
+var v6BarkDeviceColumns = []string{"EncryptionAlgorithm", "EncryptionMode", "EncryptionKey", "EncryptionIV"}
+
+func init() {
+	register(Migration{
+		Version: 6,
+		Name:    "bark_encryption",
+		Up: func(tx *gorm.DB) error {
+			for _, column := range v6BarkDeviceColumns {
+				if tx.Migrator().HasColumn(&v6BarkDevice{}, column) {
+					continue
+				}
+				if err := tx.Migrator().AddColumn(&v6BarkDevice{}, column); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, column := range v6BarkDeviceColumns {
+				if !tx.Migrator().HasColumn(&v6BarkDevice{}, column) {
+					continue
+				}
+				if err := tx.Migrator().DropColumn(&v6BarkDevice{}, column); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,56 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 0007 Bark 推送投递队列，服务器推送超时
+
+type v7BarkOutbox struct {
+	ID            uint `gorm:"primaryKey"`
+	RecordID      uint `gorm:"index"`
+	TaskID        uint
+	DeviceKey     string
+	Payload       string `gorm:"type:text"`
+	Status        string `gorm:"index"`
+	Attempts      int
+	NextAttemptAt time.Time `gorm:"index"`
+	LockedUntil   time.Time
+	LastError     string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+func (v7BarkOutbox) TableName() string { return "bark_outbox" }
+
+type v7BarkServer struct {
+	Timeout int
+}
+
+func (v7BarkServer) TableName() string { return "bark_servers" }
+
+func init() {
+	register(Migration{
+		Version: 7,
+		Name:    "bark_outbox",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(&v7BarkOutbox{}); err != nil {
+				return err
+			}
+			if !tx.Migrator().HasColumn(&v7BarkServer{}, "Timeout") {
+				return tx.Migrator().AddColumn(&v7BarkServer{}, "Timeout")
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			if tx.Migrator().HasColumn(&v7BarkServer{}, "Timeout") {
+				if err := tx.Migrator().DropColumn(&v7BarkServer{}, "Timeout"); err != nil {
+					return err
+				}
+			}
+			return tx.Migrator().DropTable(&v7BarkOutbox{})
+		},
+	})
+}
@@ -0,0 +1,68 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 0008 通知汇总，发送记录关联汇总分组和汇总消息
+
+type v8BarkRecord struct {
+	DigestKey   string `gorm:"index"`
+	DigestAt    *time.Time
+	DigestID    uint `gorm:"index"`
+	DigestCount int
+}
+
+func (v8BarkRecord) TableName() string { return "bark_records" }
+
+var (
+	v8BarkRecordColumns = []string{"DigestKey", "DigestAt", "DigestID", "DigestCount"}
+	v8BarkRecordIndexes = []string{"DigestKey", "DigestID"}
+)
+
+func init() {
+	register(Migration{
+		Version: 8,
+		Name:    "bark_digest",
+		Up: func(tx *gorm.DB) error {
+			for _, column := range v8BarkRecordColumns {
+				if tx.Migrator().HasColumn(&v8BarkRecord{}, column) {
+					continue
+				}
+				if err := tx.Migrator().AddColumn(&v8BarkRecord{}, column); err != nil {
+					return err
+				}
+			}
+			for _, index := range v8BarkRecordIndexes {
+				if tx.Migrator().HasIndex(&v8BarkRecord{}, index) {
+					continue
+				}
+				if err := tx.Migrator().CreateIndex(&v8BarkRecord{}, index); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, index := range v8BarkRecordIndexes {
+				if !tx.Migrator().HasIndex(&v8BarkRecord{}, index) {
+					continue
+				}
+				if err := tx.Migrator().DropIndex(&v8BarkRecord{}, index); err != nil {
+					return err
+				}
+			}
+			for _, column := range v8BarkRecordColumns {
+				if !tx.Migrator().HasColumn(&v8BarkRecord{}, column) {
+					continue
+				}
+				if err := tx.Migrator().DropColumn(&v8BarkRecord{}, column); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,64 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 0009 Bark 设备和服务器的推送限额，设备免打扰时段
+
+type v9BarkDevice struct {
+	RateLimitHour int
+	RateLimitDay  int
+	QuietStart    string
+	QuietEnd      string
+	QuietMode     string
+}
+
+func (v9BarkDevice) TableName() string { return "bark_devices" }
+
+type v9BarkServer struct {
+	RateLimitHour int
+	RateLimitDay  int
+}
+
+func (v9BarkServer) TableName() string { return "bark_servers" }
+
+// v9Columns 各表新增的列
+var v9Columns = []struct {
+	model   interface{}
+	columns []string
+}{
+	{&v9BarkDevice{}, []string{"RateLimitHour", "RateLimitDay", "QuietStart", "QuietEnd", "QuietMode"}},
+	{&v9BarkServer{}, []string{"RateLimitHour", "RateLimitDay"}},
+}
+
+func init() {
+	register(Migration{
+		Version: 9,
+		Name:    "bark_rate_limits",
+		Up: func(tx *gorm.DB) error {
+			for _, table := range v9Columns {
+				for _, column := range table.columns {
+					if tx.Migrator().HasColumn(table.model, column) {
+						continue
+					}
+					if err := tx.Migrator().AddColumn(table.model, column); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, table := range v9Columns {
+				for _, column := range table.columns {
+					if !tx.Migrator().HasColumn(table.model, column) {
+						continue
+					}
+					if err := tx.Migrator().DropColumn(table.model, column); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	})
+}
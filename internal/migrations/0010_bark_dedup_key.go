@@ -0,0 +1,44 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 0010 发送记录的去重键，已有记录的去重键取内容hash
+
+type v10BarkRecord struct {
+	DedupKey string `gorm:"index"`
+}
+
+func (v10BarkRecord) TableName() string { return "bark_records" }
+
+func init() {
+	register(Migration{
+		Version: 10,
+		Name:    "bark_dedup_key",
+		Up: func(tx *gorm.DB) error {
+			if !tx.Migrator().HasColumn(&v10BarkRecord{}, "DedupKey") {
+				if err := tx.Migrator().AddColumn(&v10BarkRecord{}, "DedupKey"); err != nil {
+					return err
+				}
+			}
+			if !tx.Migrator().HasIndex(&v10BarkRecord{}, "DedupKey") {
+				if err := tx.Migrator().CreateIndex(&v10BarkRecord{}, "DedupKey"); err != nil {
+					return err
+				}
+			}
+			return tx.Model(&v10BarkRecord{}).
+				Where("dedup_key IS NULL OR dedup_key = ''").
+				Update("dedup_key", gorm.Expr("content_hash")).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			if tx.Migrator().HasIndex(&v10BarkRecord{}, "DedupKey") {
+				if err := tx.Migrator().DropIndex(&v10BarkRecord{}, "DedupKey"); err != nil {
+					return err
+				}
+			}
+			if !tx.Migrator().HasColumn(&v10BarkRecord{}, "DedupKey") {
+				return nil
+			}
+			return tx.Migrator().DropColumn(&v10BarkRecord{}, "DedupKey")
+		},
+	})
+}
@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 0011 Bark 设备分组及分组成员
+
+type v11BarkDeviceGroup struct {
+	ID          uint   `gorm:"primaryKey"`
+	Name        string `gorm:"not null"`
+	Description string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
+}
+
+func (v11BarkDeviceGroup) TableName() string { return "bark_device_groups" }
+
+type v11BarkDeviceGroupMember struct {
+	BarkDeviceGroupID uint `gorm:"primaryKey"`
+	BarkDeviceID      uint `gorm:"primaryKey;index"`
+}
+
+func (v11BarkDeviceGroupMember) TableName() string { return "bark_device_group_members" }
+
+func init() {
+	register(Migration{
+		Version: 11,
+		Name:    "bark_device_groups",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&v11BarkDeviceGroup{}, &v11BarkDeviceGroupMember{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&v11BarkDeviceGroupMember{}, &v11BarkDeviceGroup{})
+		},
+	})
+}
@@ -0,0 +1,53 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 0012 Bark 服务器的备用服务器和健康检查结果
+
+type v12BarkServer struct {
+	FallbackServerID uint
+	Health           string `gorm:"default:unknown"`
+	HealthLatency    int64
+	HealthError      string
+	HealthFailures   int
+	HealthCheckedAt  *time.Time
+}
+
+func (v12BarkServer) TableName() string { return "bark_servers" }
+
+var v12Columns = []string{"FallbackServerID", "Health", "HealthLatency", "HealthError", "HealthFailures", "HealthCheckedAt"}
+
+func init() {
+	register(Migration{
+		Version: 12,
+		Name:    "bark_server_health",
+		Up: func(tx *gorm.DB) error {
+			for _, column := range v12Columns {
+				if tx.Migrator().HasColumn(&v12BarkServer{}, column) {
+					continue
+				}
+				if err := tx.Migrator().AddColumn(&v12BarkServer{}, column); err != nil {
+					return err
+				}
+			}
+			return tx.Model(&v12BarkServer{}).
+				Where("health IS NULL OR health = ''").
+				Update("health", "unknown").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, column := range v12Columns {
+				if !tx.Migrator().HasColumn(&v12BarkServer{}, column) {
+					continue
+				}
+				if err := tx.Migrator().DropColumn(&v12BarkServer{}, column); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 0013 实例心跳
+
+type v13InstanceHeartbeat struct {
+	InstanceID string `gorm:"primaryKey"`
+	StartedAt  time.Time
+	LastSeen   time.Time `gorm:"index"`
+}
+
+func (v13InstanceHeartbeat) TableName() string { return "instance_heartbeats" }
+
+func init() {
+	register(Migration{
+		Version: 13,
+		Name:    "instance_heartbeats",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&v13InstanceHeartbeat{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&v13InstanceHeartbeat{})
+		},
+	})
+}
@@ -0,0 +1,299 @@
+package migrations
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration 一个带版本号的数据库迁移
+// Up/Down 只能使用迁移文件内定义的结构体快照，不能引用 models 包，否则模型后续变化会改变历史迁移的行为
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// SchemaMigration 已执行迁移的记录
+type SchemaMigration struct {
+	Version   int       `json:"version" gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `json:"name"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+// TableName 迁移记录表名
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// MigrationStatus 迁移状态
+type MigrationStatus struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// 迁移锁，防止多个实例同时执行迁移
+const (
+	lockName    = "autobot_schema_migrations" // MySQL GET_LOCK 名称
+	pgLockKey   = 727366931                   // PostgreSQL 咨询锁键
+	lockTimeout = 10 * time.Minute            // MySQL 等待锁的最长时间
+)
+
+// ErrDatabaseTooNew 数据库版本高于当前程序支持的版本
+var ErrDatabaseTooNew = errors.New("database schema is newer than this binary")
+
+// registry 所有迁移，由各迁移文件在 init 中注册
+var registry []Migration
+
+// register 注册迁移
+func register(m Migration) {
+	registry = append(registry, m)
+	sort.Slice(registry, func(i, j int) bool {
+		return registry[i].Version < registry[j].Version
+	})
+}
+
+// All 按版本号升序返回所有迁移
+func All() []Migration {
+	return registry
+}
+
+// Latest 当前程序支持的最新版本
+func Latest() int {
+	if len(registry) == 0 {
+		return 0
+	}
+	return registry[len(registry)-1].Version
+}
+
+// ensureTable 创建 schema_migrations 表，其他实例同时创建成功时视为成功
+func ensureTable(db *gorm.DB) error {
+	err := db.AutoMigrate(&SchemaMigration{})
+	if err != nil && db.Migrator().HasTable(&SchemaMigration{}) {
+		return nil
+	}
+	return err
+}
+
+// applied 获取已执行的迁移
+func applied(db *gorm.DB) (map[int]SchemaMigration, error) {
+	if err := ensureTable(db); err != nil {
+		return nil, err
+	}
+
+	var records []SchemaMigration
+	if err := db.Order("version asc").Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	result := make(map[int]SchemaMigration, len(records))
+	for _, record := range records {
+		result[record.Version] = record
+	}
+	return result, nil
+}
+
+// CurrentVersion 数据库当前版本（已执行的最大迁移版本号）
+func CurrentVersion(db *gorm.DB) (int, error) {
+	done, err := applied(db)
+	if err != nil {
+		return 0, err
+	}
+
+	version := 0
+	for v := range done {
+		if v > version {
+			version = v
+		}
+	}
+	return version, nil
+}
+
+// CheckCompatible 检查数据库版本是否被当前程序支持
+func CheckCompatible(db *gorm.DB) error {
+	version, err := CurrentVersion(db)
+	if err != nil {
+		return err
+	}
+	if version > Latest() {
+		return fmt.Errorf("%w: database is at version %d, binary supports up to %d", ErrDatabaseTooNew, version, Latest())
+	}
+	return nil
+}
+
+// Up 执行所有未执行的迁移，返回本次执行的迁移
+// 多个实例同时启动时通过数据库锁串行执行，已被其他实例执行的迁移直接跳过
+func Up(db *gorm.DB) (ran []Migration, err error) {
+	err = withLock(db, func(conn *gorm.DB) error {
+		ran, err = up(conn)
+		return err
+	})
+	return ran, err
+}
+
+// up 在持有迁移锁的连接上执行未执行的迁移
+func up(db *gorm.DB) ([]Migration, error) {
+	if err := CheckCompatible(db); err != nil {
+		return nil, err
+	}
+
+	done, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var ran []Migration
+	for _, m := range registry {
+		if _, ok := done[m.Version]; ok {
+			continue
+		}
+
+		// 每个迁移及其记录在同一事务中提交
+		// 事务内再次检查版本：SQLite 没有咨询锁，靠事务的写锁串行化
+		alreadyApplied := false
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var count int64
+			if err := tx.Model(&SchemaMigration{}).Where("version = ?", m.Version).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				alreadyApplied = true
+				return nil
+			}
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{
+				Version:   m.Version,
+				Name:      m.Name,
+				AppliedAt: time.Now(),
+			}).Error
+		})
+		if err != nil {
+			return ran, fmt.Errorf("migration %04d_%s failed: %v", m.Version, m.Name, err)
+		}
+		if alreadyApplied {
+			slog.Info("Migration already applied by another instance", "version", m.Version, "name", m.Name)
+			continue
+		}
+
+		slog.Info("Applied migration", "version", m.Version, "name", m.Name)
+		ran = append(ran, m)
+	}
+
+	return ran, nil
+}
+
+// withLock 在单个连接上持有迁移锁执行 fn
+// PostgreSQL 使用 pg_advisory_lock，MySQL 使用 GET_LOCK；都是会话级锁，所以 fn 必须使用同一连接
+func withLock(db *gorm.DB, fn func(conn *gorm.DB) error) error {
+	switch db.Dialector.Name() {
+	case "postgres":
+		return db.Connection(func(conn *gorm.DB) error {
+			if err := conn.Exec("SELECT pg_advisory_lock(?)", pgLockKey).Error; err != nil {
+				return fmt.Errorf("failed to acquire migration lock: %v", err)
+			}
+			defer conn.Exec("SELECT pg_advisory_unlock(?)", pgLockKey)
+			return fn(conn)
+		})
+	case "mysql":
+		return db.Connection(func(conn *gorm.DB) error {
+			var locked sql.NullInt64
+			if err := conn.Raw("SELECT GET_LOCK(?, ?)", lockName, int(lockTimeout.Seconds())).Scan(&locked).Error; err != nil {
+				return fmt.Errorf("failed to acquire migration lock: %v", err)
+			}
+			if !locked.Valid || locked.Int64 != 1 {
+				return fmt.Errorf("timed out waiting for migration lock after %s", lockTimeout)
+			}
+			defer conn.Exec("SELECT RELEASE_LOCK(?)", lockName)
+			return fn(conn)
+		})
+	default:
+		return fn(db)
+	}
+}
+
+// Down 回滚最近执行的 steps 个迁移，返回本次回滚的迁移
+func Down(db *gorm.DB, steps int) (rolledBack []Migration, err error) {
+	err = withLock(db, func(conn *gorm.DB) error {
+		rolledBack, err = down(conn, steps)
+		return err
+	})
+	return rolledBack, err
+}
+
+// down 在持有迁移锁的连接上回滚迁移
+func down(db *gorm.DB, steps int) ([]Migration, error) {
+	done, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var rolledBack []Migration
+	for i := len(registry) - 1; i >= 0 && len(rolledBack) < steps; i-- {
+		m := registry[i]
+		if _, ok := done[m.Version]; !ok {
+			continue
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&SchemaMigration{}, m.Version).Error
+		})
+		if err != nil {
+			return rolledBack, fmt.Errorf("rollback of migration %04d_%s failed: %v", m.Version, m.Name, err)
+		}
+
+		slog.Info("Rolled back migration", "version", m.Version, "name", m.Name)
+		rolledBack = append(rolledBack, m)
+	}
+
+	return rolledBack, nil
+}
+
+// Status 所有迁移的执行状态
+func Status(db *gorm.DB) ([]MigrationStatus, error) {
+	done, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(registry))
+	for _, m := range registry {
+		status := MigrationStatus{Version: m.Version, Name: m.Name}
+		if record, ok := done[m.Version]; ok {
+			appliedAt := record.AppliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+
+	// 数据库中存在但程序不认识的迁移（由更新的版本执行）
+	for version, record := range done {
+		if version > Latest() {
+			appliedAt := record.AppliedAt
+			statuses = append(statuses, MigrationStatus{
+				Version:   version,
+				Name:      record.Name + " (unknown to this binary)",
+				Applied:   true,
+				AppliedAt: &appliedAt,
+			})
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+
+	return statuses, nil
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// 投递队列状态
+const (
+	OutboxPending = "pending" // 等待投递或等待重试
+	OutboxSending = "sending" // 已被某个实例领取，正在投递
+	OutboxDead    = "dead"    // 重试次数用尽，进入死信
+)
+
+// BarkOutbox 待投递的 Bark 推送
+// 投递成功后删除；重试耗尽后保留为死信，可通过重发发送记录重新入队
+type BarkOutbox struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	RecordID      uint      `json:"record_id" gorm:"index"`       // 对应的发送记录
+	TaskID        uint      `json:"task_id"`                      // 关联的任务ID
+	DeviceKey     string    `json:"device_key"`                   // 设备密钥
+	Payload       string    `json:"-" gorm:"type:text"`           // 推送参数 JSON，加密在投递时进行
+	Status        string    `json:"status" gorm:"index"`          // pending, sending, dead
+	Attempts      int       `json:"attempts"`                     // 已投递次数
+	NextAttemptAt time.Time `json:"next_attempt_at" gorm:"index"` // 下次投递时间
+	LockedUntil   time.Time `json:"-"`                            // 领取的有效期，过期后可被重新领取
+	LastError     string    `json:"last_error,omitempty"`         // 最近一次失败原因
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// TableName 投递队列表名
+func (BarkOutbox) TableName() string { return "bark_outbox" }
@@ -10,40 +10,56 @@ import (
 // BarkRecord Bark发送记录
 type BarkRecord struct {
 	ID             uint      `gorm:"primaryKey" json:"id"`
-	TaskID         uint      `json:"task_id"`                   // 关联的任务ID
-	ContentHash    string    `gorm:"index" json:"content_hash"` // 内容hash，用于去重
-	DeviceKey      string    `json:"device_key"`                // 设备密钥
-	Title          string    `json:"title"`                     // 通知标题
-	Subtitle       string    `json:"subtitle"`                  // 通知副标题
-	Body           string    `json:"body"`                      // 通知内容
-	Level          string    `json:"level"`                     // 通知级别
-	Volume         string    `json:"volume"`                    // 音量
-	Badge          string    `json:"badge"`                     // 角标数字
-	Call           string    `json:"call"`                      // 通话模式
-	AutoCopy       string    `json:"auto_copy"`                 // 自动复制
-	Copy           string    `json:"copy"`                      // 复制内容
-	Sound          string    `json:"sound"`                     // 通知铃声
-	Icon           string    `json:"icon"`                      // 通知图标URL
-	Group          string    `json:"group"`                     // 通知分组
-	Ciphertext     string    `json:"ciphertext"`                // 加密内容
-	IsArchive      string    `json:"is_archive"`                // 是否存档
-	URL            string    `json:"url"`                       // 跳转URL
-	Action         string    `json:"action"`                    // 自定义动作
-	NotificationID string    `json:"notification_id"`           // 通知ID
-	Delete         string    `json:"delete"`                    // 删除通知
-	Status         string    `json:"status"`                    // 发送状态：success, failed
-	ErrorMessage   string    `json:"error_message,omitempty"`   // 错误信息
-	ResponseData   string    `json:"response_data,omitempty"`   // 响应数据
+	TaskID         uint      `json:"task_id"`                           // 关联的任务ID
+	ContentHash    string    `gorm:"index" json:"content_hash"`         // 内容hash
+	DedupKey       string    `gorm:"index" json:"dedup_key"`            // 去重键hash，未配置去重键时与内容hash相同
+	DeviceKey      string    `json:"device_key"`                        // 设备密钥
+	ChannelID      uint      `gorm:"index" json:"channel_id,omitempty"` // 通知渠道ID，Bark 设备的记录为 0
+	Title          string    `json:"title"`                             // 通知标题
+	Subtitle       string    `json:"subtitle"`                          // 通知副标题
+	Body           string    `json:"body"`                              // 通知内容
+	Level          string    `json:"level"`                             // 通知级别
+	Volume         string    `json:"volume"`                            // 音量
+	Badge          string    `json:"badge"`                             // 角标数字
+	Call           string    `json:"call"`                              // 通话模式
+	AutoCopy       string    `json:"auto_copy"`                         // 自动复制
+	Copy           string    `json:"copy"`                              // 复制内容
+	Sound          string    `json:"sound"`                             // 通知铃声
+	Icon           string    `json:"icon"`                              // 通知图标URL
+	Group          string    `json:"group"`                             // 通知分组
+	Ciphertext     string    `json:"ciphertext"`                        // 加密内容
+	IsArchive      string    `json:"is_archive"`                        // 是否存档
+	URL            string    `json:"url"`                               // 跳转URL
+	Action         string    `json:"action"`                            // 自定义动作
+	NotificationID string    `json:"notification_id"`                   // 通知ID
+	Delete         string    `json:"delete"`                            // 删除通知
+	Status         string    `json:"status"`                            // 发送状态：pending, success, failed, skipped, queued, digested, throttled
+	ErrorMessage   string    `json:"error_message,omitempty"`           // 错误信息
+	ResponseData   string    `json:"response_data,omitempty"`           // 响应数据
 	CreatedAt      time.Time `json:"created_at"`
+
+	// 通知汇总：被汇总的记录先以 queued 状态等待，发送汇总时改为 digested 并关联汇总消息的记录
+	DigestKey   string     `gorm:"index" json:"digest_key,omitempty"` // 汇总分组
+	DigestAt    *time.Time `json:"digest_at,omitempty"`               // 计划发送汇总的时间
+	DigestID    uint       `gorm:"index" json:"digest_id,omitempty"`  // 所属汇总消息的记录ID
+	DigestCount int        `json:"digest_count,omitempty"`            // 汇总消息包含的通知数
 }
 
-// GenerateContentHash 生成内容hash
+// 汇总和限流相关的记录状态
+const (
+	RecordQueued    = "queued"    // 等待合并到汇总
+	RecordDigested  = "digested"  // 已合并到汇总消息
+	RecordThrottled = "throttled" // 超过设备或服务器的推送限额，未发送
+)
+
+// GenerateContentHash 生成内容hash，去重键重置为内容hash
 func (br *BarkRecord) GenerateContentHash() {
 	// 构建用于hash的内容结构
 	// 注意：包含DeviceKey以确保不同设备的相同内容有不同的hash
 	content := struct {
 		TaskID     uint   `json:"task_id"`
-		DeviceKey  string `json:"device_key"` // 添加设备密钥到hash计算中
+		DeviceKey  string `json:"device_key"`           // 添加设备密钥到hash计算中
+		ChannelID  uint   `json:"channel_id,omitempty"` // 为 0 时不参与，已有记录的hash保持不变
 		Title      string `json:"title"`
 		Subtitle   string `json:"subtitle"`
 		Body       string `json:"body"`
@@ -64,6 +80,7 @@ func (br *BarkRecord) GenerateContentHash() {
 	}{
 		TaskID:     br.TaskID,
 		DeviceKey:  br.DeviceKey, // 添加设备密钥到hash计算中
+		ChannelID:  br.ChannelID,
 		Title:      br.Title,
 		Subtitle:   br.Subtitle,
 		Body:       br.Body,
@@ -87,6 +104,26 @@ func (br *BarkRecord) GenerateContentHash() {
 	jsonData, _ := json.Marshal(content)
 	hash := md5.Sum(jsonData)
 	br.ContentHash = hex.EncodeToString(hash[:])
+	br.DedupKey = br.ContentHash
+}
+
+// GenerateDedupKey 按任务、目标和去重键的值生成去重键hash
+func (br *BarkRecord) GenerateDedupKey(value string) {
+	content := struct {
+		TaskID    uint   `json:"task_id"`
+		DeviceKey string `json:"device_key"`
+		ChannelID uint   `json:"channel_id,omitempty"`
+		Key       string `json:"key"`
+	}{
+		TaskID:    br.TaskID,
+		DeviceKey: br.DeviceKey,
+		ChannelID: br.ChannelID,
+		Key:       value,
+	}
+
+	jsonData, _ := json.Marshal(content)
+	hash := md5.Sum(jsonData)
+	br.DedupKey = hex.EncodeToString(hash[:])
 }
 
 // CreateBarkRecordFromConfig 从BarkConfig创建BarkRecord
@@ -123,3 +160,28 @@ func CreateBarkRecordFromConfig(taskID uint, deviceKey string, config *BarkConfi
 
 	return record
 }
+
+// BarkConfig 由记录还原推送参数，用于重发
+func (br *BarkRecord) BarkConfig() *BarkConfig {
+	return &BarkConfig{
+		DeviceKey:  br.DeviceKey,
+		Title:      br.Title,
+		Subtitle:   br.Subtitle,
+		Body:       br.Body,
+		Level:      br.Level,
+		Volume:     br.Volume,
+		Badge:      br.Badge,
+		Call:       br.Call,
+		AutoCopy:   br.AutoCopy,
+		Copy:       br.Copy,
+		Sound:      br.Sound,
+		Icon:       br.Icon,
+		Group:      br.Group,
+		Ciphertext: br.Ciphertext,
+		IsArchive:  br.IsArchive,
+		URL:        br.URL,
+		Action:     br.Action,
+		ID:         br.NotificationID,
+		Delete:     br.Delete,
+	}
+}
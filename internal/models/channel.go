@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 通知渠道类型
+const (
+	ChannelWebhook  = "webhook"  // 通用 Webhook，请求体为 JSON 模板
+	ChannelEmail    = "email"    // SMTP 邮件
+	ChannelTelegram = "telegram" // Telegram Bot
+	ChannelSlack    = "slack"    // Slack Incoming Webhook
+	ChannelDingTalk = "dingtalk" // 钉钉群机器人
+	ChannelWeCom    = "wecom"    // 企业微信群机器人
+	ChannelFeishu   = "feishu"   // 飞书群机器人
+	ChannelNtfy     = "ntfy"     // ntfy
+	ChannelGotify   = "gotify"   // Gotify
+)
+
+// NotificationChannel Bark 以外的通知渠道
+// Config 为各类型自己的 JSON 配置，见 channel 包
+type NotificationChannel struct {
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	Name        string         `json:"name" gorm:"not null"`         // 渠道名称
+	Type        string         `json:"type" gorm:"not null;index"`   // 渠道类型
+	Config      string         `json:"config" gorm:"type:text"`      // 渠道配置 JSON，接口返回时密钥被替换为 ******
+	HasSecret   bool           `json:"has_secret" gorm:"-"`          // 是否配置了密码、令牌等密钥
+	Description string         `json:"description"`                  // 描述
+	Status      string         `json:"status" gorm:"default:active"` // active, inactive
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// CreateChannelRequest 创建通知渠道请求
+type CreateChannelRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Type        string `json:"type" binding:"required"`
+	Config      string `json:"config" binding:"required"`
+	Description string `json:"description"`
+}
+
+// UpdateChannelRequest 更新通知渠道请求
+type UpdateChannelRequest struct {
+	Name        string `json:"name"`
+	Config      string `json:"config"` // 密钥为空或为 ****** 时保留原值
+	Description string `json:"description"`
+	Status      string `json:"status"`
+}
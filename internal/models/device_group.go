@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// BarkDeviceGroup Bark 设备分组，任务按分组选择设备，修改分组成员后引用该分组的任务随之生效
+type BarkDeviceGroup struct {
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	Name        string         `json:"name" gorm:"not null"` // 分组名称，例如 on-call、family
+	Description string         `json:"description"`          // 描述
+	Devices     []BarkDevice   `json:"devices" gorm:"many2many:bark_device_group_members"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// CreateBarkDeviceGroupRequest 创建设备分组请求
+type CreateBarkDeviceGroupRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	DeviceIDs   []uint `json:"device_ids"`
+}
+
+// UpdateBarkDeviceGroupRequest 更新设备分组请求，字段为空时保持不变
+// DeviceIDs 为 null 或未提供时不修改成员，为 [] 时清空成员
+type UpdateBarkDeviceGroupRequest struct {
+	Name        string  `json:"name"`
+	Description *string `json:"description"`
+	DeviceIDs   []uint  `json:"device_ids"`
+}
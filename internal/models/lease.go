@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// SchedulerLease 调度器租约，多实例共享数据库时只有持有租约的实例执行调度
+type SchedulerLease struct {
+	Name      string    `json:"name" gorm:"primaryKey"`  // 租约名称
+	HolderID  string    `json:"holder_id"`               // 持有者实例ID
+	ExpiresAt time.Time `json:"expires_at" gorm:"index"` // 租约到期时间
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// InstanceHeartbeat 实例心跳，所有运行中的实例（包括非 Leader）定期更新
+// 用于判断执行日志所属的实例是否仍然存活，以及恢复前检查是否有实例在使用数据库
+type InstanceHeartbeat struct {
+	InstanceID string    `json:"instance_id" gorm:"primaryKey"`
+	StartedAt  time.Time `json:"started_at"`
+	LastSeen   time.Time `json:"last_seen" gorm:"index"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// SystemSetting 系统级键值配置（如调度器暂停状态）
+type SystemSetting struct {
+	Key       string    `json:"key" gorm:"column:setting_key;primaryKey"`
+	Value     string    `json:"value" gorm:"type:text"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+const (
+	// SettingSchedulerPaused 调度器是否全局暂停（"true"/"false"）
+	SettingSchedulerPaused = "scheduler_paused"
+)
@@ -18,6 +18,9 @@ type Task struct {
 	Status              string         `json:"status" gorm:"default:inactive"`         // active, inactive
 	BarkConfig          string         `json:"bark_config" gorm:"type:text"`           // Bark 通知配置 JSON
 	TimeExclusionConfig string         `json:"time_exclusion_config" gorm:"type:text"` // 时间排除配置 JSON
+	Source              string         `json:"source" gorm:"index"`                    // 任务来源：空为界面创建，git 为仓库同步
+	SourcePath          string         `json:"source_path"`                            // 仓库中的任务文件路径（相对同步目录）
+	SourceRevision      string         `json:"source_revision"`                        // 最近一次同步的提交
 	LastRun             *time.Time     `json:"last_run"`
 	NextRun             *time.Time     `json:"next_run"`
 	CreatedAt           time.Time      `json:"created_at"`
@@ -25,6 +28,16 @@ type Task struct {
 	DeletedAt           gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
+// 任务来源
+const (
+	TaskSourceGit = "git" // 由 Git 仓库同步，界面只读
+)
+
+// IsReadOnly 任务是否由外部来源管理，不允许在界面或API中修改
+func (t *Task) IsReadOnly() bool {
+	return t.Source == TaskSourceGit
+}
+
 // TaskLog 任务执行日志模型
 type TaskLog struct {
 	ID        uint      `json:"id" gorm:"primaryKey"`
@@ -37,6 +50,7 @@ type TaskLog struct {
 	Error     string    `json:"error" gorm:"type:text"`
 	Result    string    `json:"result" gorm:"type:text"` // Python 脚本返回的 JSON 结果
 	Duration  int64     `json:"duration"`                // 执行时间（毫秒）
+	Instance  string    `json:"instance" gorm:"index"`   // 执行该任务的实例ID
 	CreatedAt time.Time `json:"created_at"`
 }
 
@@ -64,68 +78,255 @@ type UpdateTaskRequest struct {
 
 // BarkServer Bark服务器配置模型
 type BarkServer struct {
-	ID          uint           `json:"id" gorm:"primaryKey"`
-	Name        string         `json:"name" gorm:"not null"`            // 服务器名称
-	URL         string         `json:"url" gorm:"not null"`             // 服务器地址
-	Description string         `json:"description"`                     // 描述
-	IsDefault   bool           `json:"is_default" gorm:"default:false"` // 是否为默认服务器
-	Status      string         `json:"status" gorm:"default:active"`    // active, inactive
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+	ID          uint   `json:"id" gorm:"primaryKey"`
+	Name        string `json:"name" gorm:"not null"`            // 服务器名称
+	URL         string `json:"url" gorm:"not null"`             // 服务器地址
+	Description string `json:"description"`                     // 描述
+	Timeout     int    `json:"timeout"`                         // 推送请求超时（秒），0 使用全局默认
+	IsDefault   bool   `json:"is_default" gorm:"default:false"` // 是否为默认服务器
+	Status      string `json:"status" gorm:"default:active"`    // active, inactive
+
+	// 服务器上所有设备合计的推送限额，0 不限制
+	RateLimitHour int `json:"rate_limit_hour"` // 每小时最多推送数
+	RateLimitDay  int `json:"rate_limit_day"`  // 每天最多推送数
+
+	// 本服务器不健康时改用的备用服务器，0 表示不转移
+	FallbackServerID uint `json:"fallback_server_id"`
+
+	// 最近一次健康检查的结果，由 Leader 定时更新
+	Health          string     `json:"health" gorm:"default:unknown"` // unknown, healthy, unhealthy
+	HealthLatency   int64      `json:"health_latency_ms"`             // 响应时间（毫秒）
+	HealthError     string     `json:"health_error,omitempty"`        // 失败原因
+	HealthFailures  int        `json:"health_failures"`               // 连续失败次数
+	HealthCheckedAt *time.Time `json:"health_checked_at"`             // 检查时间
+
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
+// Bark 服务器健康状态
+const (
+	ServerHealthUnknown   = "unknown"
+	ServerHealthHealthy   = "healthy"
+	ServerHealthUnhealthy = "unhealthy"
+)
+
 // BarkDevice Bark设备配置模型
 type BarkDevice struct {
-	ID          uint           `json:"id" gorm:"primaryKey"`
-	Name        string         `json:"name" gorm:"not null"`       // 设备名称
-	DeviceKey   string         `json:"device_key" gorm:"not null"` // 设备密钥
-	Description string         `json:"description"`                // 描述
-	ServerID    uint           `json:"server_id"`                  // 关联的服务器ID
-	Server      BarkServer     `json:"server" gorm:"foreignKey:ServerID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
-	IsDefault   bool           `json:"is_default" gorm:"default:false"` // 是否为默认设备
-	Status      string         `json:"status" gorm:"default:active"`    // active, inactive
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	Name        string     `json:"name" gorm:"not null"`       // 设备名称
+	DeviceKey   string     `json:"device_key" gorm:"not null"` // 设备密钥
+	Description string     `json:"description"`                // 描述
+	ServerID    uint       `json:"server_id"`                  // 关联的服务器ID
+	Server      BarkServer `json:"server" gorm:"foreignKey:ServerID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+	IsDefault   bool       `json:"is_default" gorm:"default:false"` // 是否为默认设备
+	Status      string     `json:"status" gorm:"default:active"`    // active, inactive
+
+	// 推送加密配置，EncryptionAlgorithm 为空表示不加密
+	EncryptionAlgorithm string `json:"encryption_algorithm"` // aes128, aes192, aes256
+	EncryptionMode      string `json:"encryption_mode"`      // cbc, ecb, gcm
+	EncryptionKey       string `json:"-"`                    // 加密密钥，不通过接口返回
+	EncryptionIV        string `json:"encryption_iv"`        // 固定 IV，为空时每次推送随机生成
+	HasEncryptionKey    bool   `json:"has_encryption_key" gorm:"-"`
+
+	// 推送限额，0 不限制
+	RateLimitHour int `json:"rate_limit_hour"` // 每小时最多推送数
+	RateLimitDay  int `json:"rate_limit_day"`  // 每天最多推送数
+
+	// 免打扰时段（HH:MM，可跨午夜），期间 critical 以外的通知按 QuietMode 处理
+	QuietStart string `json:"quiet_start"`
+	QuietEnd   string `json:"quiet_end"`
+	QuietMode  string `json:"quiet_mode"` // passive 降级为 passive 立即发送（默认），delay 推迟到时段结束
+
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// Bark 推送加密算法与模式
+const (
+	BarkEncryptionAES128 = "aes128"
+	BarkEncryptionAES192 = "aes192"
+	BarkEncryptionAES256 = "aes256"
+
+	BarkEncryptionCBC = "cbc"
+	BarkEncryptionECB = "ecb"
+	BarkEncryptionGCM = "gcm"
+)
+
+// 免打扰期间的处理方式
+const (
+	QuietPassive = "passive"
+	QuietDelay   = "delay"
+)
+
+// NormalizeQuietHours 未设置免打扰时段时清空处理方式，设置了时段但未指定方式时使用 passive
+func (d *BarkDevice) NormalizeQuietHours() {
+	switch {
+	case d.QuietStart == "" && d.QuietEnd == "":
+		d.QuietMode = ""
+	case d.QuietMode == "":
+		d.QuietMode = QuietPassive
+	}
+}
+
+// Encrypted 设备是否启用了推送加密
+func (d *BarkDevice) Encrypted() bool {
+	return d.EncryptionAlgorithm != ""
+}
+
+// AfterFind 标记是否已配置加密密钥，密钥本身不对外暴露
+func (d *BarkDevice) AfterFind(tx *gorm.DB) error {
+	d.HasEncryptionKey = d.EncryptionKey != ""
+	return nil
 }
 
 // BarkConfig Bark 通知配置结构体
 type BarkConfig struct {
-	DeviceKey         string `json:"device_key"`          // 设备密钥（兼容旧版本）
-	DeviceKeys        string `json:"device_keys"`         // 多设备密钥（逗号分隔，兼容旧版本）
-	SelectedDeviceIds []uint `json:"selected_device_ids"` // 选中的设备ID列表
-	Title             string `json:"title"`               // 通知标题
-	Subtitle          string `json:"subtitle"`            // 通知副标题
-	Body              string `json:"body"`                // 通知内容
-	Level             string `json:"level"`               // 通知级别：active, timeSensitive, passive
-	Volume            string `json:"volume"`              // 音量：0-10
-	Badge             string `json:"badge"`               // 角标数字
-	Call              string `json:"call"`                // 是否开启通话模式：1开启
-	AutoCopy          string `json:"autoCopy"`            // 自动复制：1开启
-	Copy              string `json:"copy"`                // 复制内容
-	Sound             string `json:"sound"`               // 通知铃声
-	Icon              string `json:"icon"`                // 通知图标URL
-	Group             string `json:"group"`               // 通知分组
-	Ciphertext        string `json:"ciphertext"`          // 加密内容
-	IsArchive         string `json:"isArchive"`           // 是否存档：1存档
-	URL               string `json:"url"`                 // 点击通知跳转URL
-	Action            string `json:"action"`              // 自定义动作
-	ID                string `json:"id"`                  // 通知ID
-	Delete            string `json:"delete"`              // 删除通知：1删除
+	DeviceKey          string `json:"device_key"`                     // 设备密钥（兼容旧版本）
+	DeviceKeys         string `json:"device_keys"`                    // 多设备密钥（逗号分隔，兼容旧版本）
+	SelectedDeviceIds  []uint `json:"selected_device_ids"`            // 选中的设备ID列表
+	SelectedGroupIds   []uint `json:"selected_group_ids,omitempty"`   // 选中的设备分组ID列表，与选中的设备合并发送
+	SelectedChannelIds []uint `json:"selected_channel_ids,omitempty"` // 选中的通知渠道ID列表
+	Title              string `json:"title"`                          // 通知标题
+	Subtitle           string `json:"subtitle"`                       // 通知副标题
+	Body               string `json:"body"`                           // 通知内容
+	Level              string `json:"level"`                          // 通知级别：active, timeSensitive, passive
+	Volume             string `json:"volume"`                         // 音量：0-10
+	Badge              string `json:"badge"`                          // 角标数字
+	Call               string `json:"call"`                           // 是否开启通话模式：1开启
+	AutoCopy           string `json:"autoCopy"`                       // 自动复制：1开启
+	Copy               string `json:"copy"`                           // 复制内容
+	Sound              string `json:"sound"`                          // 通知铃声
+	Icon               string `json:"icon"`                           // 通知图标URL
+	Group              string `json:"group"`                          // 通知分组
+	Ciphertext         string `json:"ciphertext"`                     // 加密内容
+	IsArchive          string `json:"isArchive"`                      // 是否存档：1存档
+	URL                string `json:"url"`                            // 点击通知跳转URL
+	Action             string `json:"action"`                         // 自定义动作
+	ID                 string `json:"id"`                             // 通知ID
+	Delete             string `json:"delete"`                         // 删除通知：1删除
 
 	// 去重配置
 	Deduplication BarkDeduplicationConfig `json:"deduplication"` // 去重设置
+
+	// 通知汇总，启用后 Bark 设备和通知渠道的通知先累积，到期后合并为一条发送
+	Digest BarkDigestConfig `json:"digest"`
+
+	// 通知规则，配置后只在规则命中时发送通知
+	Rules []NotificationRule `json:"rules,omitempty"`
+
+	// 设备路由，按顺序匹配，第一条命中的路由的分组代替 SelectedGroupIds
+	Routes []BarkRoute `json:"routes,omitempty"`
+}
+
+// BarkRoute 设备路由：结果满足表达式且通知级别匹配时发送到指定的设备分组
+// Expression 和 Levels 都为空的路由总是命中，可放在最后作为兜底
+type BarkRoute struct {
+	Name       string   `json:"name,omitempty"`       // 路由名称
+	Expression string   `json:"expression,omitempty"` // 结果匹配表达式，语法与通知规则相同
+	Levels     []string `json:"levels,omitempty"`     // 匹配的通知级别，为空时不限
+	GroupIDs   []uint   `json:"group_ids,omitempty"`  // 命中后发送的设备分组
+}
+
+// 通知规则触发条件
+const (
+	RuleOnSuccess             = "success"              // 执行成功
+	RuleOnFailure             = "failure"              // 执行失败（execution_failed 或 script_failed）
+	RuleOnStateChange         = "state_change"         // 成功变为失败，或失败后恢复
+	RuleOnRecovery            = "recovery"             // 失败后恢复成功
+	RuleOnConsecutiveFailures = "consecutive_failures" // 连续失败次数达到 Threshold 时触发一次
+	RuleOnResultMatch         = "result_match"         // 结果字段满足 Expression
+)
+
+// NotificationRule 通知规则
+// 消息字段和目标为空时沿用任务 Bark 配置中的值，每条命中的规则单独发送
+type NotificationRule struct {
+	Name       string `json:"name,omitempty"`       // 规则名称
+	Trigger    string `json:"trigger"`              // 触发条件
+	Threshold  int    `json:"threshold,omitempty"`  // 连续失败次数（consecutive_failures）
+	Expression string `json:"expression,omitempty"` // 结果匹配表达式（result_match），例如 price < 100 && status == "ok"
+
+	Title    string `json:"title,omitempty"`    // 通知标题
+	Subtitle string `json:"subtitle,omitempty"` // 通知副标题
+	Body     string `json:"body,omitempty"`     // 通知内容
+	Level    string `json:"level,omitempty"`    // 通知级别
+	Sound    string `json:"sound,omitempty"`    // 通知铃声
+	Group    string `json:"group,omitempty"`    // 通知分组
+	URL      string `json:"url,omitempty"`      // 点击通知跳转URL
+
+	SelectedDeviceIds  []uint `json:"selected_device_ids,omitempty"`  // 目标设备，为空时使用任务配置
+	SelectedGroupIds   []uint `json:"selected_group_ids,omitempty"`   // 目标设备分组，为空时使用任务配置
+	SelectedChannelIds []uint `json:"selected_channel_ids,omitempty"` // 目标通知渠道，为空时使用任务配置
+}
+
+// Apply 以规则覆盖任务 Bark 配置，返回规则实际使用的配置
+func (r *NotificationRule) Apply(base *BarkConfig) *BarkConfig {
+	config := *base
+	config.Rules = nil
+
+	overrides := []struct {
+		target *string
+		value  string
+	}{
+		{&config.Title, r.Title},
+		{&config.Subtitle, r.Subtitle},
+		{&config.Body, r.Body},
+		{&config.Level, r.Level},
+		{&config.Sound, r.Sound},
+		{&config.Group, r.Group},
+		{&config.URL, r.URL},
+	}
+	for _, o := range overrides {
+		if o.value != "" {
+			*o.target = o.value
+		}
+	}
+
+	// 规则指定了任一目标时只发送到规则的目标，不再使用任务的设备路由
+	if len(r.SelectedDeviceIds) > 0 || len(r.SelectedGroupIds) > 0 || len(r.SelectedChannelIds) > 0 {
+		config.DeviceKey = ""
+		config.DeviceKeys = ""
+		config.SelectedDeviceIds = r.SelectedDeviceIds
+		config.SelectedGroupIds = r.SelectedGroupIds
+		config.SelectedChannelIds = r.SelectedChannelIds
+		config.Routes = nil
+	}
+	return &config
+}
+
+// BarkDigestConfig 通知汇总配置
+type BarkDigestConfig struct {
+	Enabled  bool     `json:"enabled"`            // 是否启用汇总
+	Group    string   `json:"group,omitempty"`    // 汇总分组，相同分组的任务合并为一条；为空时按任务汇总
+	Interval int      `json:"interval,omitempty"` // 汇总间隔（分钟），从第一条通知开始计算
+	Times    []string `json:"times,omitempty"`    // 固定发送时间（HH:MM），设置后忽略 Interval
+	Title    string   `json:"title,omitempty"`    // 汇总标题模板
+	Body     string   `json:"body,omitempty"`     // 汇总内容模板
 }
 
 // BarkDeduplicationConfig Bark去重配置
+// 默认按推送内容去重；设置了 Key 或 Fields 时按其渲染结果去重，结果中的时间戳等变化不影响去重
 type BarkDeduplicationConfig struct {
-	Enabled    bool   `json:"enabled"`     // 是否启用去重
-	Mode       string `json:"mode"`        // 去重模式：recentN, hash, timeWindow
-	RecentN    int    `json:"recent_n"`    // 最近N条记录（recentN模式）
-	TimeWindow int    `json:"time_window"` // 时间窗口，单位分钟（timeWindow模式）
+	Enabled    bool     `json:"enabled"`          // 是否启用去重
+	Mode       string   `json:"mode"`             // 去重模式：recentN, hash, timeWindow, changed, cooldown
+	RecentN    int      `json:"recent_n"`         // 最近N条记录（recentN模式）
+	TimeWindow int      `json:"time_window"`      // 时间窗口，单位分钟（timeWindow模式）
+	Cooldown   int      `json:"cooldown"`         // 冷却时间，单位分钟（cooldown模式）
+	Key        string   `json:"key,omitempty"`    // 去重键模板，与通知字段模板语法相同
+	Fields     []string `json:"fields,omitempty"` // 作为去重键的结果字段，嵌套字段用 a.b
 }
 
+// 去重模式
+const (
+	DedupRecentN    = "recentN"    // 最近N条记录中有相同内容时跳过
+	DedupHash       = "hash"       // 历史中有相同内容时跳过
+	DedupTimeWindow = "timeWindow" // 时间窗口内有相同内容时跳过
+	DedupChanged    = "changed"    // 与上一次发送的内容（或去重键）相同时跳过，只在变化时通知
+	DedupCooldown   = "cooldown"   // 同一去重键发送后冷却时间内不再发送，未设置去重键时按任务冷却
+)
+
 // UnmarshalJSON 自定义JSON解析，支持字符串类型的数字
 func (bdc *BarkDeduplicationConfig) UnmarshalJSON(data []byte) error {
 	// 定义临时结构，字段类型更宽泛
@@ -133,6 +334,7 @@ func (bdc *BarkDeduplicationConfig) UnmarshalJSON(data []byte) error {
 	aux := &struct {
 		RecentN    interface{} `json:"recent_n"`
 		TimeWindow interface{} `json:"time_window"`
+		Cooldown   interface{} `json:"cooldown"`
 		*Alias
 	}{
 		Alias: (*Alias)(bdc),
@@ -174,6 +376,22 @@ func (bdc *BarkDeduplicationConfig) UnmarshalJSON(data []byte) error {
 		bdc.TimeWindow = 60 // 默认值
 	}
 
+	// 处理 Cooldown，支持 string 或 int
+	switch v := aux.Cooldown.(type) {
+	case string:
+		if n, err := strconv.Atoi(v); err == nil {
+			bdc.Cooldown = n
+		} else {
+			bdc.Cooldown = 60 // 默认值
+		}
+	case float64:
+		bdc.Cooldown = int(v)
+	case int:
+		bdc.Cooldown = v
+	default:
+		bdc.Cooldown = 60 // 默认值
+	}
+
 	return nil
 }
 
@@ -183,6 +401,12 @@ type CreateBarkServerRequest struct {
 	URL         string `json:"url" binding:"required"`
 	Description string `json:"description"`
 	IsDefault   bool   `json:"is_default"`
+	Timeout     int    `json:"timeout"`
+
+	RateLimitHour int `json:"rate_limit_hour"`
+	RateLimitDay  int `json:"rate_limit_day"`
+
+	FallbackServerID uint `json:"fallback_server_id"`
 }
 
 // UpdateBarkServerRequest 更新Bark服务器请求
@@ -192,6 +416,13 @@ type UpdateBarkServerRequest struct {
 	Description string `json:"description"`
 	IsDefault   bool   `json:"is_default"`
 	Status      string `json:"status"`
+	Timeout     *int   `json:"timeout"` // 为 nil 时保持不变
+
+	// 限额为 nil 时保持不变
+	RateLimitHour *int `json:"rate_limit_hour"`
+	RateLimitDay  *int `json:"rate_limit_day"`
+
+	FallbackServerID *uint `json:"fallback_server_id"` // 为 nil 时保持不变，0 表示取消
 }
 
 // CreateBarkDeviceRequest 创建Bark设备请求
@@ -201,6 +432,17 @@ type CreateBarkDeviceRequest struct {
 	Description string `json:"description"`
 	ServerID    uint   `json:"server_id"`
 	IsDefault   bool   `json:"is_default"`
+
+	EncryptionAlgorithm string `json:"encryption_algorithm"`
+	EncryptionMode      string `json:"encryption_mode"`
+	EncryptionKey       string `json:"encryption_key"`
+	EncryptionIV        string `json:"encryption_iv"`
+
+	RateLimitHour int    `json:"rate_limit_hour"`
+	RateLimitDay  int    `json:"rate_limit_day"`
+	QuietStart    string `json:"quiet_start"`
+	QuietEnd      string `json:"quiet_end"`
+	QuietMode     string `json:"quiet_mode"`
 }
 
 // UpdateBarkDeviceRequest 更新Bark设备请求
@@ -211,6 +453,19 @@ type UpdateBarkDeviceRequest struct {
 	ServerID    uint   `json:"server_id"`
 	IsDefault   bool   `json:"is_default"`
 	Status      string `json:"status"`
+
+	// 加密配置为 nil 时保持不变；EncryptionAlgorithm 设为空字符串关闭加密
+	EncryptionAlgorithm *string `json:"encryption_algorithm"`
+	EncryptionMode      *string `json:"encryption_mode"`
+	EncryptionKey       *string `json:"encryption_key"`
+	EncryptionIV        *string `json:"encryption_iv"`
+
+	// 限额和免打扰为 nil 时保持不变；QuietStart、QuietEnd 设为空字符串关闭免打扰
+	RateLimitHour *int    `json:"rate_limit_hour"`
+	RateLimitDay  *int    `json:"rate_limit_day"`
+	QuietStart    *string `json:"quiet_start"`
+	QuietEnd      *string `json:"quiet_end"`
+	QuietMode     *string `json:"quiet_mode"`
 }
 
 // GetBarkConfig 解析任务的 Bark 配置
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// APIToken API 访问令牌，供命令行客户端等非浏览器调用使用
+// 只保存令牌的 SHA-256，明文只在创建时返回一次
+type APIToken struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     uint       `json:"user_id" gorm:"not null;index"`
+	Name       string     `json:"name" gorm:"not null"`
+	TokenHash  string     `json:"-" gorm:"size:64;uniqueIndex;not null"`
+	Prefix     string     `json:"prefix"` // 令牌前缀，便于在列表中识别
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  *time.Time `json:"expires_at"` // 为空表示不过期
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreateAPITokenRequest 创建令牌请求
+type CreateAPITokenRequest struct {
+	Name      string `json:"name" binding:"required"`
+	ExpiresIn string `json:"expires_in"` // 有效期，例如 720h；为空表示不过期
+}
@@ -0,0 +1,326 @@
+// Package msgtemplate 通知字段模板
+//
+// 包含 {{ 的字段按 Go text/template 渲染，其余字段保持 $key 占位符替换。模板数据：
+//
+//	.Result  脚本返回的 JSON 结果，嵌套字段用 .Result.a.b，数组用 index .Result.items 0
+//	.Task    任务（.Task.Name、.Task.Description、.Task.CronExpr 等）
+//	.Log     本次执行记录（.Log.Status、.Log.Duration 毫秒、.Log.Error、.Log.StartTime、.Log.EndTime）
+//	.Now     渲染时间
+//
+// 辅助函数见 funcs，例如 {{.Result.price | number 2}}、{{.Log.StartTime | date "01-02 15:04"}}、
+// {{.Result.tags | join ", "}}、{{.Result.message | truncate 50}}、{{.Result.name | default "未知"}}
+package msgtemplate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"autobot/internal/models"
+)
+
+// maxOutput 单个字段渲染结果的最大字节数
+const maxOutput = 16 * 1024
+
+// Data 模板数据
+type Data struct {
+	Result map[string]interface{}
+	Task   *models.Task
+	Log    *models.TaskLog
+	Now    time.Time
+}
+
+// NewData 创建模板数据，task 和 taskLog 可以为 nil
+func NewData(task *models.Task, taskLog *models.TaskLog, result map[string]interface{}) Data {
+	if task == nil {
+		task = &models.Task{}
+	}
+	if taskLog == nil {
+		taskLog = &models.TaskLog{}
+	}
+	if result == nil {
+		result = map[string]interface{}{}
+	}
+	return Data{Result: result, Task: task, Log: taskLog, Now: time.Now()}
+}
+
+// IsTemplate 字段是否为模板
+func IsTemplate(text string) bool {
+	return strings.Contains(text, "{{")
+}
+
+// parse 解析模板
+func parse(text string) (*template.Template, error) {
+	return template.New("field").Funcs(funcs).Option("missingkey=zero").Parse(text)
+}
+
+// Validate 检查模板语法，非模板字段直接通过
+func Validate(text string) error {
+	if !IsTemplate(text) {
+		return nil
+	}
+	_, err := parse(text)
+	return err
+}
+
+// ValidateConfig 检查 Bark 配置及其通知规则中的所有模板字段
+func ValidateConfig(config *models.BarkConfig) error {
+	fields := map[string]string{
+		"title": config.Title, "subtitle": config.Subtitle, "body": config.Body,
+		"level": config.Level, "volume": config.Volume, "badge": config.Badge,
+		"call": config.Call, "autoCopy": config.AutoCopy, "copy": config.Copy,
+		"sound": config.Sound, "icon": config.Icon, "group": config.Group,
+		"ciphertext": config.Ciphertext, "isArchive": config.IsArchive, "url": config.URL,
+		"action": config.Action, "id": config.ID, "delete": config.Delete,
+	}
+	for name, text := range fields {
+		if err := Validate(text); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	for i, rule := range config.Rules {
+		for name, text := range map[string]string{
+			"title": rule.Title, "subtitle": rule.Subtitle, "body": rule.Body,
+			"level": rule.Level, "sound": rule.Sound, "group": rule.Group, "url": rule.URL,
+		} {
+			if err := Validate(text); err != nil {
+				return fmt.Errorf("rule #%d %s: %v", i+1, name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Render 渲染模板字段
+func Render(text string, data Data) (string, error) {
+	return Execute(text, data)
+}
+
+// Execute 使用任意数据渲染模板，辅助函数与通知字段相同（用于通知汇总等）
+func Execute(text string, data interface{}) (string, error) {
+	tmpl, err := parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var out bytes.Buffer
+	if err := tmpl.Execute(&limitedWriter{buf: &out, limit: maxOutput}, data); err != nil {
+		return "", err
+	}
+	// missingkey=zero 对 map[string]interface{} 仍输出 <no value>，不存在的字段按空处理
+	return strings.ReplaceAll(out.String(), "<no value>", ""), nil
+}
+
+// limitedWriter 超过上限后丢弃输出，防止模板生成过大的通知
+type limitedWriter struct {
+	buf   *bytes.Buffer
+	limit int
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if remaining := w.limit - w.buf.Len(); remaining > 0 {
+		if len(p) > remaining {
+			w.buf.Write(p[:remaining])
+		} else {
+			w.buf.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+// funcs 模板辅助函数，参数顺序便于管道使用：{{.Result.x | truncate 20}}
+var funcs = template.FuncMap{
+	"date":     formatDate,
+	"duration": formatDuration,
+	"truncate": truncate,
+	"join":     join,
+	"number":   formatNumber,
+	"default":  defaultValue,
+	"json":     toJSON,
+	"upper":    func(v interface{}) string { return strings.ToUpper(ToString(v)) },
+	"lower":    func(v interface{}) string { return strings.ToLower(ToString(v)) },
+	"trim":     func(v interface{}) string { return strings.TrimSpace(ToString(v)) },
+	"replace":  func(old, new string, v interface{}) string { return strings.ReplaceAll(ToString(v), old, new) },
+}
+
+// formatDate 格式化时间，value 可以是 time.Time、RFC3339 字符串或 Unix 时间戳（秒或毫秒）
+func formatDate(layout string, value interface{}) string {
+	var t time.Time
+	switch v := value.(type) {
+	case time.Time:
+		t = v
+	case *time.Time:
+		if v == nil {
+			return ""
+		}
+		t = *v
+	case string:
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return v
+		}
+		t = parsed
+	default:
+		n, ok := toFloat(value)
+		if !ok {
+			return ToString(value)
+		}
+		if n > 1e12 {
+			t = time.UnixMilli(int64(n))
+		} else {
+			t = time.Unix(int64(n), 0)
+		}
+	}
+	if t.IsZero() {
+		return ""
+	}
+	return t.Local().Format(layout)
+}
+
+// formatDuration 将毫秒数或 time.Duration 格式化为 1m30s 形式
+func formatDuration(value interface{}) string {
+	if d, ok := value.(time.Duration); ok {
+		return d.String()
+	}
+	ms, ok := toFloat(value)
+	if !ok {
+		return ToString(value)
+	}
+	d := time.Duration(ms) * time.Millisecond
+	if d >= time.Second {
+		d = d.Round(100 * time.Millisecond)
+	}
+	return d.String()
+}
+
+// truncate 按字符截断，超出时以 … 结尾
+func truncate(n int, value interface{}) string {
+	runes := []rune(ToString(value))
+	if n <= 0 || len(runes) <= n {
+		return string(runes)
+	}
+	if n == 1 {
+		return "…"
+	}
+	return string(runes[:n-1]) + "…"
+}
+
+// join 连接列表元素
+func join(sep string, value interface{}) string {
+	switch v := value.(type) {
+	case []interface{}:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = ToString(item)
+		}
+		return strings.Join(parts, sep)
+	case []string:
+		return strings.Join(v, sep)
+	default:
+		return ToString(value)
+	}
+}
+
+// formatNumber 保留 decimals 位小数并添加千分位分隔符
+func formatNumber(decimals int, value interface{}) string {
+	n, ok := toFloat(value)
+	if !ok {
+		return ToString(value)
+	}
+	if decimals < 0 {
+		decimals = 0
+	}
+	text := strconv.FormatFloat(math.Abs(n), 'f', decimals, 64)
+	intPart, fracPart, _ := strings.Cut(text, ".")
+
+	var b strings.Builder
+	if n < 0 && strings.Trim(text, "0.") != "" {
+		b.WriteByte('-')
+	}
+	for i, digit := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(digit)
+	}
+	if fracPart != "" {
+		b.WriteByte('.')
+		b.WriteString(fracPart)
+	}
+	return b.String()
+}
+
+// defaultValue 值为空（nil、空字符串、空列表）时返回 def
+func defaultValue(def, value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return def
+	case string:
+		if strings.TrimSpace(v) == "" {
+			return def
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			return def
+		}
+	case map[string]interface{}:
+		if len(v) == 0 {
+			return def
+		}
+	}
+	return value
+}
+
+// toJSON 序列化为 JSON
+func toJSON(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// toFloat 将数字或数字字符串转为 float64
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// ToString 将结果值转为字符串，与 $key 占位符的转换规则一致
+func ToString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float32, float64:
+		return fmt.Sprintf("%g", v)
+	case map[string]interface{}, []interface{}:
+		if data, err := json.Marshal(v); err == nil {
+			return string(data)
+		}
+	}
+	return fmt.Sprintf("%v", value)
+}
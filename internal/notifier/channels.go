@@ -0,0 +1,151 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"autobot/internal/channel"
+	"autobot/internal/database"
+	"autobot/internal/metrics"
+	"autobot/internal/models"
+	"autobot/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+// sendToSelectedChannels 向任务选中的通知渠道发送通知
+// 使用与 Bark 相同的 $placeholder 替换和去重配置，每个渠道单独记录到 Bark 历史
+func (n *Notifier) sendToSelectedChannels(ctx context.Context, barkConfig *models.BarkConfig, result map[string]interface{}, task *models.Task) error {
+	var channels []models.NotificationChannel
+	err := database.WithRetry(func(db *gorm.DB) error {
+		return db.Where("id IN ? AND status = ?", barkConfig.SelectedChannelIds, "active").Find(&channels).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get selected channels: %v", err)
+	}
+
+	if len(channels) == 0 {
+		return fmt.Errorf("no active channels found for selected IDs")
+	}
+
+	msg := channelMessage(n.replacePlaceholders(n.barkConfigToMap(barkConfig), result), task, result)
+
+	var errors []string
+	successCount := 0
+
+	for _, ch := range channels {
+		logger := n.logger.With("channel", ch.Name, "channel_type", ch.Type)
+
+		record := channelRecord(task.ID, ch.ID, msg)
+		n.applyDedupKey(record, &barkConfig.Deduplication, result)
+
+		// 检查是否重复
+		_, dedupSpan := tracing.Start(ctx, "notifier.dedup_check",
+			attribute.String("channel.name", ch.Name),
+			attribute.String("dedup.mode", barkConfig.Deduplication.Mode),
+		)
+		isDuplicate, err := n.historyManager.CheckDuplication(record, &barkConfig.Deduplication)
+		if err != nil {
+			logger.Warn("Failed to check duplication", "error", err)
+			tracing.RecordError(dedupSpan, err)
+		}
+		dedupSpan.SetAttributes(attribute.Bool("dedup.duplicate", isDuplicate))
+		dedupSpan.End()
+
+		if isDuplicate {
+			logger.Info("Skipping duplicate channel notification", "dedup_mode", barkConfig.Deduplication.Mode)
+			record.Status = "skipped"
+			record.ErrorMessage = "Duplicate content detected"
+			n.historyManager.SaveBarkRecord(record)
+			metrics.ChannelSent(ch.Type, record.Status)
+			metrics.DedupSkipped(barkConfig.Deduplication.Mode)
+			continue
+		}
+
+		// 启用汇总时只加入汇总，到期后合并发送
+		if barkConfig.Digest.Enabled {
+			if err := n.queueDigest(record, &barkConfig.Digest); err != nil {
+				errors = append(errors, fmt.Sprintf("渠道 %s: %v", ch.Name, err))
+				logger.Error("Failed to queue channel notification for digest", "error", err)
+				continue
+			}
+			metrics.ChannelSent(ch.Type, record.Status)
+			successCount++
+			continue
+		}
+
+		if err := SendToChannel(ctx, &ch, msg); err != nil {
+			errors = append(errors, fmt.Sprintf("渠道 %s: %v", ch.Name, err))
+			logger.Warn("Channel notification failed", "error", err)
+			record.Status = "failed"
+			record.ErrorMessage = err.Error()
+		} else {
+			successCount++
+			logger.Info("Channel notification sent")
+			record.Status = "success"
+		}
+
+		n.historyManager.SaveBarkRecord(record)
+		metrics.ChannelSent(ch.Type, record.Status)
+	}
+
+	if len(errors) > 0 {
+		if successCount > 0 {
+			n.logger.Warn("Channel notification partially failed", "succeeded", successCount, "channels", len(channels))
+			return fmt.Errorf("partial failure: %s", strings.Join(errors, "; "))
+		}
+		return fmt.Errorf("all channels failed: %s", strings.Join(errors, "; "))
+	}
+
+	return nil
+}
+
+// channelMessage 由替换后的配置创建渠道消息
+func channelMessage(config map[string]string, task *models.Task, result map[string]interface{}) channel.Message {
+	return channel.Message{
+		Title:    config["title"],
+		Subtitle: config["subtitle"],
+		Body:     config["body"],
+		URL:      config["url"],
+		Level:    config["level"],
+		Group:    config["group"],
+		TaskID:   task.ID,
+		TaskName: task.Name,
+		Result:   result,
+	}
+}
+
+// channelRecord 创建渠道发送记录，用于去重检查和保存
+func channelRecord(taskID, channelID uint, msg channel.Message) *models.BarkRecord {
+	record := models.CreateBarkRecordFromConfig(taskID, "", &models.BarkConfig{
+		Title:    msg.Title,
+		Subtitle: msg.Subtitle,
+		Body:     msg.Body,
+		Level:    msg.Level,
+		Group:    msg.Group,
+		URL:      msg.URL,
+	}, "", "", "")
+	record.ChannelID = channelID
+	record.GenerateContentHash()
+	return record
+}
+
+// SendToChannel 通过单个渠道发送消息
+func SendToChannel(ctx context.Context, ch *models.NotificationChannel, msg channel.Message) (err error) {
+	ctx, span := tracing.Start(ctx, "channel.send",
+		attribute.String("channel.type", ch.Type),
+		attribute.String("channel.name", ch.Name),
+	)
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
+	sender, err := channel.New(ch.Type, ch.Config)
+	if err != nil {
+		return err
+	}
+	return sender.Send(ctx, msg)
+}
@@ -0,0 +1,38 @@
+package notifier
+
+import (
+	"strings"
+
+	"autobot/internal/models"
+	"autobot/internal/rules"
+)
+
+// applyDedupKey 按去重配置设置记录的去重键
+// 配置了去重键模板或结果字段时使用其渲染结果；cooldown 模式未配置时按任务和目标冷却；
+// 其余情况保持内容hash
+func (n *Notifier) applyDedupKey(record *models.BarkRecord, dedup *models.BarkDeduplicationConfig, result map[string]interface{}) {
+	if !dedup.Enabled {
+		return
+	}
+	if dedup.Key == "" && len(dedup.Fields) == 0 {
+		if dedup.Mode == models.DedupCooldown {
+			record.GenerateDedupKey("")
+		}
+		return
+	}
+	record.GenerateDedupKey(n.dedupValue(dedup, result))
+}
+
+// dedupValue 渲染去重键模板并拼接结果字段的值
+func (n *Notifier) dedupValue(dedup *models.BarkDeduplicationConfig, result map[string]interface{}) string {
+	var parts []string
+	if dedup.Key != "" {
+		// 与通知字段相同，支持 {{模板}} 和 $key 占位符
+		parts = append(parts, n.replacePlaceholders(map[string]string{"key": dedup.Key}, result)["key"])
+	}
+	for _, field := range dedup.Fields {
+		field = strings.TrimSpace(field)
+		parts = append(parts, field+"="+n.interfaceToString(rules.Lookup(result, field)))
+	}
+	return strings.Join(parts, "\n")
+}
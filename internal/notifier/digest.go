@@ -0,0 +1,260 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"autobot/internal/channel"
+	"autobot/internal/database"
+	"autobot/internal/digest"
+	"autobot/internal/metrics"
+	"autobot/internal/models"
+	"autobot/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+// 启用汇总的任务不立即发送：通过去重检查的通知保存为 queued 记录，
+// 同一汇总分组和目标（设备或渠道）的记录共用一个发送时间，到期后由 OutboxWorker
+// 合并为一条汇总消息。汇总消息本身也是一条发送记录，Bark 设备的汇总经投递队列发送，
+// 被汇总的记录改为 digested 并通过 digest_id 关联汇总消息。
+
+// errDigestTaken 汇总已被其他实例发送
+var errDigestTaken = errors.New("digest already sent")
+
+// digestTarget 一个待发送的汇总：分组加目标
+type digestTarget struct {
+	DigestKey string
+	DeviceKey string
+	ChannelID uint
+}
+
+// queueDigest 将通过去重检查的记录加入汇总，同一分组和目标已有等待中的汇总时沿用其发送时间
+func (n *Notifier) queueDigest(record *models.BarkRecord, config *models.BarkDigestConfig) error {
+	key := digest.Key(config, record.TaskID)
+	dueAt := digest.NextAt(config, time.Now())
+
+	var existing []models.BarkRecord
+	err := database.WithRetry(func(db *gorm.DB) error {
+		return db.Where("status = ? AND digest_key = ? AND device_key = ? AND channel_id = ?",
+			models.RecordQueued, key, record.DeviceKey, record.ChannelID).
+			Order("digest_at asc").
+			Limit(1).
+			Find(&existing).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get queued digest: %v", err)
+	}
+	if len(existing) > 0 && existing[0].DigestAt != nil {
+		dueAt = *existing[0].DigestAt
+	}
+
+	record.Status = models.RecordQueued
+	record.DigestKey = key
+	record.DigestAt = &dueAt
+	if err := n.historyManager.SaveBarkRecord(record); err != nil {
+		return fmt.Errorf("failed to queue notification for digest: %v", err)
+	}
+	n.logger.Info("Notification queued for digest", "digest", key, "digest_at", dueAt)
+	return nil
+}
+
+// flushDigests 发送所有到期的汇总
+func (n *Notifier) flushDigests(ctx context.Context) {
+	var targets []digestTarget
+	err := database.WithRetry(func(db *gorm.DB) error {
+		return db.Model(&models.BarkRecord{}).
+			Select("digest_key, device_key, channel_id").
+			Where("status = ? AND digest_at <= ?", models.RecordQueued, time.Now()).
+			Group("digest_key, device_key, channel_id").
+			Scan(&targets).Error
+	})
+	if err != nil {
+		n.logger.Error("Failed to load due digests", "error", err)
+		return
+	}
+
+	for _, target := range targets {
+		if ctx.Err() != nil {
+			return
+		}
+		logger := n.logger.With("digest", target.DigestKey)
+		spanCtx, span := tracing.Start(ctx, "notifier.digest",
+			attribute.String("digest.key", target.DigestKey),
+			attribute.Int64("digest.channel_id", int64(target.ChannelID)),
+		)
+		err := n.sendDigest(spanCtx, target, logger)
+		if errors.Is(err, errDigestTaken) {
+			err = nil
+		}
+		if err != nil {
+			logger.Warn("Failed to send digest", "error", err)
+		}
+		tracing.RecordError(span, err)
+		span.End()
+	}
+}
+
+// sendDigest 合并一个分组和目标下等待中的记录并发送
+func (n *Notifier) sendDigest(ctx context.Context, target digestTarget, logger *slog.Logger) error {
+	var items []models.BarkRecord
+	err := database.WithRetry(func(db *gorm.DB) error {
+		return db.Where("status = ? AND digest_key = ? AND device_key = ? AND channel_id = ?",
+			models.RecordQueued, target.DigestKey, target.DeviceKey, target.ChannelID).
+			Order("created_at asc").
+			Find(&items).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get queued notifications: %v", err)
+	}
+	if len(items) == 0 {
+		return nil
+	}
+
+	latest := items[len(items)-1]
+	config, taskNames := n.digestConfig(items)
+	data := make([]digest.Item, 0, len(items))
+	for _, item := range items {
+		data = append(data, digest.Item{
+			TaskID:   item.TaskID,
+			TaskName: taskNames[item.TaskID],
+			Title:    item.Title,
+			Subtitle: item.Subtitle,
+			Body:     item.Body,
+			URL:      item.URL,
+			Time:     item.CreatedAt,
+		})
+	}
+	title, body, err := digest.Render(config, digest.NewData(config.Group, data))
+	if err != nil {
+		// 模板在保存时已校验，渲染出错时退回默认模板，避免汇总一直发不出去
+		logger.Warn("Failed to render digest template, using default", "error", err)
+		title, body, _ = digest.Render(&models.BarkDigestConfig{}, digest.NewData(config.Group, data))
+	}
+
+	// 其余推送参数沿用最新一条通知
+	pushConfig := latest.BarkConfig()
+	pushConfig.Title, pushConfig.Subtitle, pushConfig.Body = title, "", body
+	pushConfig.URL, pushConfig.Copy, pushConfig.ID, pushConfig.Delete = "", "", "", ""
+	record := models.CreateBarkRecordFromConfig(0, target.DeviceKey, pushConfig, "pending", "", "")
+	record.ChannelID = target.ChannelID
+	record.DigestKey = target.DigestKey
+	record.DigestCount = len(items)
+	record.GenerateContentHash()
+
+	if err := n.claimDigest(record, items); err != nil {
+		return err
+	}
+	logger.Info("Sending digest", "notifications", len(items), "record_id", record.ID)
+
+	if target.ChannelID != 0 {
+		return n.sendChannelDigest(ctx, record, logger)
+	}
+
+	// 汇总不计入推送限额，但遵守设备的免打扰时段
+	configMap := n.barkConfigToMap(record.BarkConfig())
+	_, device, _ := n.getBarkServer(target.DeviceKey)
+	deliverAt, delayed := applyQuietHours(device, configMap, logger)
+	if !delayed {
+		deliverAt = time.Now()
+	}
+	entry, err := n.enqueueAt(record, configMap, deliverAt)
+	if err != nil {
+		n.updateRecord(record.ID, "failed", err.Error())
+		metrics.BarkSent("failed")
+		return err
+	}
+	if delayed {
+		return nil
+	}
+	return n.deliver(ctx, entry, logger)
+}
+
+// claimDigest 保存汇总消息的记录并关联被汇总的记录，记录已被其他实例处理时放弃
+func (n *Notifier) claimDigest(record *models.BarkRecord, items []models.BarkRecord) error {
+	ids := make([]uint, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+
+	return database.WithRetry(func(db *gorm.DB) error {
+		return db.Transaction(func(tx *gorm.DB) error {
+			record.ID = 0
+			if err := tx.Create(record).Error; err != nil {
+				return err
+			}
+			result := tx.Model(&models.BarkRecord{}).
+				Where("id IN ? AND status = ?", ids, models.RecordQueued).
+				Updates(map[string]interface{}{"status": models.RecordDigested, "digest_id": record.ID})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected != int64(len(ids)) {
+				return errDigestTaken
+			}
+			return nil
+		})
+	})
+}
+
+// sendChannelDigest 通过通知渠道发送汇总消息
+func (n *Notifier) sendChannelDigest(ctx context.Context, record *models.BarkRecord, logger *slog.Logger) error {
+	var ch models.NotificationChannel
+	err := database.WithRetry(func(db *gorm.DB) error {
+		return db.First(&ch, record.ChannelID).Error
+	})
+	if err != nil {
+		n.updateRecord(record.ID, "failed", "channel not found")
+		return fmt.Errorf("failed to get channel %d: %v", record.ChannelID, err)
+	}
+
+	msg := channel.Message{
+		Title: record.Title,
+		Body:  record.Body,
+		Level: record.Level,
+		Group: record.Group,
+	}
+	status, errorMessage := "success", ""
+	if err = SendToChannel(ctx, &ch, msg); err != nil {
+		status, errorMessage = "failed", err.Error()
+		logger.Warn("Channel digest failed", "channel", ch.Name, "error", err)
+	}
+	n.updateRecord(record.ID, status, errorMessage)
+	metrics.ChannelSent(ch.Type, status)
+	return err
+}
+
+// digestConfig 汇总使用最新一条通知所属任务的汇总配置，同时返回涉及的任务名称
+// 任务已被删除时使用默认配置
+func (n *Notifier) digestConfig(items []models.BarkRecord) (*models.BarkDigestConfig, map[uint]string) {
+	taskIDs := make([]uint, 0, len(items))
+	for _, item := range items {
+		taskIDs = append(taskIDs, item.TaskID)
+	}
+
+	var tasks []models.Task
+	err := database.WithRetry(func(db *gorm.DB) error {
+		return db.Where("id IN ?", taskIDs).Find(&tasks).Error
+	})
+	if err != nil {
+		n.logger.Warn("Failed to get digest tasks", "error", err)
+	}
+
+	names := make(map[uint]string, len(tasks))
+	config := &models.BarkDigestConfig{}
+	latestTaskID := items[len(items)-1].TaskID
+	for _, task := range tasks {
+		names[task.ID] = task.Name
+		if task.ID != latestTaskID {
+			continue
+		}
+		if barkConfig, err := task.GetBarkConfig(); err == nil {
+			config = &barkConfig.Digest
+		}
+	}
+	return config, names
+}
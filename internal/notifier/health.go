@@ -0,0 +1,236 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"autobot/internal/database"
+	"autobot/internal/metrics"
+	"autobot/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Bark 服务器健康检查：HealthChecker 在 Leader 上定时请求每个启用服务器的 /ping（不支持时再试 /healthz），
+// 结果写回 bark_servers。连续失败达到阈值后标记为 unhealthy，选择服务器时跳过不健康的服务器，
+// 沿备用服务器链改用健康（或尚未检查）的服务器；没有可用的备用服务器时仍使用原服务器。
+
+// 健康检查参数，由 SetHealthOptions 按配置设置
+var (
+	healthInterval  = time.Minute
+	healthThreshold = 2
+)
+
+// HealthOptions Bark 服务器健康检查参数
+type HealthOptions struct {
+	Interval  time.Duration // 检查间隔，0 表示不做定时检查
+	Threshold int           // 连续失败多少次后标记为不健康
+}
+
+// SetHealthOptions 设置健康检查参数，Threshold 为 0 时保持默认
+func SetHealthOptions(opts HealthOptions) {
+	healthInterval = opts.Interval
+	if opts.Threshold > 0 {
+		healthThreshold = opts.Threshold
+	}
+}
+
+// serverTimeout 服务器未单独设置超时时使用全局默认值
+func serverTimeout(server *models.BarkServer) time.Duration {
+	if server.Timeout > 0 {
+		return time.Duration(server.Timeout) * time.Second
+	}
+	return defaultTimeout
+}
+
+// pingBarkServer 请求服务器的 /ping，返回 404 时再请求 /healthz，返回响应时间
+func (n *Notifier) pingBarkServer(ctx context.Context, server *models.BarkServer) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, serverTimeout(server))
+	defer cancel()
+
+	base := strings.TrimSuffix(strings.TrimSuffix(server.URL, "/"), "/push")
+	start := time.Now()
+	var lastErr error
+	for _, path := range []string{"/ping", "/healthz"} {
+		req, err := http.NewRequestWithContext(ctx, "GET", base+path, nil)
+		if err != nil {
+			return 0, fmt.Errorf("invalid server url: %v", err)
+		}
+		resp, err := n.client.Do(req)
+		if err != nil {
+			return 0, err
+		}
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			return time.Since(start), nil
+		}
+		lastErr = fmt.Errorf("%s returned status %d", path, resp.StatusCode)
+		if resp.StatusCode != http.StatusNotFound {
+			break
+		}
+	}
+	return 0, lastErr
+}
+
+// CheckBarkServer 检查一个服务器并保存结果，返回更新后的服务器；只有保存失败时返回错误
+func CheckBarkServer(ctx context.Context, server *models.BarkServer) (*models.BarkServer, error) {
+	n := New(database.GetDB())
+	latency, pingErr := n.pingBarkServer(ctx, server)
+
+	now := time.Now()
+	previous := server.Health
+	updates := map[string]interface{}{
+		"health_checked_at": now,
+	}
+	if pingErr == nil {
+		server.Health = models.ServerHealthHealthy
+		server.HealthLatency = latency.Milliseconds()
+		server.HealthError = ""
+		server.HealthFailures = 0
+	} else {
+		server.HealthFailures++
+		server.HealthError = pingErr.Error()
+		if server.HealthFailures >= healthThreshold {
+			server.Health = models.ServerHealthUnhealthy
+		}
+	}
+	server.HealthCheckedAt = &now
+	updates["health"] = server.Health
+	updates["health_latency"] = server.HealthLatency
+	updates["health_error"] = server.HealthError
+	updates["health_failures"] = server.HealthFailures
+
+	err := database.WithRetry(func(db *gorm.DB) error {
+		return db.Model(&models.BarkServer{}).Where("id = ?", server.ID).Updates(updates).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save server health: %v", err)
+	}
+
+	metrics.BarkServerHealth(server.Name, server.Health == models.ServerHealthHealthy, latency)
+	switch {
+	case server.Health == models.ServerHealthUnhealthy && previous != models.ServerHealthUnhealthy:
+		n.logger.Warn("Bark server is unhealthy", "server", server.Name, "failures", server.HealthFailures, "error", pingErr)
+	case server.Health == models.ServerHealthHealthy && previous == models.ServerHealthUnhealthy:
+		n.logger.Info("Bark server recovered", "server", server.Name, "latency_ms", server.HealthLatency)
+	}
+	return server, nil
+}
+
+// failover 返回实际发送使用的服务器，改用备用服务器时记录日志和指标
+func (n *Notifier) failover(server *models.BarkServer) *models.BarkServer {
+	current := fallbackServer(server)
+	if current.ID != server.ID {
+		n.logger.Info("Bark server is unhealthy, failing over", "server", server.Name, "fallback", current.Name)
+		metrics.BarkFailover()
+	}
+	return current
+}
+
+// fallbackServer 服务器不健康时沿备用服务器链查找健康或尚未检查的启用服务器，找不到时返回原服务器
+func fallbackServer(server *models.BarkServer) *models.BarkServer {
+	current := server
+	seen := map[uint]bool{server.ID: true}
+	for current.Health == models.ServerHealthUnhealthy && current.FallbackServerID != 0 && !seen[current.FallbackServerID] {
+		seen[current.FallbackServerID] = true
+		var next models.BarkServer
+		err := database.WithRetry(func(db *gorm.DB) error {
+			return db.Where("status = 'active'").First(&next, current.FallbackServerID).Error
+		})
+		if err != nil {
+			break
+		}
+		current = &next
+	}
+
+	if current.Health == models.ServerHealthUnhealthy {
+		return server
+	}
+	return current
+}
+
+// HealthChecker 定时检查所有启用的 Bark 服务器
+// 多实例部署时只在 Leader 上检查，结果保存在数据库中供所有实例选择服务器
+type HealthChecker struct {
+	isLeader func() bool
+
+	mutex  sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewHealthChecker 创建健康检查器，isLeader 为 nil 时总是检查
+func NewHealthChecker(isLeader func() bool) *HealthChecker {
+	return &HealthChecker{isLeader: isLeader}
+}
+
+// Start 启动定时检查，检查间隔为 0 时不启动
+func (h *HealthChecker) Start() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.cancel != nil || healthInterval <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	h.cancel = cancel
+	h.done = make(chan struct{})
+
+	go func() {
+		defer close(h.done)
+		ticker := time.NewTicker(healthInterval)
+		defer ticker.Stop()
+
+		for {
+			if h.isLeader == nil || h.isLeader() {
+				h.checkAll(ctx)
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止定时检查，等待正在进行的检查结束
+func (h *HealthChecker) Stop() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.cancel == nil {
+		return
+	}
+	h.cancel()
+	<-h.done
+	h.cancel = nil
+}
+
+// checkAll 并发检查所有启用的服务器
+func (h *HealthChecker) checkAll(ctx context.Context) {
+	var servers []models.BarkServer
+	err := database.WithRetry(func(db *gorm.DB) error {
+		return db.Where("status = 'active'").Find(&servers).Error
+	})
+	if err != nil {
+		slog.Error("Failed to load Bark servers for health check", "error", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for i := range servers {
+		wg.Add(1)
+		go func(server *models.BarkServer) {
+			defer wg.Done()
+			if _, err := CheckBarkServer(ctx, server); err != nil {
+				slog.Error("Failed to check Bark server", "server", server.Name, "error", err)
+			}
+		}(&servers[i])
+	}
+	wg.Wait()
+}
@@ -0,0 +1,59 @@
+package notifier
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"autobot/internal/models"
+	"autobot/internal/throttle"
+)
+
+// limiter 按设备和服务器的推送令牌桶，任务通知经过限流，测试推送、重发和汇总消息不计入
+var limiter = throttle.New()
+
+// checkRateLimit 检查设备和所在服务器的推送限额，通过时扣除令牌，超限时返回超出的限额
+// server 为 nil 时只检查设备
+func checkRateLimit(device *models.BarkDevice, server *models.BarkServer) (bool, string) {
+	deviceKey := "device:" + device.DeviceKey
+	deviceName := "device " + device.Name
+	limits := []throttle.Limit{
+		throttle.PerHour(deviceKey, deviceName, device.RateLimitHour),
+		throttle.PerDay(deviceKey, deviceName, device.RateLimitDay),
+	}
+	if server != nil {
+		serverKey := "server:" + strconv.FormatUint(uint64(server.ID), 10)
+		serverName := "server " + server.Name
+		limits = append(limits,
+			throttle.PerHour(serverKey, serverName, server.RateLimitHour),
+			throttle.PerDay(serverKey, serverName, server.RateLimitDay),
+		)
+	}
+
+	ok, limit := limiter.Allow(time.Now(), limits...)
+	if ok {
+		return true, ""
+	}
+	return false, fmt.Sprintf("%s (%d) exceeded", limit.Name, limit.Max)
+}
+
+// applyQuietHours 设备处于免打扰时段时处理 critical 以外的通知：
+// passive 模式把 config 的 level 改为 passive，delay 模式返回时段结束时间
+func applyQuietHours(device *models.BarkDevice, config map[string]string, logger *slog.Logger) (time.Time, bool) {
+	if device == nil || config["level"] == "critical" {
+		return time.Time{}, false
+	}
+	until, quiet := throttle.QuietUntil(device.QuietStart, device.QuietEnd, time.Now())
+	if !quiet {
+		return time.Time{}, false
+	}
+
+	if device.QuietMode == models.QuietDelay {
+		logger.Info("Device in quiet hours, delaying Bark notification", "until", until)
+		return until, true
+	}
+	config["level"] = "passive"
+	logger.Debug("Device in quiet hours, downgrading Bark notification to passive")
+	return time.Time{}, false
+}
@@ -2,17 +2,26 @@ package notifier
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"regexp"
+	"sort"
 	"strings"
+	"time"
 
+	"autobot/internal/barkcrypto"
 	"autobot/internal/barkhistory"
 	"autobot/internal/database"
+	"autobot/internal/metrics"
 	"autobot/internal/models"
+	"autobot/internal/msgtemplate"
+	"autobot/internal/tracing"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
 	"gorm.io/gorm"
 )
 
@@ -23,6 +32,11 @@ type Notifier struct {
 	client         *http.Client
 	db             *gorm.DB
 	historyManager *barkhistory.BarkHistoryManager
+	logger         *slog.Logger
+
+	// 本次通知对应的任务和执行记录，供模板字段使用
+	task    *models.Task
+	taskLog *models.TaskLog
 }
 
 // New creates a new Notifier instance
@@ -31,32 +45,55 @@ func New(db *gorm.DB) *Notifier {
 		client:         &http.Client{},
 		db:             db,
 		historyManager: barkhistory.NewBarkHistoryManager(),
+		logger:         slog.Default(),
 	}
 }
 
+// WithLogger 使用带上下文属性（task_id、log_id 等）的 logger
+func (n *Notifier) WithLogger(logger *slog.Logger) *Notifier {
+	n.logger = logger
+	return n
+}
+
 // SendBark sends a Bark notification with the given configuration
 // config: map[string]string containing Bark parameters
-func (n *Notifier) SendBark(config map[string]string) error {
+func (n *Notifier) SendBark(ctx context.Context, config map[string]string) (err error) {
+	ctx, span := tracing.Start(ctx, "bark.push", attribute.Bool("bark.multi_device", config["device_key"] == ""))
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
 	// 验证必需的参数
 	if config["device_key"] == "" && config["device_keys"] == "" {
 		return fmt.Errorf("device_key or device_keys is required for Bark notification")
 	}
 
-	// 获取Bark服务器URL
-	var barkURL string
-	var err error
+	// 获取Bark服务器
+	var server *models.BarkServer
+	var device *models.BarkDevice
 	if config["device_key"] != "" {
-		barkURL, err = n.getBarkServerURL(config["device_key"])
+		server, device, err = n.getBarkServer(config["device_key"])
 		if err != nil {
 			return err
 		}
 	} else {
-		// 对于多设备，使用默认服务器URL
-		barkURL, err = n.getDefaultBarkServerURL()
+		// 多设备共用同一请求体，无法按设备加密，拒绝以明文发往加密设备
+		if err := n.checkUnencryptedDevices(config["device_keys"]); err != nil {
+			return err
+		}
+		// 对于多设备，使用默认服务器
+		server, err = n.getDefaultBarkServer()
 		if err != nil {
 			return err
 		}
 	}
+	// 服务器不健康时改用其备用服务器
+	server = n.failover(server)
+	barkURL := server.URL
+
+	ctx, cancel := context.WithTimeout(ctx, serverTimeout(server))
+	defer cancel()
 
 	// 构建请求体
 	payload := make(map[string]interface{})
@@ -68,6 +105,14 @@ func (n *Notifier) SendBark(config map[string]string) error {
 		}
 	}
 
+	// 设备启用加密时，内容字段只以密文发出
+	if device != nil && device.Encrypted() {
+		if err := barkcrypto.Encrypt(device, payload); err != nil {
+			return err
+		}
+		span.SetAttributes(attribute.Bool("bark.encrypted", true))
+	}
+
 	// 序列化为 JSON
 	jsonData, err := json.Marshal(payload)
 	if err != nil {
@@ -82,13 +127,18 @@ func (n *Notifier) SendBark(config map[string]string) error {
 		barkURL = barkURL + "/push"
 	}
 
-	req, err := http.NewRequest("POST", barkURL, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", barkURL, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return fmt.Errorf("failed to create Bark request: %v", err)
 	}
+	span.SetAttributes(
+		attribute.String("http.request.method", req.Method),
+		attribute.String("server.address", req.URL.Host),
+	)
 
 	// 设置请求头
 	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	tracing.Inject(ctx, propagation.HeaderCarrier(req.Header))
 
 	// 发送请求
 	resp, err := n.client.Do(req)
@@ -96,6 +146,7 @@ func (n *Notifier) SendBark(config map[string]string) error {
 		return fmt.Errorf("failed to send Bark notification: %v", err)
 	}
 	defer resp.Body.Close()
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
 
 	// 检查响应状态
 	if resp.StatusCode != http.StatusOK {
@@ -105,36 +156,58 @@ func (n *Notifier) SendBark(config map[string]string) error {
 	return nil
 }
 
-// getBarkServerURL 根据设备密钥获取对应的Bark服务器URL
-func (n *Notifier) getBarkServerURL(deviceKey string) (string, error) {
+// getBarkServer 根据设备密钥获取对应的Bark服务器，设备已登记时一并返回设备
+func (n *Notifier) getBarkServer(deviceKey string) (*models.BarkServer, *models.BarkDevice, error) {
 	// 查找设备对应的服务器 - 使用重试机制
 	var device models.BarkDevice
+	var found *models.BarkDevice
 	err := database.WithRetry(func(db *gorm.DB) error {
 		return db.Preload("Server").Where("device_key = ? AND status = 'active'", deviceKey).First(&device).Error
 	})
 
 	if err == nil {
+		found = &device
 		if device.Server.URL != "" && device.Server.Status == "active" {
-			return device.Server.URL, nil
+			return &device.Server, found, nil
 		}
 	}
 
-	// 如果没找到设备配置，尝试获取默认服务器 - 使用重试机制
-	var defaultServer models.BarkServer
-	err = database.WithRetry(func(db *gorm.DB) error {
-		return db.Where("is_default = true AND status = 'active'").First(&defaultServer).Error
-	})
+	// 如果没找到设备配置，尝试获取默认服务器
+	server, err := n.getDefaultBarkServer()
+	if err != nil {
+		// 如果都没找到，返回错误
+		return nil, nil, fmt.Errorf("no Bark server configuration found for device %s. Please configure a Bark server in the Bark management page", deviceKey)
+	}
+	return server, found, nil
+}
 
-	if err == nil {
-		return defaultServer.URL, nil
+// checkUnencryptedDevices 确认 device_keys 中没有启用加密的设备
+func (n *Notifier) checkUnencryptedDevices(deviceKeys string) error {
+	var keys []string
+	for _, key := range strings.Split(deviceKeys, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
 	}
 
-	// 如果都没找到，返回错误
-	return "", fmt.Errorf("no Bark server configuration found for device %s. Please configure a Bark server in the Bark management page", deviceKey)
+	var devices []models.BarkDevice
+	err := database.WithRetry(func(db *gorm.DB) error {
+		return db.Where("device_key IN ? AND encryption_algorithm <> ''", keys).Find(&devices).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to check device encryption: %v", err)
+	}
+	if len(devices) > 0 {
+		return fmt.Errorf("device %s requires encryption and cannot be pushed via device_keys", devices[0].Name)
+	}
+	return nil
 }
 
-// getDefaultBarkServerURL 获取默认的Bark服务器URL
-func (n *Notifier) getDefaultBarkServerURL() (string, error) {
+// getDefaultBarkServer 获取默认的Bark服务器
+func (n *Notifier) getDefaultBarkServer() (*models.BarkServer, error) {
 	// 尝试获取默认服务器 - 使用重试机制
 	var defaultServer models.BarkServer
 	err := database.WithRetry(func(db *gorm.DB) error {
@@ -142,19 +215,25 @@ func (n *Notifier) getDefaultBarkServerURL() (string, error) {
 	})
 
 	if err == nil {
-		return defaultServer.URL, nil
+		return &defaultServer, nil
 	}
 
 	// 如果没找到默认服务器，返回错误
-	return "", fmt.Errorf("no default Bark server configuration found. Please configure a default Bark server in the Bark management page")
+	return nil, fmt.Errorf("no default Bark server configuration found. Please configure a default Bark server in the Bark management page")
 }
 
 // ProcessBarkNotification processes and sends Bark notification for a task
 // task: the task that was executed
-func (n *Notifier) ProcessBarkNotification(task *models.Task) error {
+func (n *Notifier) ProcessBarkNotification(ctx context.Context, task *models.Task) (err error) {
+	ctx, span := tracing.Start(ctx, "notifier.process_bark", attribute.Int64("task.id", int64(task.ID)))
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
 	// 从数据库重新获取最新的任务配置，确保配置是最新的 - 使用重试机制
 	var latestTask models.Task
-	err := database.WithRetry(func(db *gorm.DB) error {
+	err = database.WithRetry(func(db *gorm.DB) error {
 		return db.First(&latestTask, task.ID).Error
 	})
 	if err != nil {
@@ -167,44 +246,71 @@ func (n *Notifier) ProcessBarkNotification(task *models.Task) error {
 		return fmt.Errorf("failed to parse Bark config: %v", err)
 	}
 
-	// 检查是否有设备配置
-	hasDeviceConfig := false
-	if len(barkConfig.SelectedDeviceIds) > 0 {
-		hasDeviceConfig = true
-	} else if barkConfig.DeviceKey != "" {
-		hasDeviceConfig = true
+	// 配置了通知规则时按规则发送
+	if len(barkConfig.Rules) > 0 {
+		return n.processRules(ctx, &latestTask, barkConfig)
 	}
 
-	if !hasDeviceConfig {
-		log.Printf("No Bark device configuration found for task %d, skipping notification", task.ID)
+	// 检查是否有设备配置
+	if !hasTargets(barkConfig) {
+		n.logger.Debug("No Bark device configured, skipping notification")
+		span.SetAttributes(attribute.String("notifier.skipped", "no_device"))
 		return nil
 	}
 
 	// 从最新执行记录中获取 result
-	result, err := n.getLatestTaskResult(task.ID)
+	taskLog, err := n.getLatestTaskLog(task.ID)
 	if err != nil {
-		log.Printf("Failed to get result for task %d: %v", task.ID, err)
+		n.logger.Warn("Failed to get task result for notification", "error", err)
 		return nil
 	}
+	result := n.parseLogResult(taskLog)
+	n.task, n.taskLog = &latestTask, taskLog
 
 	// 新的过滤逻辑：验证占位符
 	if !n.validatePlaceholders(barkConfig, result) {
-		log.Printf("Placeholder validation failed for task %d, skipping notification", task.ID)
+		n.logger.Info("Placeholder validation failed, skipping notification")
+		span.SetAttributes(attribute.String("notifier.skipped", "placeholders"))
 		return nil
 	}
 
-	log.Printf("Placeholder validation passed for task %d, proceeding with notification", task.ID)
+	n.logger.Debug("Placeholder validation passed, proceeding with notification")
+
+	return n.dispatch(ctx, barkConfig, result, &latestTask)
+}
 
-	// 根据设备配置发送通知
-	if len(barkConfig.SelectedDeviceIds) > 0 {
+// hasTargets 配置中是否有设备或通知渠道
+func hasTargets(barkConfig *models.BarkConfig) bool {
+	return selectsDevices(barkConfig) || barkConfig.DeviceKey != "" || len(barkConfig.SelectedChannelIds) > 0
+}
+
+// dispatch 根据设备配置发送通知，Bark 设备和其他渠道互不影响
+func (n *Notifier) dispatch(ctx context.Context, barkConfig *models.BarkConfig, result map[string]interface{}, task *models.Task) error {
+	var failures []string
+	if selectsDevices(barkConfig) {
 		// 使用新的设备选择逻辑
-		return n.sendBarkToSelectedDevices(barkConfig, result, task.ID)
-	} else {
+		if err := n.sendBarkToSelectedDevices(ctx, barkConfig, result, task.ID); err != nil {
+			failures = append(failures, err.Error())
+		}
+	} else if barkConfig.DeviceKey != "" {
 		// 兼容旧的配置方式
 		configMap := n.barkConfigToMap(barkConfig)
 		finalConfig := n.replacePlaceholders(configMap, result)
-		return n.SendBark(finalConfig)
+		if err := n.SendBark(ctx, finalConfig); err != nil {
+			failures = append(failures, err.Error())
+		}
 	}
+
+	if len(barkConfig.SelectedChannelIds) > 0 {
+		if err := n.sendToSelectedChannels(ctx, barkConfig, result, task); err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("%s", strings.Join(failures, "; "))
+	}
+	return nil
 }
 
 // barkConfigToMap converts BarkConfig struct to map[string]string
@@ -290,6 +396,16 @@ func (n *Notifier) replacePlaceholders(config map[string]string, result map[stri
 	placeholderRegex := regexp.MustCompile(`\$(\w+)`)
 
 	for key, value := range config {
+		// 包含 {{ 的字段按模板渲染
+		if msgtemplate.IsTemplate(value) {
+			rendered, err := msgtemplate.Render(value, msgtemplate.NewData(n.task, n.taskLog, result))
+			if err != nil {
+				n.logger.Warn("Failed to render template field", "field", key, "error", err)
+			}
+			finalConfig[key] = rendered
+			continue
+		}
+
 		// 替换占位符
 		finalValue := placeholderRegex.ReplaceAllStringFunc(value, func(match string) string {
 			// 提取占位符中的 key（去掉 $ 符号）
@@ -299,13 +415,13 @@ func (n *Notifier) replacePlaceholders(config map[string]string, result map[stri
 			if resultValue, exists := result[placeholder]; exists {
 				// 检查值是否为空
 				if resultValue == nil {
-					log.Printf("Removing empty placeholder $%s (nil value)", placeholder)
+					n.logger.Debug("Removing empty placeholder (nil value)", "placeholder", placeholder)
 					return "" // 移除空的占位符
 				}
 
 				// 检查字符串是否为空
 				if strValue, ok := resultValue.(string); ok && strings.TrimSpace(strValue) == "" {
-					log.Printf("Removing empty placeholder $%s (empty string)", placeholder)
+					n.logger.Debug("Removing empty placeholder (empty string)", "placeholder", placeholder)
 					return "" // 移除空的占位符
 				}
 
@@ -314,7 +430,7 @@ func (n *Notifier) replacePlaceholders(config map[string]string, result map[stri
 			}
 
 			// 如果没有找到对应的值，移除占位符
-			log.Printf("Removing undefined placeholder $%s", placeholder)
+			n.logger.Debug("Removing undefined placeholder", "placeholder", placeholder)
 			return ""
 		})
 
@@ -352,8 +468,8 @@ func (n *Notifier) interfaceToString(value interface{}) string {
 	}
 }
 
-// getLatestTaskResult 获取任务的最新执行结果
-func (n *Notifier) getLatestTaskResult(taskID uint) (map[string]interface{}, error) {
+// getLatestTaskLog 获取任务的最新执行记录
+func (n *Notifier) getLatestTaskLog(taskID uint) (*models.TaskLog, error) {
 	var taskLog models.TaskLog
 
 	// 查询最新的执行记录（不限制状态）- 使用重试机制
@@ -367,6 +483,11 @@ func (n *Notifier) getLatestTaskResult(taskID uint) (map[string]interface{}, err
 		return nil, fmt.Errorf("no task result found: %v", err)
 	}
 
+	return &taskLog, nil
+}
+
+// parseLogResult 解析执行记录中的 JSON 结果
+func (n *Notifier) parseLogResult(taskLog *models.TaskLog) map[string]interface{} {
 	result := make(map[string]interface{})
 
 	// 首先尝试解析JSON结果
@@ -379,23 +500,23 @@ func (n *Notifier) getLatestTaskResult(taskID uint) (map[string]interface{}, err
 				result[key] = value
 			}
 			jsonParsed = true
-			log.Printf("JSON parsed successfully for task %d, status: %s", taskID, taskLog.Status)
+			n.logger.Debug("Task result parsed", "log_id", taskLog.ID, "status", taskLog.Status)
 
 			// 对于script_failed状态，JSON中的error字段是有效数据，应该可以作为占位符使用
 			if taskLog.Status == "script_failed" {
-				log.Printf("Script failed status detected, error field available as placeholder: %v", result["error"])
+				n.logger.Debug("Script failed, error field available as placeholder", "error", result["error"])
 			}
 		} else {
-			log.Printf("Failed to parse JSON for task %d: %v", taskID, err)
+			n.logger.Warn("Failed to parse task result", "log_id", taskLog.ID, "error", err)
 		}
 	}
 
 	// 如果JSON解析失败，不提供默认数据，只使用基础信息
 	if !jsonParsed {
-		log.Printf("JSON parsing failed for task %d with status %s, no custom data available", taskID, taskLog.Status)
+		n.logger.Debug("No JSON result, no custom data available", "log_id", taskLog.ID, "status", taskLog.Status)
 	}
 
-	return result, nil
+	return result
 }
 
 // extractPlaceholders 从配置字符串中提取占位符
@@ -420,8 +541,15 @@ func extractPlaceholders(configValue string) []string {
 	return placeholders
 }
 
-// validatePlaceholders 验证占位符，只要有一个占位符有非空值就允许发送
-func (n *Notifier) validatePlaceholders(barkConfig *models.BarkConfig, result map[string]interface{}) bool {
+// PlaceholderCheck 占位符校验结果
+type PlaceholderCheck struct {
+	Passed  bool              `json:"passed"`  // 是否允许发送
+	Valid   []string          `json:"valid"`   // 有非空值的占位符
+	Invalid map[string]string `json:"invalid"` // 无效的占位符及原因：missing, null, empty
+}
+
+// checkPlaceholders 检查占位符，没有占位符或至少有一个占位符有非空值时允许发送
+func checkPlaceholders(barkConfig *models.BarkConfig, result map[string]interface{}) PlaceholderCheck {
 	// 收集所有配置字段中的占位符
 	allPlaceholders := make(map[string]bool)
 
@@ -446,86 +574,110 @@ func (n *Notifier) validatePlaceholders(barkConfig *models.BarkConfig, result ma
 		barkConfig.Delete,
 	}
 
-	// 提取所有占位符
+	// 提取所有占位符，模板字段不参与校验
 	for _, field := range configFields {
+		if msgtemplate.IsTemplate(field) {
+			continue
+		}
 		placeholders := extractPlaceholders(field)
 		for _, placeholder := range placeholders {
 			allPlaceholders[placeholder] = true
 		}
 	}
 
-	// 如果没有占位符，直接返回true（允许发送）
-	if len(allPlaceholders) == 0 {
-		log.Printf("No placeholders found in Bark config, allowing notification")
-		return true
-	}
-
-	// 检查是否至少有一个占位符有非空值
-	validPlaceholders := make([]string, 0)
-	invalidPlaceholders := make([]string, 0)
-
+	check := PlaceholderCheck{Valid: []string{}, Invalid: map[string]string{}}
 	for placeholder := range allPlaceholders {
 		value, exists := result[placeholder]
-		if !exists {
-			log.Printf("Placeholder $%s not found in result data", placeholder)
-			invalidPlaceholders = append(invalidPlaceholders, placeholder)
-			continue
-		}
-
-		// 检查值是否为空
-		if value == nil {
-			log.Printf("Placeholder $%s has nil value", placeholder)
-			invalidPlaceholders = append(invalidPlaceholders, placeholder)
-			continue
-		}
-
-		// 检查字符串是否为空
-		if strValue, ok := value.(string); ok && strings.TrimSpace(strValue) == "" {
-			log.Printf("Placeholder $%s has empty string value", placeholder)
-			invalidPlaceholders = append(invalidPlaceholders, placeholder)
-			continue
+		switch {
+		case !exists:
+			check.Invalid[placeholder] = "missing"
+		case value == nil:
+			check.Invalid[placeholder] = "null"
+		default:
+			// 检查字符串是否为空
+			if strValue, ok := value.(string); ok && strings.TrimSpace(strValue) == "" {
+				check.Invalid[placeholder] = "empty"
+				continue
+			}
+			check.Valid = append(check.Valid, placeholder)
 		}
-
-		// 这个占位符有有效值
-		validPlaceholders = append(validPlaceholders, placeholder)
 	}
+	sort.Strings(check.Valid)
 
-	// 只要有至少一个占位符有效，就允许发送
-	if len(validPlaceholders) > 0 {
-		log.Printf("Placeholder validation passed: %d valid placeholders %v, %d invalid placeholders %v",
-			len(validPlaceholders), validPlaceholders, len(invalidPlaceholders), invalidPlaceholders)
-		return true
+	// 没有占位符，或者只要有至少一个占位符有效，就允许发送
+	check.Passed = len(allPlaceholders) == 0 || len(check.Valid) > 0
+	return check
+}
+
+// validatePlaceholders 验证占位符，只要有一个占位符有非空值就允许发送
+func (n *Notifier) validatePlaceholders(barkConfig *models.BarkConfig, result map[string]interface{}) bool {
+	check := checkPlaceholders(barkConfig, result)
+	switch {
+	case len(check.Valid) == 0 && len(check.Invalid) == 0:
+		n.logger.Debug("No placeholders found in Bark config, allowing notification")
+	case check.Passed:
+		n.logger.Debug("Placeholder validation passed", "valid", check.Valid, "invalid", check.Invalid)
+	default:
+		// 所有占位符都无效
+		n.logger.Debug("Placeholder validation failed: all placeholders are invalid", "invalid", check.Invalid)
 	}
+	return check.Passed
+}
 
-	// 所有占位符都无效
-	log.Printf("Placeholder validation failed: all placeholders are invalid %v", invalidPlaceholders)
-	return false
+// deviceRecord 由替换后的推送参数创建发送记录，用于去重检查和保存
+func deviceRecord(taskID uint, config map[string]string) *models.BarkRecord {
+	processedBarkConfig := &models.BarkConfig{
+		DeviceKey:  config["device_key"],
+		Title:      config["title"],
+		Subtitle:   config["subtitle"],
+		Body:       config["body"], // 这里是替换后的body
+		Level:      config["level"],
+		Volume:     config["volume"],
+		Badge:      config["badge"],
+		Call:       config["call"],
+		AutoCopy:   config["autoCopy"],
+		Copy:       config["copy"],
+		Sound:      config["sound"],
+		Icon:       config["icon"],
+		Group:      config["group"],
+		Ciphertext: config["ciphertext"],
+		IsArchive:  config["isArchive"],
+		URL:        config["url"],
+		Action:     config["action"],
+		ID:         config["id"],
+		Delete:     config["delete"],
+	}
+	return models.CreateBarkRecordFromConfig(taskID, config["device_key"], processedBarkConfig, "", "", "")
 }
 
 // sendBarkToSelectedDevices 向选中的设备发送 Bark 通知
-func (n *Notifier) sendBarkToSelectedDevices(barkConfig *models.BarkConfig, result map[string]interface{}, taskID uint) error {
-	// 获取选中的设备信息 - 使用重试机制
-	var devices []models.BarkDevice
-	err := database.WithRetry(func(db *gorm.DB) error {
-		return db.Where("id IN ? AND status = ?", barkConfig.SelectedDeviceIds, "active").Find(&devices).Error
-	})
+func (n *Notifier) sendBarkToSelectedDevices(ctx context.Context, barkConfig *models.BarkConfig, result map[string]interface{}, taskID uint) error {
+	// 准备基础配置
+	baseConfig := n.barkConfigToMap(barkConfig)
+	baseConfig = n.replacePlaceholders(baseConfig, result)
+
+	// 获取选中的设备和分组成员，设备路由按渲染后的通知级别匹配
+	devices, route, err := n.resolveDevices(barkConfig, baseConfig["level"], result)
 	if err != nil {
-		return fmt.Errorf("failed to get selected devices: %v", err)
+		return err
 	}
 
 	if len(devices) == 0 {
+		// 只配置了路由且没有命中时不发送
+		if len(barkConfig.SelectedDeviceIds) == 0 && len(barkConfig.SelectedGroupIds) == 0 && route == "" {
+			n.logger.Info("No device route matched, skipping Bark notification")
+			return nil
+		}
 		return fmt.Errorf("no active devices found for selected IDs")
 	}
 
-	// 准备基础配置
-	baseConfig := n.barkConfigToMap(barkConfig)
-	baseConfig = n.replacePlaceholders(baseConfig, result)
-
 	var errors []string
 	successCount := 0
 
 	// 为每个设备单独发送（以便独立记录和去重）
 	for _, device := range devices {
+		logger := n.logger.With("device", device.Name)
+
 		// 准备设备特定配置
 		config := make(map[string]string)
 		for k, v := range baseConfig {
@@ -533,69 +685,91 @@ func (n *Notifier) sendBarkToSelectedDevices(barkConfig *models.BarkConfig, resu
 		}
 		config["device_key"] = device.DeviceKey
 
-		// 创建替换后的BarkConfig用于记录
-		processedBarkConfig := &models.BarkConfig{
-			DeviceKey:  device.DeviceKey,
-			Title:      config["title"],
-			Subtitle:   config["subtitle"],
-			Body:       config["body"], // 这里是替换后的body
-			Level:      config["level"],
-			Volume:     config["volume"],
-			Badge:      config["badge"],
-			Call:       config["call"],
-			AutoCopy:   config["autoCopy"],
-			Copy:       config["copy"],
-			Sound:      config["sound"],
-			Icon:       config["icon"],
-			Group:      config["group"],
-			Ciphertext: config["ciphertext"],
-			IsArchive:  config["isArchive"],
-			URL:        config["url"],
-			Action:     config["action"],
-			ID:         config["id"],
-			Delete:     config["delete"],
-		}
-
 		// 创建Bark记录用于去重检查（使用替换后的配置）
-		record := models.CreateBarkRecordFromConfig(taskID, device.DeviceKey, processedBarkConfig, "", "", "")
+		record := deviceRecord(taskID, config)
+		n.applyDedupKey(record, &barkConfig.Deduplication, result)
 
 		// 检查是否重复
+		_, dedupSpan := tracing.Start(ctx, "notifier.dedup_check",
+			attribute.String("bark.device", device.Name),
+			attribute.String("dedup.mode", barkConfig.Deduplication.Mode),
+		)
 		isDuplicate, err := n.historyManager.CheckDuplication(record, &barkConfig.Deduplication)
 		if err != nil {
-			log.Printf("Failed to check duplication for device %s: %v", device.Name, err)
+			logger.Warn("Failed to check duplication", "error", err)
+			tracing.RecordError(dedupSpan, err)
 		}
+		dedupSpan.SetAttributes(attribute.Bool("dedup.duplicate", isDuplicate))
+		dedupSpan.End()
 
 		if isDuplicate {
-			log.Printf("Skipping duplicate bark notification for device %s (task %d)", device.Name, taskID)
+			logger.Info("Skipping duplicate Bark notification", "dedup_mode", barkConfig.Deduplication.Mode)
 			// 记录跳过的通知
 			record.Status = "skipped"
 			record.ErrorMessage = "Duplicate content detected"
 			n.historyManager.SaveBarkRecord(record)
+			metrics.BarkSent(record.Status)
+			metrics.DedupSkipped(barkConfig.Deduplication.Mode)
+			continue
+		}
+
+		// 启用汇总时只加入汇总，到期后合并发送
+		if barkConfig.Digest.Enabled {
+			if err := n.queueDigest(record, &barkConfig.Digest); err != nil {
+				errors = append(errors, fmt.Sprintf("设备 %s: %v", device.Name, err))
+				logger.Error("Failed to queue Bark notification for digest", "error", err)
+				continue
+			}
+			metrics.BarkSent(record.Status)
+			successCount++
+			continue
+		}
+
+		// 超过设备或服务器的推送限额时不发送
+		server, _, _ := n.getBarkServer(device.DeviceKey)
+		if ok, reason := checkRateLimit(&device, server); !ok {
+			logger.Warn("Bark notification throttled", "reason", reason)
+			record.Status = models.RecordThrottled
+			record.ErrorMessage = reason
+			n.historyManager.SaveBarkRecord(record)
+			metrics.BarkSent(record.Status)
 			continue
 		}
 
-		// 发送通知
-		err = n.SendBark(config)
+		// 免打扰时段内降级为 passive 或推迟到时段结束
+		deliverAt, delayed := applyQuietHours(&device, config, logger)
+		record.Level = config["level"]
+
+		// 先保存记录并写入投递队列，再立即投递一次，失败后由 OutboxWorker 重试
+		record.Status = "pending"
+		n.historyManager.SaveBarkRecord(record)
+		if !delayed {
+			deliverAt = time.Now()
+		}
+		entry, err := n.enqueueAt(record, config, deliverAt)
 		if err != nil {
 			errors = append(errors, fmt.Sprintf("设备 %s: %v", device.Name, err))
-			// 记录失败的通知
-			record.Status = "failed"
-			record.ErrorMessage = err.Error()
-		} else {
+			logger.Error("Failed to enqueue Bark notification", "error", err)
+			n.updateRecord(record.ID, "failed", err.Error())
+			metrics.BarkSent("failed")
+			continue
+		}
+		if delayed {
 			successCount++
-			log.Printf("Bark notification sent successfully to device: %s", device.Name)
-			// 记录成功的通知
-			record.Status = "success"
+			continue
 		}
 
-		// 保存记录
-		n.historyManager.SaveBarkRecord(record)
+		if err := n.deliver(ctx, entry, logger); err != nil {
+			errors = append(errors, fmt.Sprintf("设备 %s: %v", device.Name, err))
+		} else {
+			successCount++
+		}
 	}
 
 	// 处理结果
 	if len(errors) > 0 {
 		if successCount > 0 {
-			log.Printf("Partial success: %d/%d devices, errors: %v", successCount, len(devices), errors)
+			n.logger.Warn("Bark notification partially failed", "succeeded", successCount, "devices", len(devices))
 			return fmt.Errorf("partial failure: %s", strings.Join(errors, "; "))
 		} else {
 			return fmt.Errorf("all devices failed: %s", strings.Join(errors, "; "))
@@ -0,0 +1,383 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"autobot/internal/database"
+	"autobot/internal/metrics"
+	"autobot/internal/models"
+	"autobot/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+// Bark 推送先写入 bark_outbox 再投递：首次投递在通知流程中立即进行，
+// 失败后由 OutboxWorker 按指数退避重试，次数用尽后保留为死信，发送记录标记为 failed。
+
+const (
+	// outboxPollInterval 检查到期重试的间隔
+	outboxPollInterval = 5 * time.Second
+	// outboxBatchSize 每轮最多处理的条数
+	outboxBatchSize = 50
+	// outboxLockDuration 领取的有效期，实例在投递中途退出时过期后由其他实例接管
+	outboxLockDuration = 5 * time.Minute
+)
+
+// 投递参数，由 SetDeliveryOptions 按配置设置
+var (
+	defaultTimeout  = 10 * time.Second
+	maxAttempts     = 8
+	retryBackoff    = 30 * time.Second
+	maxRetryBackoff = time.Hour
+)
+
+// DeliveryOptions Bark 推送超时和重试策略
+type DeliveryOptions struct {
+	Timeout         time.Duration // 服务器未单独设置时的请求超时
+	MaxAttempts     int           // 最多投递次数（含首次）
+	RetryBackoff    time.Duration // 首次重试间隔，之后每次翻倍
+	MaxRetryBackoff time.Duration // 重试间隔上限
+}
+
+// SetDeliveryOptions 设置推送超时和重试策略，零值保持默认
+func SetDeliveryOptions(opts DeliveryOptions) {
+	if opts.Timeout > 0 {
+		defaultTimeout = opts.Timeout
+	}
+	if opts.MaxAttempts > 0 {
+		maxAttempts = opts.MaxAttempts
+	}
+	if opts.RetryBackoff > 0 {
+		retryBackoff = opts.RetryBackoff
+	}
+	if opts.MaxRetryBackoff > 0 {
+		maxRetryBackoff = opts.MaxRetryBackoff
+	}
+}
+
+// backoff 第 attempts 次失败后的重试间隔
+func backoff(attempts int) time.Duration {
+	delay := retryBackoff
+	for i := 1; i < attempts && delay < maxRetryBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryBackoff {
+		delay = maxRetryBackoff
+	}
+	return delay
+}
+
+// errNotClaimed 队列记录已被其他实例领取或已处理
+var errNotClaimed = errors.New("outbox entry already claimed")
+
+// 重发错误
+var (
+	ErrRecordNotFound = errors.New("bark record not found")
+	ErrNotResendable  = errors.New("only failed Bark device records can be resent")
+)
+
+// enqueue 为已保存的发送记录创建投递队列记录
+func (n *Notifier) enqueue(record *models.BarkRecord, config map[string]string) (*models.BarkOutbox, error) {
+	return n.enqueueAt(record, config, time.Now())
+}
+
+// enqueueAt 创建投递队列记录，到 at 之后才会投递
+func (n *Notifier) enqueueAt(record *models.BarkRecord, config map[string]string, at time.Time) (*models.BarkOutbox, error) {
+	payload, err := json.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal Bark payload: %v", err)
+	}
+
+	entry := &models.BarkOutbox{
+		RecordID:      record.ID,
+		TaskID:        record.TaskID,
+		DeviceKey:     record.DeviceKey,
+		Payload:       string(payload),
+		Status:        models.OutboxPending,
+		NextAttemptAt: at,
+	}
+	err = database.WithRetry(func(db *gorm.DB) error {
+		return db.Create(entry).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to enqueue Bark notification: %v", err)
+	}
+	return entry, nil
+}
+
+// claim 领取队列记录，只有待投递或领取已过期的记录可以被领取
+func claim(entry *models.BarkOutbox) (bool, error) {
+	now := time.Now()
+	var claimed bool
+	err := database.WithRetry(func(db *gorm.DB) error {
+		result := db.Model(&models.BarkOutbox{}).
+			Where("id = ? AND (status = ? OR (status = ? AND locked_until < ?))",
+				entry.ID, models.OutboxPending, models.OutboxSending, now).
+			Updates(map[string]interface{}{
+				"status":       models.OutboxSending,
+				"locked_until": now.Add(outboxLockDuration),
+			})
+		claimed = result.RowsAffected == 1
+		return result.Error
+	})
+	return claimed, err
+}
+
+// deliver 领取并投递一次队列记录，同步更新发送记录，返回本次投递的错误
+func (n *Notifier) deliver(ctx context.Context, entry *models.BarkOutbox, logger *slog.Logger) error {
+	claimed, err := claim(entry)
+	if err != nil {
+		return fmt.Errorf("failed to claim outbox entry: %v", err)
+	}
+	if !claimed {
+		return errNotClaimed
+	}
+
+	var config map[string]string
+	sendErr := json.Unmarshal([]byte(entry.Payload), &config)
+	if sendErr == nil {
+		sendErr = n.SendBark(ctx, config)
+	}
+
+	// 停机导致的中断不计入投递次数，交还队列由下次重试处理
+	if sendErr != nil && ctx.Err() != nil {
+		n.saveOutbox(entry, map[string]interface{}{"status": models.OutboxPending})
+		return sendErr
+	}
+
+	entry.Attempts++
+	switch {
+	case sendErr == nil:
+		err := database.WithRetry(func(db *gorm.DB) error {
+			return db.Delete(&models.BarkOutbox{}, entry.ID).Error
+		})
+		if err != nil {
+			logger.Warn("Failed to remove delivered outbox entry", "outbox_id", entry.ID, "error", err)
+		}
+		n.updateRecord(entry.RecordID, "success", "")
+		metrics.BarkSent("success")
+		logger.Info("Bark notification sent", "attempt", entry.Attempts)
+
+	case entry.Attempts >= maxAttempts:
+		entry.Status = models.OutboxDead
+		n.saveOutbox(entry, map[string]interface{}{
+			"status":     models.OutboxDead,
+			"attempts":   entry.Attempts,
+			"last_error": sendErr.Error(),
+		})
+		n.updateRecord(entry.RecordID, "failed", sendErr.Error())
+		metrics.BarkSent("failed")
+		logger.Error("Bark notification failed, moved to dead letter", "attempts", entry.Attempts, "error", sendErr)
+
+	default:
+		entry.NextAttemptAt = time.Now().Add(backoff(entry.Attempts))
+		n.saveOutbox(entry, map[string]interface{}{
+			"status":          models.OutboxPending,
+			"attempts":        entry.Attempts,
+			"next_attempt_at": entry.NextAttemptAt,
+			"last_error":      sendErr.Error(),
+		})
+		n.updateRecord(entry.RecordID, "pending", sendErr.Error())
+		metrics.BarkRetried()
+		logger.Warn("Bark notification failed, will retry",
+			"attempt", entry.Attempts, "next_attempt_at", entry.NextAttemptAt, "error", sendErr)
+	}
+	return sendErr
+}
+
+// saveOutbox 更新队列记录
+func (n *Notifier) saveOutbox(entry *models.BarkOutbox, fields map[string]interface{}) {
+	err := database.WithRetry(func(db *gorm.DB) error {
+		return db.Model(&models.BarkOutbox{}).Where("id = ?", entry.ID).Updates(fields).Error
+	})
+	if err != nil {
+		n.logger.Error("Failed to update outbox entry", "outbox_id", entry.ID, "error", err)
+	}
+}
+
+// updateRecord 更新发送记录的状态，记录可能已被清理
+func (n *Notifier) updateRecord(recordID uint, status, errorMessage string) {
+	if recordID == 0 {
+		return
+	}
+	err := database.WithRetry(func(db *gorm.DB) error {
+		return db.Model(&models.BarkRecord{}).Where("id = ?", recordID).
+			Updates(map[string]interface{}{"status": status, "error_message": errorMessage}).Error
+	})
+	if err != nil {
+		n.logger.Error("Failed to update Bark record", "record_id", recordID, "error", err)
+	}
+}
+
+// Resend 将失败的发送记录重新加入投递队列并立即投递一次，返回更新后的记录
+// 重发不做去重检查；已有死信时复用死信并重置投递次数
+func (n *Notifier) Resend(ctx context.Context, recordID uint) (*models.BarkRecord, error) {
+	var record models.BarkRecord
+	err := database.WithRetry(func(db *gorm.DB) error {
+		return db.First(&record, recordID).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	if record.ChannelID != 0 {
+		return nil, ErrNotResendable
+	}
+
+	// 以状态条件更新，防止同一记录被并发重发
+	var updated bool
+	err = database.WithRetry(func(db *gorm.DB) error {
+		result := db.Model(&models.BarkRecord{}).Where("id = ? AND status = ?", record.ID, "failed").
+			Updates(map[string]interface{}{"status": "pending", "error_message": ""})
+		updated = result.RowsAffected == 1
+		return result.Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, ErrNotResendable
+	}
+
+	var entry models.BarkOutbox
+	err = database.WithRetry(func(db *gorm.DB) error {
+		return db.Where("record_id = ? AND status = ?", record.ID, models.OutboxDead).First(&entry).Error
+	})
+	switch {
+	case err == nil:
+		entry.Status = models.OutboxPending
+		entry.Attempts = 0
+		entry.NextAttemptAt = time.Now()
+		entry.LastError = ""
+		err = database.WithRetry(func(db *gorm.DB) error {
+			return db.Save(&entry).Error
+		})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		// 早于投递队列的记录没有死信，按记录内容重建
+		var created *models.BarkOutbox
+		if created, err = n.enqueue(&record, n.barkConfigToMap(record.BarkConfig())); err == nil {
+			entry = *created
+		}
+	}
+	if err != nil {
+		n.updateRecord(record.ID, "failed", err.Error())
+		return nil, err
+	}
+
+	logger := n.logger.With("record_id", record.ID, "task_id", record.TaskID)
+	logger.Info("Resending Bark notification")
+	if err := n.deliver(ctx, &entry, logger); err != nil && !errors.Is(err, errNotClaimed) {
+		logger.Warn("Resent Bark notification failed", "error", err)
+	}
+
+	err = database.WithRetry(func(db *gorm.DB) error {
+		return db.First(&record, record.ID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// OutboxWorker 按退避策略重试投递队列中的推送，并发送到期的通知汇总
+// 多实例部署时只在 Leader 上处理，首次投递仍由发起通知的实例完成
+type OutboxWorker struct {
+	isLeader func() bool
+
+	mutex  sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewOutboxWorker 创建投递队列处理器，isLeader 为 nil 时总是处理
+func NewOutboxWorker(isLeader func() bool) *OutboxWorker {
+	return &OutboxWorker{isLeader: isLeader}
+}
+
+// Start 启动定时重试
+func (w *OutboxWorker) Start() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+	w.done = make(chan struct{})
+
+	go func() {
+		defer close(w.done)
+		ticker := time.NewTicker(outboxPollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+			if w.isLeader == nil || w.isLeader() {
+				w.processDue(ctx)
+				New(database.GetDB()).flushDigests(ctx)
+			}
+		}
+	}()
+}
+
+// Stop 停止定时重试，等待正在进行的投递结束
+func (w *OutboxWorker) Stop() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.cancel == nil {
+		return
+	}
+	w.cancel()
+	<-w.done
+	w.cancel = nil
+}
+
+// processDue 投递到期的队列记录
+func (w *OutboxWorker) processDue(ctx context.Context) {
+	now := time.Now()
+	var entries []models.BarkOutbox
+	err := database.WithRetry(func(db *gorm.DB) error {
+		return db.Where("(status = ? AND next_attempt_at <= ?) OR (status = ? AND locked_until < ?)",
+			models.OutboxPending, now, models.OutboxSending, now).
+			Order("next_attempt_at asc").
+			Limit(outboxBatchSize).
+			Find(&entries).Error
+	})
+	if err != nil {
+		slog.Error("Failed to load Bark outbox", "error", err)
+		return
+	}
+
+	for i := range entries {
+		if ctx.Err() != nil {
+			return
+		}
+		entry := &entries[i]
+		logger := slog.With("outbox_id", entry.ID, "task_id", entry.TaskID, "record_id", entry.RecordID)
+
+		spanCtx, span := tracing.Start(ctx, "outbox.deliver",
+			attribute.Int64("outbox.id", int64(entry.ID)),
+			attribute.Int("outbox.attempt", entry.Attempts+1),
+		)
+		err := New(database.GetDB()).WithLogger(logger).deliver(spanCtx, entry, logger)
+		if errors.Is(err, errNotClaimed) {
+			err = nil
+		}
+		tracing.RecordError(span, err)
+		span.End()
+	}
+}
@@ -0,0 +1,294 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"autobot/internal/database"
+	"autobot/internal/digest"
+	"autobot/internal/metrics"
+	"autobot/internal/models"
+	"autobot/internal/rules"
+
+	"gorm.io/gorm"
+)
+
+// Preview 通知预览：按当前配置渲染出的最终推送内容，不实际发送
+type Preview struct {
+	ResultSource string                 `json:"result_source"`    // latest, supplied, none
+	LogID        uint                   `json:"log_id,omitempty"` // 使用的执行记录
+	Result       map[string]interface{} `json:"result"`           // 渲染使用的结果
+	Placeholders *PlaceholderCheck      `json:"placeholders,omitempty"`
+	Devices      []DevicePreview        `json:"devices"`
+	Channels     []ChannelPreview       `json:"channels"`
+	Rules        []RulePreview          `json:"rules,omitempty"`  // 配置了通知规则时按规则预览，不使用上面的目标
+	Digest       *DigestPreview         `json:"digest,omitempty"` // 启用汇总时通知不会立即发送
+}
+
+// DigestPreview 通知进入的汇总
+type DigestPreview struct {
+	Key    string    `json:"key"`
+	NextAt time.Time `json:"next_at"` // 预计发送汇总的时间
+	Queued int64     `json:"queued"`  // 分组中已在等待的通知数
+}
+
+// DevicePreview 单个 Bark 设备的推送参数
+type DevicePreview struct {
+	DeviceID  uint              `json:"device_id,omitempty"`
+	Name      string            `json:"name"`
+	Server    string            `json:"server,omitempty"`
+	Encrypted bool              `json:"encrypted"`       // 发送时内容字段会加密为 ciphertext
+	Payload   map[string]string `json:"payload"`         // 加密前的推送参数
+	Duplicate bool              `json:"duplicate"`       // 按当前去重配置会被跳过
+	Route     string            `json:"route,omitempty"` // 本次命中的设备路由
+	Error     string            `json:"error,omitempty"`
+}
+
+// ChannelPreview 单个通知渠道的消息
+type ChannelPreview struct {
+	ChannelID uint   `json:"channel_id"`
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Subtitle  string `json:"subtitle,omitempty"`
+	Body      string `json:"body"`
+	URL       string `json:"url,omitempty"`
+	Level     string `json:"level,omitempty"`
+	Group     string `json:"group,omitempty"`
+	Duplicate bool   `json:"duplicate"`
+}
+
+// RulePreview 单条通知规则的预览
+type RulePreview struct {
+	Name         string            `json:"name"`
+	Trigger      string            `json:"trigger"`
+	Matched      bool              `json:"matched"` // 按最近的执行状态是否命中
+	Error        string            `json:"error,omitempty"`
+	Placeholders *PlaceholderCheck `json:"placeholders,omitempty"`
+	Devices      []DevicePreview   `json:"devices"`
+	Channels     []ChannelPreview  `json:"channels"`
+}
+
+// Preview 按最近一次执行的结果（或传入的 result）渲染通知配置
+// barkConfig 可以是尚未保存的配置；result 为 nil 时使用最近一次执行的结果
+func (n *Notifier) Preview(ctx context.Context, task *models.Task, barkConfig *models.BarkConfig, result map[string]interface{}) (*Preview, error) {
+	preview := &Preview{ResultSource: "supplied"}
+
+	taskLog, err := n.getLatestTaskLog(task.ID)
+	if err != nil {
+		taskLog = nil
+	}
+	if result == nil {
+		preview.ResultSource = "none"
+		result = map[string]interface{}{}
+		if taskLog != nil {
+			preview.ResultSource = "latest"
+			result = n.parseLogResult(taskLog)
+		}
+	}
+	if taskLog != nil {
+		preview.LogID = taskLog.ID
+	}
+	preview.Result = result
+	n.task, n.taskLog = task, taskLog
+
+	if barkConfig.Digest.Enabled {
+		if preview.Digest, err = previewDigest(&barkConfig.Digest, task.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(barkConfig.Rules) > 0 {
+		rulePreviews, err := n.previewRules(task, barkConfig, result)
+		if err != nil {
+			return nil, err
+		}
+		preview.Rules = rulePreviews
+		preview.Devices, preview.Channels = []DevicePreview{}, []ChannelPreview{}
+		return preview, nil
+	}
+
+	check := checkPlaceholders(barkConfig, result)
+	preview.Placeholders = &check
+	preview.Devices, preview.Channels, err = n.previewTargets(barkConfig, result, task)
+	if err != nil {
+		return nil, err
+	}
+	return preview, nil
+}
+
+// previewRules 按最近的执行状态判断每条规则是否命中，并渲染各自的内容
+func (n *Notifier) previewRules(task *models.Task, barkConfig *models.BarkConfig, result map[string]interface{}) ([]RulePreview, error) {
+	var logs []models.TaskLog
+	err := database.WithRetry(func(db *gorm.DB) error {
+		return db.Where("task_id = ? AND status IN ?", task.ID, []string{"success", "execution_failed", "script_failed"}).
+			Order("created_at desc").
+			Limit(rules.MaxHistory(barkConfig.Rules)).
+			Find(&logs).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task history: %v", err)
+	}
+
+	// 没有执行过时按一次成功的执行预览
+	state := rules.RunState{Status: "success"}
+	lastLog := &models.TaskLog{Status: "success"}
+	if len(logs) > 0 {
+		state = runState(logs)
+		lastLog = &logs[0]
+	}
+	vars := ruleVars(task, lastLog, state, result)
+
+	previews := make([]RulePreview, 0, len(barkConfig.Rules))
+	for i := range barkConfig.Rules {
+		rule := &barkConfig.Rules[i]
+		rp := RulePreview{Name: ruleLabel(rule, i), Trigger: rule.Trigger}
+
+		matched, err := rules.Match(rule, state, result)
+		if err != nil {
+			rp.Error = err.Error()
+		}
+		rp.Matched = matched
+
+		config := rule.Apply(barkConfig)
+		check := checkPlaceholders(config, vars)
+		rp.Placeholders = &check
+		if rp.Devices, rp.Channels, err = n.previewTargets(config, vars, task); err != nil {
+			return nil, err
+		}
+		previews = append(previews, rp)
+	}
+	return previews, nil
+}
+
+// previewTargets 渲染每个设备和渠道最终收到的内容
+func (n *Notifier) previewTargets(barkConfig *models.BarkConfig, result map[string]interface{}, task *models.Task) ([]DevicePreview, []ChannelPreview, error) {
+	baseConfig := n.replacePlaceholders(n.barkConfigToMap(barkConfig), result)
+	devicePreviews := []DevicePreview{}
+	channelPreviews := []ChannelPreview{}
+
+	if selectsDevices(barkConfig) {
+		devices, route, err := n.resolveDevices(barkConfig, baseConfig["level"], result)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, device := range devices {
+			config := make(map[string]string, len(baseConfig)+1)
+			for k, v := range baseConfig {
+				config[k] = v
+			}
+			config["device_key"] = device.DeviceKey
+
+			dp := n.previewDevice(task.ID, config, &barkConfig.Deduplication, result)
+			dp.DeviceID = device.ID
+			dp.Name = device.Name
+			dp.Route = route
+			devicePreviews = append(devicePreviews, dp)
+		}
+	} else if barkConfig.DeviceKey != "" {
+		// 兼容旧的配置方式，不做去重
+		dp := n.previewDevice(task.ID, baseConfig, nil, nil)
+		dp.Name = barkConfig.DeviceKey
+		devicePreviews = append(devicePreviews, dp)
+	}
+
+	if len(barkConfig.SelectedChannelIds) > 0 {
+		var channels []models.NotificationChannel
+		err := database.WithRetry(func(db *gorm.DB) error {
+			return db.Where("id IN ? AND status = ?", barkConfig.SelectedChannelIds, "active").Find(&channels).Error
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get selected channels: %v", err)
+		}
+		msg := channelMessage(baseConfig, task, result)
+		for _, ch := range channels {
+			record := channelRecord(task.ID, ch.ID, msg)
+			n.applyDedupKey(record, &barkConfig.Deduplication, result)
+			duplicate, _ := n.historyManager.CheckDuplication(record, &barkConfig.Deduplication)
+			channelPreviews = append(channelPreviews, ChannelPreview{
+				ChannelID: ch.ID,
+				Name:      ch.Name,
+				Type:      ch.Type,
+				Title:     msg.Title,
+				Subtitle:  msg.Subtitle,
+				Body:      msg.Body,
+				URL:       msg.URL,
+				Level:     msg.Level,
+				Group:     msg.Group,
+				Duplicate: duplicate,
+			})
+		}
+	}
+
+	return devicePreviews, channelPreviews, nil
+}
+
+// previewDevice 解析设备的服务器、加密设置并检查去重
+func (n *Notifier) previewDevice(taskID uint, config map[string]string, dedup *models.BarkDeduplicationConfig, result map[string]interface{}) DevicePreview {
+	dp := DevicePreview{Payload: config}
+	if config["device_key"] == "" {
+		return dp
+	}
+
+	server, device, err := n.getBarkServer(config["device_key"])
+	if err != nil {
+		dp.Error = err.Error()
+	} else {
+		dp.Server = fallbackServer(server).URL
+	}
+	if device != nil {
+		dp.Encrypted = device.Encrypted()
+	}
+	if dedup != nil {
+		record := deviceRecord(taskID, config)
+		n.applyDedupKey(record, dedup, result)
+		dp.Duplicate, _ = n.historyManager.CheckDuplication(record, dedup)
+	}
+	return dp
+}
+
+// SendTest 向设备发送一条测试推送并记录到发送历史，不经过投递队列和去重
+func (n *Notifier) SendTest(ctx context.Context, device *models.BarkDevice) (*models.BarkRecord, error) {
+	config := map[string]string{
+		"device_key": device.DeviceKey,
+		"title":      "autobot 测试通知",
+		"body":       "设备 " + device.Name + " 配置正确，发送时间 " + time.Now().Format("2006-01-02 15:04:05"),
+		"group":      "autobot",
+	}
+
+	record := deviceRecord(0, config)
+	err := n.SendBark(ctx, config)
+	if err != nil {
+		record.Status = "failed"
+		record.ErrorMessage = err.Error()
+		n.logger.Warn("Bark test notification failed", "device", device.Name, "error", err)
+	} else {
+		record.Status = "success"
+		n.logger.Info("Bark test notification sent", "device", device.Name)
+	}
+
+	n.historyManager.SaveBarkRecord(record)
+	metrics.BarkSent(record.Status)
+	return record, err
+}
+
+// previewDigest 汇总分组中等待的通知数和预计发送时间
+func previewDigest(config *models.BarkDigestConfig, taskID uint) (*DigestPreview, error) {
+	dp := &DigestPreview{Key: digest.Key(config, taskID), NextAt: digest.NextAt(config, time.Now())}
+
+	var queued []models.BarkRecord
+	err := database.WithRetry(func(db *gorm.DB) error {
+		return db.Where("status = ? AND digest_key = ?", models.RecordQueued, dp.Key).
+			Order("digest_at asc").
+			Find(&queued).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get queued digest: %v", err)
+	}
+	dp.Queued = int64(len(queued))
+	if len(queued) > 0 && queued[0].DigestAt != nil {
+		dp.NextAt = *queued[0].DigestAt
+	}
+	return dp, nil
+}
@@ -0,0 +1,62 @@
+package notifier
+
+import (
+	"fmt"
+
+	"autobot/internal/database"
+	"autobot/internal/models"
+	"autobot/internal/rules"
+
+	"gorm.io/gorm"
+)
+
+// selectsDevices 配置是否按设备、设备分组或路由选择 Bark 设备
+func selectsDevices(barkConfig *models.BarkConfig) bool {
+	return len(barkConfig.SelectedDeviceIds) > 0 || len(barkConfig.SelectedGroupIds) > 0 || len(barkConfig.Routes) > 0
+}
+
+// resolveDevices 计算本次通知的目标设备：选中的设备加上分组成员，
+// 有路由命中时使用路由的分组代替选中的分组。返回启用的设备（按ID去重）和命中的路由名称
+func (n *Notifier) resolveDevices(barkConfig *models.BarkConfig, level string, result map[string]interface{}) ([]models.BarkDevice, string, error) {
+	groupIDs := barkConfig.SelectedGroupIds
+	route := ""
+	if len(barkConfig.Routes) > 0 {
+		index, err := rules.MatchRoute(barkConfig.Routes, level, result)
+		if err != nil {
+			return nil, "", err
+		}
+		if index >= 0 {
+			groupIDs = barkConfig.Routes[index].GroupIDs
+			route = rules.RouteLabel(&barkConfig.Routes[index], index)
+			n.logger.Debug("Device route matched", "route", route)
+		}
+	}
+
+	deviceIDs := append([]uint{}, barkConfig.SelectedDeviceIds...)
+	if len(groupIDs) > 0 {
+		var groups []models.BarkDeviceGroup
+		err := database.WithRetry(func(db *gorm.DB) error {
+			return db.Preload("Devices").Where("id IN ?", groupIDs).Find(&groups).Error
+		})
+		if err != nil {
+			return nil, route, fmt.Errorf("failed to get device groups: %v", err)
+		}
+		for _, group := range groups {
+			for _, device := range group.Devices {
+				deviceIDs = append(deviceIDs, device.ID)
+			}
+		}
+	}
+	if len(deviceIDs) == 0 {
+		return nil, route, nil
+	}
+
+	var devices []models.BarkDevice
+	err := database.WithRetry(func(db *gorm.DB) error {
+		return db.Where("id IN ? AND status = ?", deviceIDs, "active").Order("id asc").Find(&devices).Error
+	})
+	if err != nil {
+		return nil, route, fmt.Errorf("failed to get selected devices: %v", err)
+	}
+	return devices, route, nil
+}
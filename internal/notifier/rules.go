@@ -0,0 +1,136 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"autobot/internal/database"
+	"autobot/internal/models"
+	"autobot/internal/rules"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// processRules 按通知规则发送，每条命中的规则使用自己的消息和目标单独发送
+// 规则的占位符除了脚本结果，还可以使用 $task_name、$task_status、$task_error、
+// $previous_status 和 $consecutive_failures（结果中有同名字段时以结果为准）
+func (n *Notifier) processRules(ctx context.Context, task *models.Task, barkConfig *models.BarkConfig) error {
+	var logs []models.TaskLog
+	err := database.WithRetry(func(db *gorm.DB) error {
+		return db.Where("task_id = ? AND status IN ?", task.ID, []string{"success", "execution_failed", "script_failed"}).
+			Order("created_at desc").
+			Limit(rules.MaxHistory(barkConfig.Rules)).
+			Find(&logs).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get task history: %v", err)
+	}
+	if len(logs) == 0 {
+		n.logger.Warn("No finished execution found for notification rules")
+		return nil
+	}
+
+	state := runState(logs)
+	result := n.parseLogResult(&logs[0])
+	n.task, n.taskLog = task, &logs[0]
+	vars := ruleVars(task, &logs[0], state, result)
+
+	var failures []string
+	matched := 0
+	for i := range barkConfig.Rules {
+		rule := &barkConfig.Rules[i]
+		label := ruleLabel(rule, i)
+		logger := n.logger.With("rule", label, "trigger", rule.Trigger)
+
+		ok, err := rules.Match(rule, state, result)
+		if err != nil {
+			logger.Warn("Invalid notification rule", "error", err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		config := rule.Apply(barkConfig)
+		if !hasTargets(config) {
+			logger.Warn("Notification rule matched but has no device or channel")
+			continue
+		}
+		if !n.validatePlaceholders(config, vars) {
+			logger.Info("Placeholder validation failed, skipping rule")
+			continue
+		}
+
+		matched++
+		logger.Info("Notification rule matched", "status", state.Status, "consecutive_failures", state.ConsecutiveFailures)
+		ruleNotifier := *n
+		ruleNotifier.logger = logger
+		if err := ruleNotifier.dispatch(ctx, config, vars, task); err != nil {
+			failures = append(failures, fmt.Sprintf("规则 %s: %v", label, err))
+		}
+	}
+
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int("notifier.rules_matched", matched))
+	if matched == 0 {
+		n.logger.Debug("No notification rule matched", "status", state.Status)
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("%s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// runState 由最近的执行记录（按时间倒序，第一条为本次）计算状态
+func runState(logs []models.TaskLog) rules.RunState {
+	state := rules.RunState{Status: logs[0].Status}
+	if len(logs) > 1 {
+		state.PreviousStatus = logs[1].Status
+	}
+	for _, log := range logs {
+		if !rules.IsFailure(log.Status) {
+			break
+		}
+		state.ConsecutiveFailures++
+	}
+	return state
+}
+
+// ruleVars 规则可用的占位符：脚本结果加上执行状态
+func ruleVars(task *models.Task, taskLog *models.TaskLog, state rules.RunState, result map[string]interface{}) map[string]interface{} {
+	vars := make(map[string]interface{}, len(result)+5)
+	builtins := map[string]interface{}{
+		"task_name":            task.Name,
+		"task_status":          state.Status,
+		"task_error":           lastLine(taskLog.Error),
+		"previous_status":      state.PreviousStatus,
+		"consecutive_failures": float64(state.ConsecutiveFailures),
+	}
+	if errValue, ok := result["error"]; ok && errValue != nil {
+		builtins["task_error"] = errValue
+	}
+	for key, value := range builtins {
+		vars[key] = value
+	}
+	for key, value := range result {
+		vars[key] = value
+	}
+	return vars
+}
+
+// ruleLabel 规则名称，未命名时使用序号
+func ruleLabel(rule *models.NotificationRule, index int) string {
+	if rule.Name != "" {
+		return rule.Name
+	}
+	return fmt.Sprintf("#%d", index+1)
+}
+
+// lastLine 错误输出的最后一个非空行，Python traceback 的最后一行即异常信息
+func lastLine(text string) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
@@ -0,0 +1,515 @@
+package rules
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expression 编译后的结果匹配表达式
+//
+// 语法：
+//
+//	price < 100
+//	status == "ok" && count >= 3
+//	!(message contains "timeout") || retry
+//	title matches "^\\[告警\\]"
+//	data.items.0.name == 'foo'
+//
+// 左右操作数可以是结果字段路径（用 . 访问嵌套对象和数组下标）或字面量（数字、字符串、true、false、null）；
+// 比较运算符为 == != > >= < <= contains matches，逻辑运算符为 && || !（也可写作 and or not）。
+// 单独的字段按真值判断：不存在、null、false、0、空字符串和空数组为假。
+type Expression struct {
+	source string
+	root   node
+}
+
+// Compile 编译表达式
+func Compile(source string) (*Expression, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("expression is empty")
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q at position %d", p.tokens[p.pos].text, p.tokens[p.pos].pos)
+	}
+	return &Expression{source: source, root: root}, nil
+}
+
+// String 返回表达式源码
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Match 对结果求值
+func (e *Expression) Match(result map[string]interface{}) bool {
+	return truthy(e.root.eval(result))
+}
+
+// ===== 词法分析 =====
+
+type tokenKind int
+
+const (
+	tokIdent tokenKind = iota
+	tokString
+	tokNumber
+	tokOperator
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	num  float64
+	pos  int
+}
+
+// tokenize 拆分表达式
+func tokenize(source string) ([]token, error) {
+	var tokens []token
+	runes := []rune(source)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokLParen, text: "(", pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokRParen, text: ")", pos: i})
+			i++
+		case r == '"' || r == '\'':
+			start := i
+			var b strings.Builder
+			i++
+			for ; i < len(runes) && runes[i] != r; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+					switch runes[i] {
+					case 'n':
+						b.WriteRune('\n')
+					case 't':
+						b.WriteRune('\t')
+					default:
+						b.WriteRune(runes[i])
+					}
+					continue
+				}
+				b.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated string at position %d", start)
+			}
+			i++
+			tokens = append(tokens, token{kind: tokString, text: b.String(), pos: start})
+		case strings.ContainsRune("=!<>&|", r):
+			start := i
+			op := string(r)
+			if i+1 < len(runes) {
+				two := string(runes[i : i+2])
+				switch two {
+				case "==", "!=", ">=", "<=", "&&", "||":
+					op = two
+				}
+			}
+			if op == "=" || op == "&" || op == "|" {
+				return nil, fmt.Errorf("unknown operator %q at position %d", op, start)
+			}
+			i += len([]rune(op))
+			tokens = append(tokens, token{kind: tokOperator, text: op, pos: start})
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			i++
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.' || runes[i] == 'e' || runes[i] == 'E') {
+				i++
+			}
+			text := string(runes[start:i])
+			num, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q at position %d", text, start)
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: text, num: num, pos: start})
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '.') {
+				i++
+			}
+			text := string(runes[start:i])
+			switch text {
+			case "and":
+				tokens = append(tokens, token{kind: tokOperator, text: "&&", pos: start})
+			case "or":
+				tokens = append(tokens, token{kind: tokOperator, text: "||", pos: start})
+			case "not":
+				tokens = append(tokens, token{kind: tokOperator, text: "!", pos: start})
+			case "contains", "matches":
+				tokens = append(tokens, token{kind: tokOperator, text: text, pos: start})
+			default:
+				tokens = append(tokens, token{kind: tokIdent, text: text, pos: start})
+			}
+		default:
+			return nil, fmt.Errorf("unexpected character %q at position %d", r, i)
+		}
+	}
+	return tokens, nil
+}
+
+// ===== 语法分析 =====
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() *token {
+	if p.pos < len(p.tokens) {
+		return &p.tokens[p.pos]
+	}
+	return nil
+}
+
+func (p *parser) isOperator(text string) bool {
+	t := p.peek()
+	return t != nil && t.kind == tokOperator && t.text == text
+}
+
+// parseOr or := and ('||' and)*
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("||") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+// parseAnd and := unary ('&&' unary)*
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("&&") {
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+// parseUnary unary := '!' unary | '(' or ')' | comparison
+func (p *parser) parseUnary() (node, error) {
+	if p.isOperator("!") {
+		p.pos++
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand}, nil
+	}
+	if t := p.peek(); t != nil && t.kind == tokLParen {
+		p.pos++
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if t := p.peek(); t == nil || t.kind != tokRParen {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		p.pos++
+		return inner, nil
+	}
+	return p.parseComparison()
+}
+
+// parseComparison comparison := operand [op operand]
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.peek()
+	if t == nil || t.kind != tokOperator {
+		return left, nil
+	}
+	switch t.text {
+	case "==", "!=", ">", ">=", "<", "<=", "contains", "matches":
+	default:
+		return left, nil
+	}
+	op := t.text
+	p.pos++
+
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	cmp := compareNode{op: op, left: left, right: right}
+	if op == "matches" {
+		lit, ok := right.(literalNode)
+		pattern, isString := lit.value.(string)
+		if !ok || !isString {
+			return nil, fmt.Errorf("matches requires a string pattern")
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %v", pattern, err)
+		}
+		cmp.re = re
+	}
+	return cmp, nil
+}
+
+// parseOperand operand := path | literal
+func (p *parser) parseOperand() (node, error) {
+	t := p.peek()
+	if t == nil {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	p.pos++
+	switch t.kind {
+	case tokString:
+		return literalNode{t.text}, nil
+	case tokNumber:
+		return literalNode{t.num}, nil
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return literalNode{true}, nil
+		case "false":
+			return literalNode{false}, nil
+		case "null", "nil":
+			return literalNode{nil}, nil
+		}
+		return pathNode(strings.Split(t.text, ".")), nil
+	default:
+		return nil, fmt.Errorf("unexpected %q at position %d", t.text, t.pos)
+	}
+}
+
+// ===== 求值 =====
+
+type node interface {
+	eval(result map[string]interface{}) interface{}
+}
+
+type literalNode struct{ value interface{} }
+
+func (n literalNode) eval(map[string]interface{}) interface{} { return n.value }
+
+type pathNode []string
+
+// eval 按路径读取结果字段，不存在时返回 nil
+func (n pathNode) eval(result map[string]interface{}) interface{} {
+	var current interface{} = result
+	for _, key := range n {
+		switch v := current.(type) {
+		case map[string]interface{}:
+			current = v[key]
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(v) {
+				return nil
+			}
+			current = v[index]
+		default:
+			return nil
+		}
+	}
+	return current
+}
+
+// Lookup 按 a.b 形式的路径读取结果字段，数组元素用下标，不存在时返回 nil
+func Lookup(result map[string]interface{}, path string) interface{} {
+	return pathNode(strings.Split(path, ".")).eval(result)
+}
+
+type notNode struct{ operand node }
+
+func (n notNode) eval(result map[string]interface{}) interface{} {
+	return !truthy(n.operand.eval(result))
+}
+
+type andNode struct{ left, right node }
+
+func (n andNode) eval(result map[string]interface{}) interface{} {
+	return truthy(n.left.eval(result)) && truthy(n.right.eval(result))
+}
+
+type orNode struct{ left, right node }
+
+func (n orNode) eval(result map[string]interface{}) interface{} {
+	return truthy(n.left.eval(result)) || truthy(n.right.eval(result))
+}
+
+type compareNode struct {
+	op          string
+	left, right node
+	re          *regexp.Regexp
+}
+
+func (n compareNode) eval(result map[string]interface{}) interface{} {
+	left := n.left.eval(result)
+	right := n.right.eval(result)
+
+	switch n.op {
+	case "==":
+		return equal(left, right)
+	case "!=":
+		return !equal(left, right)
+	case "contains":
+		return contains(left, right)
+	case "matches":
+		if left == nil {
+			return false
+		}
+		return n.re.MatchString(toString(left))
+	}
+
+	// 大小比较：两边都能转为数字时按数字比较，都是字符串时按字典序比较
+	if a, ok := toNumber(left); ok {
+		if b, ok := toNumber(right); ok {
+			return compareOrdered(n.op, a, b)
+		}
+	}
+	a, aok := left.(string)
+	b, bok := right.(string)
+	if aok && bok {
+		return compareOrdered(n.op, a, b)
+	}
+	return false
+}
+
+// compareOrdered 按运算符比较有序值
+func compareOrdered[T float64 | string](op string, a, b T) bool {
+	switch op {
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	}
+	return false
+}
+
+// equal 比较相等：数字按数值比较（字符串形式的数字也可以），其他按值比较
+func equal(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if x, ok := toNumber(a); ok {
+		if y, ok := toNumber(b); ok {
+			return x == y
+		}
+	}
+	if x, ok := a.(bool); ok {
+		y, ok := b.(bool)
+		return ok && x == y
+	}
+	if _, ok := b.(bool); ok {
+		return false
+	}
+	switch a.(type) {
+	case map[string]interface{}, []interface{}:
+		return reflect.DeepEqual(a, b)
+	}
+	return toString(a) == toString(b)
+}
+
+// contains 字符串包含子串，或数组包含元素
+func contains(container, item interface{}) bool {
+	switch v := container.(type) {
+	case string:
+		return item != nil && strings.Contains(v, toString(item))
+	case []interface{}:
+		for _, element := range v {
+			if equal(element, item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// toNumber 将 JSON 数字或数字字符串转为 float64
+func toNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil || math.IsNaN(f) {
+			return 0, false
+		}
+		return f, true
+	}
+	return 0, false
+}
+
+// toString 将值转为字符串
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// truthy 真值判断
+func truthy(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	case string:
+		return v != ""
+	case []interface{}:
+		return len(v) > 0
+	case map[string]interface{}:
+		return len(v) > 0
+	}
+	return true
+}
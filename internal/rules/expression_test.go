@@ -0,0 +1,160 @@
+package rules
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// testResult 模拟脚本返回的 JSON 结果，数字为 float64
+func testResult(t *testing.T) map[string]interface{} {
+	t.Helper()
+	var result map[string]interface{}
+	err := json.Unmarshal([]byte(`{
+		"status": "ok",
+		"count": 3,
+		"price": "99.5",
+		"delta": -2,
+		"retry": false,
+		"message": "connection timeout",
+		"title": "[告警] disk full",
+		"tags": ["a", "b"],
+		"empty": [],
+		"data": {"items": [{"name": "foo"}, {"name": "bar"}]}
+	}`), &result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestExpressionMatch(t *testing.T) {
+	result := testResult(t)
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		// 比较
+		{`status == "ok"`, true},
+		{`status != 'ok'`, false},
+		{`count >= 3`, true},
+		{`count > 3`, false},
+		{`missing == null`, true},
+		{`missing`, false},
+		{`tags`, true},
+		{`empty`, false},
+
+		// && 优先于 ||，! 优先于 &&
+		{`true || false && false`, true},
+		{`(true || false) && false`, false},
+		{`false && false || true`, true},
+		{`!false && false`, false},
+		{`!(false && false)`, true},
+		{`!retry && count == 3`, true},
+		{`!!retry`, false},
+
+		// and / or / not 关键字
+		{`status == "ok" and count == 3`, true},
+		{`retry or count < 3`, false},
+		{`not retry and not (count < 3)`, true},
+		{`retry or not retry`, true},
+
+		// 数组下标和嵌套路径
+		{`data.items.0.name == 'foo'`, true},
+		{`data.items.1.name == "bar"`, true},
+		{`data.items.2.name == null`, true},
+		{`data.items.x.name`, false},
+		{`tags.1 == "b"`, true},
+
+		// 数字与字符串：能转为数字时按数值比较，否则按字符串
+		{`price < 100`, true},
+		{`price == 99.5`, true},
+		{`price > "100"`, false},
+		{`count == "3"`, true},
+		{`count == "3.0"`, true},
+		{`status > 1`, false},
+		{`status < 1`, false},
+		{`"abc" < "abd"`, true},
+		{`"10" > "9"`, true},
+
+		// 负数
+		{`delta == -2`, true},
+		{`delta < -1`, true},
+		{`delta > -2.5`, true},
+		{`delta>-3`, true},
+		{`-1 < 0`, true},
+
+		// contains / matches
+		{`message contains "timeout"`, true},
+		{`!(message contains "timeout") || retry`, false},
+		{`tags contains "b"`, true},
+		{`tags contains "c"`, false},
+		{`title matches "^\\[告警\\]"`, true},
+		{`missing matches ".*"`, false},
+
+		// 字符串转义
+		{`"it's" == 'it\'s'`, true},
+	}
+
+	for _, tt := range tests {
+		expr, err := Compile(tt.expr)
+		if err != nil {
+			t.Errorf("Compile(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := expr.Match(result); got != tt.want {
+			t.Errorf("%q = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestExpressionCompileErrors(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr string
+	}{
+		{``, "empty"},
+		{`   `, "empty"},
+		{`status == "ok`, "unterminated string"},
+		{`status == 'ok`, "unterminated string"},
+		{`title matches "("`, "invalid pattern"},
+		{`title matches "[a-"`, "invalid pattern"},
+		{`title matches count`, "string pattern"},
+		{`status = "ok"`, "unknown operator"},
+		{`a & b`, "unknown operator"},
+		{`a | b`, "unknown operator"},
+		{`(status == "ok"`, "closing parenthesis"},
+		{`status == "ok")`, "unexpected"},
+		{`status ==`, "end of expression"},
+		{`count > 1 2`, "unexpected"},
+		{`count # 1`, "unexpected character"},
+		{`1.2.3 > 0`, "invalid number"},
+		{`&& status`, "unexpected"},
+	}
+
+	for _, tt := range tests {
+		_, err := Compile(tt.expr)
+		if err == nil {
+			t.Errorf("Compile(%q) succeeded, want error containing %q", tt.expr, tt.wantErr)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("Compile(%q) error = %q, want it to contain %q", tt.expr, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	result := testResult(t)
+
+	if got := Lookup(result, "data.items.1.name"); got != "bar" {
+		t.Errorf("Lookup(data.items.1.name) = %v, want bar", got)
+	}
+	if got := Lookup(result, "data.items.-1.name"); got != nil {
+		t.Errorf("Lookup with negative index = %v, want nil", got)
+	}
+	if got := Lookup(result, "status.length"); got != nil {
+		t.Errorf("Lookup through a string = %v, want nil", got)
+	}
+}
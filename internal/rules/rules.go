@@ -0,0 +1,169 @@
+// Package rules 根据执行状态和结果判断通知规则和设备路由是否命中
+package rules
+
+import (
+	"fmt"
+
+	"autobot/internal/models"
+)
+
+// RunState 本次执行及历史状态
+type RunState struct {
+	Status              string // 本次执行状态
+	PreviousStatus      string // 上一次执行状态，没有历史执行时为空
+	ConsecutiveFailures int    // 截至本次的连续失败次数，本次成功时为 0
+}
+
+// Succeeded 本次执行是否成功
+func (s RunState) Succeeded() bool {
+	return s.Status == "success"
+}
+
+// previousSucceeded 上一次执行是否成功
+func (s RunState) previousSucceeded() bool {
+	return s.PreviousStatus == "success"
+}
+
+// IsFailure 状态是否算作失败，interrupted 不计入
+func IsFailure(status string) bool {
+	return status == "execution_failed" || status == "script_failed"
+}
+
+// Match 判断规则是否命中
+func Match(rule *models.NotificationRule, state RunState, result map[string]interface{}) (bool, error) {
+	switch rule.Trigger {
+	case models.RuleOnSuccess:
+		return state.Succeeded(), nil
+	case models.RuleOnFailure:
+		return IsFailure(state.Status), nil
+	case models.RuleOnStateChange:
+		if state.PreviousStatus == "" {
+			return false, nil
+		}
+		return state.Succeeded() != state.previousSucceeded(), nil
+	case models.RuleOnRecovery:
+		return state.Succeeded() && IsFailure(state.PreviousStatus), nil
+	case models.RuleOnConsecutiveFailures:
+		return state.ConsecutiveFailures == threshold(rule), nil
+	case models.RuleOnResultMatch:
+		expr, err := Compile(rule.Expression)
+		if err != nil {
+			return false, err
+		}
+		return expr.Match(result), nil
+	default:
+		return false, fmt.Errorf("unknown trigger: %s", rule.Trigger)
+	}
+}
+
+// threshold 连续失败阈值，未配置时为 1
+func threshold(rule *models.NotificationRule) int {
+	if rule.Threshold < 1 {
+		return 1
+	}
+	return rule.Threshold
+}
+
+// MaxHistory 判断规则需要查询的历史执行条数（含本次）
+func MaxHistory(rules []models.NotificationRule) int {
+	n := 2
+	for i := range rules {
+		if rules[i].Trigger == models.RuleOnConsecutiveFailures && threshold(&rules[i])+1 > n {
+			n = threshold(&rules[i]) + 1
+		}
+	}
+	return n
+}
+
+// Validate 校验规则配置
+func Validate(rules []models.NotificationRule) error {
+	for i, rule := range rules {
+		label := rule.Name
+		if label == "" {
+			label = fmt.Sprintf("#%d", i+1)
+		}
+		switch rule.Trigger {
+		case models.RuleOnSuccess, models.RuleOnFailure, models.RuleOnStateChange, models.RuleOnRecovery:
+		case models.RuleOnConsecutiveFailures:
+			if rule.Threshold < 1 {
+				return fmt.Errorf("rule %s: threshold must be at least 1", label)
+			}
+		case models.RuleOnResultMatch:
+			if _, err := Compile(rule.Expression); err != nil {
+				return fmt.Errorf("rule %s: invalid expression: %v", label, err)
+			}
+		case "":
+			return fmt.Errorf("rule %s: trigger is required", label)
+		default:
+			return fmt.Errorf("rule %s: unknown trigger %q", label, rule.Trigger)
+		}
+	}
+	return nil
+}
+
+// MatchRoute 返回第一条命中的设备路由的序号，没有命中时返回 -1
+// level 为渲染后的通知级别，为空时按 Bark 的默认级别 active 匹配
+func MatchRoute(routes []models.BarkRoute, level string, result map[string]interface{}) (int, error) {
+	if level == "" {
+		level = "active"
+	}
+	for i := range routes {
+		route := &routes[i]
+		if len(route.Levels) > 0 && !containsString(route.Levels, level) {
+			continue
+		}
+		if route.Expression != "" {
+			expr, err := Compile(route.Expression)
+			if err != nil {
+				return -1, fmt.Errorf("route %s: %v", RouteLabel(route, i), err)
+			}
+			if !expr.Match(result) {
+				continue
+			}
+		}
+		return i, nil
+	}
+	return -1, nil
+}
+
+// RouteLabel 路由名称，未命名时使用序号
+func RouteLabel(route *models.BarkRoute, index int) string {
+	if route.Name != "" {
+		return route.Name
+	}
+	return fmt.Sprintf("#%d", index+1)
+}
+
+// ValidateRoutes 校验设备路由配置
+func ValidateRoutes(routes []models.BarkRoute) error {
+	for i := range routes {
+		route := &routes[i]
+		label := RouteLabel(route, i)
+		if len(route.GroupIDs) == 0 {
+			return fmt.Errorf("route %s: at least one device group is required", label)
+		}
+		if route.Expression != "" {
+			if _, err := Compile(route.Expression); err != nil {
+				return fmt.Errorf("route %s: invalid expression: %v", label, err)
+			}
+		}
+		for _, level := range route.Levels {
+			switch level {
+			case "active", "timeSensitive", "passive", "critical":
+			default:
+				return fmt.Errorf("route %s: unknown level %q", label, level)
+			}
+		}
+	}
+	return nil
+}
+
+// containsString 列表中是否包含 value
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -5,11 +5,16 @@ import (
 	"autobot/internal/executor"
 	"autobot/internal/models"
 	"autobot/internal/timeutils"
-	"log"
+	"autobot/internal/tracing"
+	"context"
+	"log/slog"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/robfig/cron/v3"
+	"go.opentelemetry.io/otel/attribute"
 	"gorm.io/gorm"
 )
 
@@ -18,20 +23,33 @@ type Scheduler struct {
 	cron         *cron.Cron
 	entries      map[uint]cron.EntryID // 任务ID -> cron 条目ID 的映射
 	mutex        sync.RWMutex          // 保护 entries 映射的读写锁
+	cronExprs    map[uint]string       // 任务ID -> 已调度的 cron 表达式，用于同步其他实例的修改
 	cleanupMutex sync.Mutex            // 防止 cleanupDeletedTasks 并发执行
+	paused       atomic.Bool           // 全局暂停：保留调度条目但跳过执行
+	running      atomic.Bool           // 调度器是否正在运行（多实例时仅 Leader 运行）
+	maintenance  sync.Once             // 维护任务只注册一次，Start 可能被多次调用
 }
 
 // NewScheduler 创建新的调度器
 func NewScheduler() *Scheduler {
 	return &Scheduler{
-		cron:    cron.New(cron.WithSeconds()),
-		entries: make(map[uint]cron.EntryID),
+		cron:      cron.New(cron.WithSeconds()),
+		entries:   make(map[uint]cron.EntryID),
+		cronExprs: make(map[uint]string),
 	}
 }
 
 // Start 启动调度器
+// 多实例部署时由 Leader 选举在成为 Leader 后调用，失去 Leader 身份后调用 Stop，可反复调用
 func (s *Scheduler) Start() {
+	// 恢复持久化的暂停状态
+	s.refreshPaused()
+	if s.IsPaused() {
+		slog.Warn("Task scheduler is paused, scheduled executions will be skipped until resumed")
+	}
+
 	s.cron.Start()
+	s.running.Store(true)
 
 	// 加载所有活跃的任务
 	s.loadActiveTasks()
@@ -39,18 +57,65 @@ func (s *Scheduler) Start() {
 	// 立即执行一次清理，移除可能存在的已删除任务
 	s.cleanupDeletedTasks()
 
-	// 添加定期清理任务，每小时检查一次已删除的任务
-	s.cron.AddFunc("0 0 * * * *", func() {
-		s.cleanupDeletedTasks()
+	s.maintenance.Do(func() {
+		// 添加定期清理任务，每小时检查一次已删除的任务
+		s.cron.AddFunc("0 0 * * * *", func() {
+			s.cleanupDeletedTasks()
+		})
+
+		// 定期同步数据库中的任务和暂停状态，其他实例上的修改不会直接通知到本调度器
+		s.cron.AddFunc("*/15 * * * * *", func() {
+			s.syncTasks()
+		})
 	})
 
-	// log.Println("Task scheduler started") // 减少启动日志
+	slog.Info("Task scheduler started", "entries", s.GetScheduledTasks())
 }
 
-// Stop 停止调度器
+// Stop 停止调度器，不再触发新的执行
+// 正在运行的执行由 executor.Shutdown 负责等待
 func (s *Scheduler) Stop() {
+	if !s.running.Swap(false) {
+		return
+	}
 	s.cron.Stop()
-	log.Println("Task scheduler stopped")
+	slog.Info("Task scheduler stopped")
+}
+
+// IsRunning 调度器是否正在运行
+func (s *Scheduler) IsRunning() bool {
+	return s.running.Load()
+}
+
+// refreshPaused 从数据库读取暂停状态
+func (s *Scheduler) refreshPaused() {
+	paused, _ := strconv.ParseBool(database.GetSetting(models.SettingSchedulerPaused, "false"))
+	s.paused.Store(paused)
+}
+
+// Pause 全局暂停调度，不修改各任务的激活状态
+func (s *Scheduler) Pause() error {
+	if err := database.SetSetting(models.SettingSchedulerPaused, "true"); err != nil {
+		return err
+	}
+	s.paused.Store(true)
+	slog.Info("Task scheduler paused")
+	return nil
+}
+
+// Resume 恢复全局调度
+func (s *Scheduler) Resume() error {
+	if err := database.SetSetting(models.SettingSchedulerPaused, "false"); err != nil {
+		return err
+	}
+	s.paused.Store(false)
+	slog.Info("Task scheduler resumed")
+	return nil
+}
+
+// IsPaused 调度器是否处于全局暂停状态
+func (s *Scheduler) IsPaused() bool {
+	return s.paused.Load()
 }
 
 // loadActiveTasks 加载所有活跃的任务
@@ -61,17 +126,17 @@ func (s *Scheduler) loadActiveTasks() {
 		return db.Where("status = ?", "active").Find(&tasks).Error
 	})
 	if err != nil {
-		log.Printf("Failed to load active tasks: %v", err)
+		slog.Error("Failed to load active tasks", "error", err)
 		return
 	}
 
 	for _, task := range tasks {
 		if err := s.AddTask(&task); err != nil {
-			log.Printf("Failed to add task %d to scheduler: %v", task.ID, err)
+			slog.Error("Failed to add task to scheduler", "task_id", task.ID, "error", err)
 		}
 	}
 
-	// log.Printf("Loaded %d active tasks", len(tasks)) // 减少加载日志
+	slog.Debug("Loaded active tasks", "count", len(tasks))
 }
 
 // AddTask 添加任务到调度器
@@ -81,6 +146,7 @@ func (s *Scheduler) AddTask(task *models.Task) error {
 	if entryID, exists := s.entries[task.ID]; exists {
 		s.cron.Remove(entryID)
 		delete(s.entries, task.ID)
+		delete(s.cronExprs, task.ID)
 	}
 	s.mutex.Unlock()
 
@@ -91,7 +157,21 @@ func (s *Scheduler) AddTask(task *models.Task) error {
 
 	// 添加新的任务到调度器
 	entryID, err := s.cron.AddFunc(task.CronExpr, func() {
-		// log.Printf("Executing scheduled task: %s (ID: %d)", task.Name, task.ID) // 减少执行日志
+		// 每次触发作为一条 trace 的根 span
+		ctx, span := tracing.Start(context.Background(), "scheduler.tick",
+			attribute.Int64("task.id", int64(task.ID)),
+			attribute.String("task.cron_expr", task.CronExpr),
+		)
+		defer span.End()
+
+		logger := slog.With("task_id", task.ID)
+		logger.Debug("Cron tick", "trace_id", tracing.TraceID(ctx))
+
+		// 全局暂停时跳过本次执行
+		if s.IsPaused() {
+			span.SetAttributes(attribute.String("scheduler.skipped", "paused"))
+			return
+		}
 
 		// 更新任务的最后执行时间
 		now := time.Now()
@@ -102,12 +182,13 @@ func (s *Scheduler) AddTask(task *models.Task) error {
 			return db.First(&latestTask, task.ID).Error
 		})
 		if err != nil {
-			log.Printf("Failed to get latest task config for task %d: %v", task.ID, err)
+			logger.Error("Failed to get latest task config", "error", err)
+			tracing.RecordError(span, err)
 			// 如果任务不存在（可能已被删除），从调度器中移除该任务
 			// 使用 goroutine 避免在回调中直接操作映射导致死锁
 			go func(taskID uint) {
 				s.RemoveTask(taskID)
-				log.Printf("Removed deleted task %d from scheduler", taskID)
+				logger.Info("Removed deleted task from scheduler")
 			}(task.ID)
 			return
 		}
@@ -115,11 +196,12 @@ func (s *Scheduler) AddTask(task *models.Task) error {
 		// 检查时间排除
 		timeExclusionConfig, err := latestTask.GetTimeExclusionConfig()
 		if err != nil {
-			log.Printf("Failed to parse time exclusion config for task %d: %v", task.ID, err)
+			logger.Warn("Failed to parse time exclusion config", "error", err)
 		} else {
 			excluded, reason := timeutils.IsTimeExcluded(now, timeExclusionConfig)
 			if excluded {
-				log.Printf("Skipping task execution due to time exclusion: %s (ID: %d) - %s", latestTask.Name, task.ID, reason)
+				logger.Info("Skipping task execution due to time exclusion", "task_name", latestTask.Name, "reason", reason)
+				span.SetAttributes(attribute.String("scheduler.skipped", "time_exclusion"))
 
 				// 对于时间排除的任务，不需要立即更新数据库，减少不必要的写入操作
 				// 下次执行时间将由正常的调度逻辑计算
@@ -140,7 +222,7 @@ func (s *Scheduler) AddTask(task *models.Task) error {
 				nextRun = &next
 			}
 		} else {
-			log.Printf("Failed to parse cron expression '%s' during execution for task %d: %v", latestTask.CronExpr, task.ID, err)
+			logger.Warn("Failed to parse cron expression during execution", "cron_expr", latestTask.CronExpr, "error", err)
 		}
 
 		// 更新执行时间 - 使用重试机制，忽略错误（非关键操作）
@@ -153,7 +235,7 @@ func (s *Scheduler) AddTask(task *models.Task) error {
 		})
 
 		// 执行任务
-		executor.ExecuteTask(&latestTask)
+		executor.ExecuteTask(ctx, &latestTask)
 	})
 
 	if err != nil {
@@ -163,6 +245,7 @@ func (s *Scheduler) AddTask(task *models.Task) error {
 	// 保存 entryID
 	s.mutex.Lock()
 	s.entries[task.ID] = entryID
+	s.cronExprs[task.ID] = task.CronExpr
 	s.mutex.Unlock()
 
 	// 计算下次执行时间（考虑时间排除）
@@ -184,10 +267,10 @@ func (s *Scheduler) AddTask(task *models.Task) error {
 			return db.Model(&models.Task{}).Where("id = ?", task.ID).Update("next_run", nextRun).Error
 		})
 	} else {
-		log.Printf("Failed to parse cron expression '%s' for task %d: %v", task.CronExpr, task.ID, err)
+		slog.Warn("Failed to parse cron expression", "task_id", task.ID, "cron_expr", task.CronExpr, "error", err)
 	}
 
-	// log.Printf("Task added to scheduler: %s (ID: %d)", task.Name, task.ID) // 减少添加日志
+	slog.Debug("Task added to scheduler", "task_id", task.ID, "task_name", task.Name, "cron_expr", task.CronExpr)
 	return nil
 }
 
@@ -198,7 +281,8 @@ func (s *Scheduler) RemoveTask(taskID uint) {
 	if entryID, exists := s.entries[taskID]; exists {
 		s.cron.Remove(entryID)
 		delete(s.entries, taskID)
-		// log.Printf("Task removed from scheduler: ID %d", taskID) // 减少移除日志
+		delete(s.cronExprs, taskID)
+		slog.Debug("Task removed from scheduler", "task_id", taskID)
 	}
 }
 
@@ -212,7 +296,11 @@ func (s *Scheduler) UpdateTask(task *models.Task) error {
 }
 
 // GetScheduledTasks 获取当前调度的任务数量
+// 调度器未运行（已停止或本实例不是 Leader）时为 0
 func (s *Scheduler) GetScheduledTasks() int {
+	if !s.IsRunning() {
+		return 0
+	}
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
 	return len(s.entries)
@@ -223,6 +311,80 @@ func (s *Scheduler) ListEntries() []cron.Entry {
 	return s.cron.Entries()
 }
 
+// ScheduledEntry 任务的调度条目
+type ScheduledEntry struct {
+	TaskID   uint      `json:"task_id"`
+	CronExpr string    `json:"cron_expr"`
+	Next     time.Time `json:"next"`
+	Prev     time.Time `json:"prev"`
+}
+
+// ScheduledEntries 列出已调度任务及其下次触发时间，按下次触发时间排序
+// 不包括维护用的内部条目；调度器未运行（已停止或本实例不是 Leader）时本实例不会触发任何任务，返回空列表
+func (s *Scheduler) ScheduledEntries() []ScheduledEntry {
+	if !s.IsRunning() {
+		return []ScheduledEntry{}
+	}
+
+	s.mutex.RLock()
+	taskIDs := make(map[cron.EntryID]uint, len(s.entries))
+	for taskID, entryID := range s.entries {
+		taskIDs[entryID] = taskID
+	}
+	cronExprs := make(map[uint]string, len(s.cronExprs))
+	for taskID, expr := range s.cronExprs {
+		cronExprs[taskID] = expr
+	}
+	s.mutex.RUnlock()
+
+	// cron.Entries 已按下次触发时间排序
+	result := make([]ScheduledEntry, 0, len(taskIDs))
+	for _, entry := range s.ListEntries() {
+		taskID, ok := taskIDs[entry.ID]
+		if !ok {
+			continue
+		}
+		result = append(result, ScheduledEntry{
+			TaskID:   taskID,
+			CronExpr: cronExprs[taskID],
+			Next:     entry.Next,
+			Prev:     entry.Prev,
+		})
+	}
+	return result
+}
+
+// syncTasks 将数据库中的活跃任务同步到调度器
+// 新增或修改了 cron 表达式的任务重新调度，不再活跃的任务由 cleanupDeletedTasks 移除
+func (s *Scheduler) syncTasks() {
+	s.refreshPaused()
+
+	var tasks []models.Task
+	err := database.WithRetry(func(db *gorm.DB) error {
+		return db.Where("status = ?", "active").Find(&tasks).Error
+	})
+	if err != nil {
+		slog.Error("Failed to sync active tasks", "error", err)
+		return
+	}
+
+	for i := range tasks {
+		task := tasks[i]
+		s.mutex.RLock()
+		scheduledExpr, exists := s.cronExprs[task.ID]
+		s.mutex.RUnlock()
+
+		if exists && scheduledExpr == task.CronExpr {
+			continue
+		}
+		if err := s.AddTask(&task); err != nil {
+			slog.Error("Failed to sync task to scheduler", "task_id", task.ID, "error", err)
+		}
+	}
+
+	s.cleanupDeletedTasks()
+}
+
 // cleanupDeletedTasks 清理已删除的任务
 func (s *Scheduler) cleanupDeletedTasks() {
 	// 防止并发执行清理操作
@@ -247,7 +409,7 @@ func (s *Scheduler) cleanupDeletedTasks() {
 		return db.Where("id IN ? AND status = ?", scheduledTaskIDs, "active").Find(&activeTasks).Error
 	})
 	if err != nil {
-		log.Printf("Failed to query active tasks during cleanup after retries: %v", err)
+		slog.Error("Failed to query active tasks during cleanup after retries", "error", err)
 		return
 	}
 
@@ -263,11 +425,11 @@ func (s *Scheduler) cleanupDeletedTasks() {
 		if !activeTaskMap[taskID] {
 			s.RemoveTask(taskID) // RemoveTask 内部已经有锁保护
 			removedCount++
-			log.Printf("Cleaned up deleted/inactive task %d from scheduler", taskID)
+			slog.Info("Cleaned up deleted/inactive task from scheduler", "task_id", taskID)
 		}
 	}
 
 	if removedCount > 0 {
-		log.Printf("Scheduler cleanup completed: removed %d deleted/inactive tasks", removedCount)
+		slog.Info("Scheduler cleanup completed", "removed", removedCount)
 	}
 }
@@ -0,0 +1,138 @@
+// Package throttle Bark 推送限流：按设备和服务器的令牌桶，以及设备的免打扰时段
+//
+// 令牌桶保存在进程内存中，容量为周期内的最大推送数，令牌按周期匀速补充；
+// 重启后桶为满，多实例部署时各实例分别计数。
+package throttle
+
+import (
+	"autobot/internal/timeutils"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Limit 一个限额，Max 为 0 表示不限制
+type Limit struct {
+	Key    string        // 令牌桶标识，例如 device:KEY、server:1
+	Name   string        // 超限时的说明
+	Max    int           // 周期内最多推送数
+	Period time.Duration // 周期
+}
+
+// bucket 令牌桶
+type bucket struct {
+	capacity float64
+	tokens   float64
+	last     time.Time
+}
+
+// Limiter 令牌桶集合
+type Limiter struct {
+	mutex   sync.Mutex
+	buckets map[string]*bucket
+}
+
+// New 创建限流器
+func New() *Limiter {
+	return &Limiter{buckets: make(map[string]*bucket)}
+}
+
+// Allow 检查所有限额，全部有余量时各扣除一个令牌并返回 true；
+// 否则不扣除任何令牌，返回第一个超限的限额
+func (l *Limiter) Allow(now time.Time, limits ...Limit) (bool, *Limit) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	active := make([]*bucket, 0, len(limits))
+	for i := range limits {
+		limit := &limits[i]
+		if limit.Max <= 0 || limit.Period <= 0 {
+			continue
+		}
+		b := l.refill(limit, now)
+		if b.tokens < 1 {
+			return false, limit
+		}
+		active = append(active, b)
+	}
+	for _, b := range active {
+		b.tokens--
+	}
+	return true, nil
+}
+
+// refill 按经过的时间补充令牌，限额修改后按新容量调整
+func (l *Limiter) refill(limit *Limit, now time.Time) *bucket {
+	key := fmt.Sprintf("%s/%s", limit.Key, limit.Period)
+	capacity := float64(limit.Max)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{capacity: capacity, tokens: capacity, last: now}
+		l.buckets[key] = b
+		return b
+	}
+	b.capacity = capacity
+
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += elapsed.Seconds() * capacity / limit.Period.Seconds()
+		b.last = now
+	}
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+	return b
+}
+
+// PerHour 每小时限额
+func PerHour(key, name string, max int) Limit {
+	return Limit{Key: key, Name: name + " hourly limit", Max: max, Period: time.Hour}
+}
+
+// PerDay 每天限额
+func PerDay(key, name string, max int) Limit {
+	return Limit{Key: key, Name: name + " daily limit", Max: max, Period: 24 * time.Hour}
+}
+
+// ValidateQuietHours 检查免打扰时段，开始和结束都为空表示不启用
+func ValidateQuietHours(start, end string) error {
+	if start == "" && end == "" {
+		return nil
+	}
+	startMin, err := timeutils.ParseClock(start)
+	if err != nil {
+		return err
+	}
+	endMin, err := timeutils.ParseClock(end)
+	if err != nil {
+		return err
+	}
+	if startMin == endMin {
+		return fmt.Errorf("quiet hours start and end must differ")
+	}
+	return nil
+}
+
+// QuietUntil now 是否处于免打扰时段（可跨午夜），是则返回时段结束时间
+func QuietUntil(start, end string, now time.Time) (time.Time, bool) {
+	startMin, err := timeutils.ParseClock(start)
+	if err != nil {
+		return time.Time{}, false
+	}
+	endMin, err := timeutils.ParseClock(end)
+	if err != nil || startMin == endMin {
+		return time.Time{}, false
+	}
+
+	current := now.Hour()*60 + now.Minute()
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch {
+	case startMin < endMin && current >= startMin && current < endMin:
+		return midnight.Add(time.Duration(endMin) * time.Minute), true
+	case startMin > endMin && current >= startMin:
+		return midnight.AddDate(0, 0, 1).Add(time.Duration(endMin) * time.Minute), true
+	case startMin > endMin && current < endMin:
+		return midnight.Add(time.Duration(endMin) * time.Minute), true
+	}
+	return time.Time{}, false
+}
@@ -3,7 +3,8 @@ package timeutils
 import (
 	"autobot/internal/models"
 	"fmt"
-	"log"
+	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/robfig/cron/v3"
@@ -35,7 +36,7 @@ func isRuleMatched(checkTime time.Time, rule models.TimeExclusionRule) (bool, st
 	case "date_range":
 		return isDateRangeRuleMatched(checkTime, rule)
 	default:
-		log.Printf("Unknown time exclusion rule type: %s", rule.Type)
+		slog.Warn("Unknown time exclusion rule type", "type", rule.Type)
 		return false, ""
 	}
 }
@@ -48,13 +49,13 @@ func isDailyRuleMatched(checkTime time.Time, rule models.TimeExclusionRule) (boo
 
 	startTime, err := time.Parse("15:04", rule.StartTime)
 	if err != nil {
-		log.Printf("Failed to parse start time '%s': %v", rule.StartTime, err)
+		slog.Warn("Failed to parse start time", "value", rule.StartTime, "error", err)
 		return false, ""
 	}
 
 	endTime, err := time.Parse("15:04", rule.EndTime)
 	if err != nil {
-		log.Printf("Failed to parse end time '%s': %v", rule.EndTime, err)
+		slog.Warn("Failed to parse end time", "value", rule.EndTime, "error", err)
 		return false, ""
 	}
 
@@ -130,13 +131,13 @@ func isDateRangeRuleMatched(checkTime time.Time, rule models.TimeExclusionRule)
 
 	startDate, err := time.Parse("2006-01-02", rule.StartDate)
 	if err != nil {
-		log.Printf("Failed to parse start date '%s': %v", rule.StartDate, err)
+		slog.Warn("Failed to parse start date", "value", rule.StartDate, "error", err)
 		return false, ""
 	}
 
 	endDate, err := time.Parse("2006-01-02", rule.EndDate)
 	if err != nil {
-		log.Printf("Failed to parse end date '%s': %v", rule.EndDate, err)
+		slog.Warn("Failed to parse end date", "value", rule.EndDate, "error", err)
 		return false, ""
 	}
 
@@ -183,3 +184,13 @@ func GetNextAllowedTime(schedule cron.Schedule, config *models.TimeExclusionConf
 	// 如果100次都被排除，返回默认的下次执行时间
 	return schedule.Next(fromTime)
 }
+
+// ParseClock 解析 HH:MM，返回当天的分钟数
+// 通知摘要的发送时间和设备免打扰时段共用，保证两处接受的格式一致
+func ParseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
@@ -0,0 +1,43 @@
+package version
+
+import (
+	"runtime/debug"
+	"time"
+)
+
+// Version 程序版本，构建时通过 -ldflags "-X autobot/internal/version.Version=v1.2.3" 设置
+var Version = "dev"
+
+// startedAt 进程启动时间
+var startedAt = time.Now()
+
+// StartedAt 进程启动时间
+func StartedAt() time.Time {
+	return startedAt
+}
+
+// Uptime 进程已运行时长
+func Uptime() time.Duration {
+	return time.Since(startedAt)
+}
+
+// Revision 构建时的 git 提交，未知时返回空字符串
+func Revision() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	revision, modified := "", false
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value == "true"
+		}
+	}
+	if revision != "" && modified {
+		revision += "-dirty"
+	}
+	return revision
+}
@@ -1,34 +1,187 @@
 package main
 
 import (
+	"autobot/internal/backup"
+	"autobot/internal/barkhistory"
+	"autobot/internal/config"
 	"autobot/internal/database"
 	"autobot/internal/executor"
+	"autobot/internal/gitsync"
 	"autobot/internal/handlers"
+	"autobot/internal/leader"
+	"autobot/internal/logging"
 	"autobot/internal/logmanager"
+	"autobot/internal/metrics"
 	"autobot/internal/middleware"
+	"autobot/internal/migrations"
+	"autobot/internal/notifier"
 	"autobot/internal/scheduler"
+	"autobot/internal/tracing"
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
+	// 加载配置：配置文件 < AUTOBOT_* 环境变量 < 命令行参数
+	cfg, args, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatal("Failed to load config:", err)
+	}
+
+	// 程序日志：级别和格式来自配置
+	if err := logging.Setup(os.Stderr, cfg.Log.Level, cfg.Log.Format); err != nil {
+		log.Fatal(err)
+	}
+
+	// 子命令：autobot restore <file>，在打开数据库之前执行
+	if len(args) > 0 && args[0] == "restore" {
+		if err := runRestore(cfg, args[1:]); err != nil {
+			logging.Fatal("Restore failed", "error", err)
+		}
+		return
+	}
+
 	// 初始化数据库
-	if err := database.InitDB(); err != nil {
-		log.Fatal("Failed to initialize database:", err)
+	if err := database.InitDB(database.Config{
+		Driver: cfg.Database.Driver,
+		DSN:    cfg.Database.DSN,
+	}); err != nil {
+		logging.Fatal("Failed to initialize database", "error", err)
 	}
 
-	// 初始化调度器
-	taskScheduler := scheduler.NewScheduler()
-	taskScheduler.Start()
-	defer taskScheduler.Stop()
+	// 子命令：autobot migrate up|down|status, autobot backup [file]
+	if len(args) > 0 {
+		switch args[0] {
+		case "migrate":
+			err = runMigrate(args[1:])
+		case "backup":
+			err = runBackup(cfg, args[1:])
+		default:
+			err = fmt.Errorf("unknown command: %s", args[0])
+		}
+		if err != nil {
+			logging.Fatal("Command failed", "command", args[0], "error", err)
+		}
+		return
+	}
+
+	// 执行未完成的迁移；数据库版本高于本程序时拒绝启动
+	if _, err := migrations.Up(database.GetDB()); err != nil {
+		logging.Fatal("Failed to migrate database", "error", err)
+	}
+
+	// 当前实例ID，多实例共享数据库时用于 Leader 选举和区分执行日志
+	instanceID := cfg.Server.InstanceID
+	if instanceID == "" {
+		instanceID = leader.DefaultInstanceID()
+	}
+	executor.SetInstanceID(instanceID)
+	executor.SetPythonBinary(cfg.Executor.Python)
+	executor.SetTimeout(time.Duration(cfg.Executor.Timeout))
+	barkhistory.SetMaxBarkRecords(cfg.Bark.MaxRecords)
+	barkhistory.SetRecordRetention(time.Duration(cfg.Bark.Retention))
+	notifier.SetDeliveryOptions(notifier.DeliveryOptions{
+		Timeout:         time.Duration(cfg.Bark.Timeout),
+		MaxAttempts:     cfg.Bark.MaxAttempts,
+		RetryBackoff:    time.Duration(cfg.Bark.RetryBackoff),
+		MaxRetryBackoff: time.Duration(cfg.Bark.MaxRetryBackoff),
+	})
+	notifier.SetHealthOptions(notifier.HealthOptions{
+		Interval:  time.Duration(cfg.Bark.HealthInterval),
+		Threshold: cfg.Bark.HealthThreshold,
+	})
+
+	// OpenTelemetry 追踪，未启用时 span 为 noop
+	shutdownTracing := func(context.Context) error { return nil }
+	if cfg.Tracing.Enabled {
+		shutdownTracing, err = tracing.Setup(context.Background(), tracing.Options{
+			Endpoint:    cfg.Tracing.Endpoint,
+			Insecure:    cfg.Tracing.Insecure,
+			ServiceName: cfg.Tracing.ServiceName,
+			InstanceID:  instanceID,
+			SampleRatio: cfg.Tracing.SampleRatio,
+		})
+		if err != nil {
+			logging.Fatal("Failed to initialize tracing", "error", err)
+		}
+		slog.Info("Tracing enabled", "endpoint", cfg.Tracing.Endpoint, "sample_ratio", cfg.Tracing.SampleRatio)
+	}
 
 	// 初始化日志管理器
-	logMgr := logmanager.NewLogManager()
+	logMgr := logmanager.NewLogManager(cfg.Logs.MaxLogsPerTask, cfg.Logs.MaxTotalLogs)
+
+	// 上次退出时未完成的执行不会再有结果，先标记为 interrupted
+	// 心跳已失效的其他实例遗留的执行也一并处理
+	logMgr.RecoverInterruptedLogs(instanceID, leader.LiveSince())
+
+	// 定时备份（仅 SQLite）
+	backupMgr := backup.NewManager(cfg.Backup.Dir, time.Duration(cfg.Backup.Interval), cfg.Backup.Keep)
+	if cfg.Database.Driver == database.DriverSQLite {
+		backupMgr.Start()
+	} else if cfg.Backup.Interval > 0 {
+		slog.Warn("Scheduled backups are only supported for SQLite, ignoring backup interval")
+	}
+
+	// 实例心跳，其他实例据此判断本实例的执行日志是否仍在进行
+	heartbeat := leader.NewHeartbeat(instanceID)
+	heartbeat.Start()
+
+	// 初始化调度器，只有获得租约的 Leader 实例才会运行调度
+	taskScheduler := scheduler.NewScheduler()
+	elector := leader.NewElector(instanceID, taskScheduler.Start, taskScheduler.Stop)
+	// Leader 定期处理崩溃实例遗留的 running 日志
+	elector.SetLeaderTick(func() {
+		if _, err := logMgr.RecoverOrphanedLogs(instanceID, leader.LiveSince()); err != nil {
+			return
+		}
+		if err := leader.PruneHeartbeats(); err != nil {
+			slog.Warn("Failed to prune instance heartbeats", "error", err)
+		}
+	})
+	elector.Start()
+
+	// 失败推送的重试只在 Leader 上进行
+	outboxWorker := notifier.NewOutboxWorker(elector.IsLeader)
+	outboxWorker.Start()
+
+	// Bark 服务器健康检查只在 Leader 上进行，结果写入数据库
+	healthChecker := notifier.NewHealthChecker(elector.IsLeader)
+	healthChecker.Start()
+
+	// 从 git 仓库同步任务定义，定时同步只在 Leader 上执行
+	var gitSyncer *gitsync.Syncer
+	if cfg.GitSync.Repo != "" {
+		gitSyncer = gitsync.NewSyncer(gitsync.Options{
+			Repo:     cfg.GitSync.Repo,
+			Dir:      cfg.GitSync.Dir,
+			Interval: time.Duration(cfg.GitSync.Interval),
+			Pull:     cfg.GitSync.Pull,
+			Prune:    cfg.GitSync.Prune,
+		}, taskScheduler, elector.IsLeader)
+		gitSyncer.Start()
+	}
 
 	// 设置全局调度器和日志管理器
 	handlers.SetScheduler(taskScheduler)
 	handlers.SetLogManager(logMgr)
+	handlers.SetElector(elector)
+	handlers.SetConfig(cfg)
+	handlers.SetBackupManager(backupMgr)
+	handlers.SetGitSyncer(gitSyncer)
+
+	// Prometheus 指标中抓取时读取的状态
+	metrics.RegisterScheduler(taskScheduler)
+	metrics.RegisterLogStats(logMgr.GetLogStats)
 
 	// 设置日志清理回调函数
 	executor.SetLogCleanupCallback(logMgr.CleanupLogsAfterExecution)
@@ -36,6 +189,15 @@ func main() {
 	// 设置 Gin 路由 - 使用发布模式减少日志输出
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.New()
+
+	// 请求日志：分配 request_id，按状态码分级记录，跳过配置的路径
+	// 放在 Recovery 之前，panic 恢复后的 500 也会被记录
+	requestLevel, _ := logging.ParseLevel(cfg.Log.Requests.Level)
+	r.Use(middleware.RequestLogger(middleware.RequestLogOptions{
+		Enabled:   cfg.Log.Requests.Enabled,
+		Level:     requestLevel,
+		SkipPaths: cfg.Log.Requests.SkipPaths,
+	}))
 	r.Use(gin.Recovery())
 
 	// 静态文件服务
@@ -44,6 +206,15 @@ func main() {
 	// 设置HTML模板路径
 	r.LoadHTMLGlob("web/templates/*")
 
+	// Prometheus 指标（可通过 metrics.token 单独鉴权）
+	if cfg.Metrics.Enabled {
+		r.GET("/metrics", handlers.MetricsHandler(cfg.Metrics.Token))
+	}
+
+	// 存活与就绪检查（无需鉴权，供容器编排和负载均衡使用）
+	r.GET("/healthz", handlers.Healthz)
+	r.GET("/readyz", handlers.Readyz)
+
 	// 公开路由（无需鉴权）
 	public := r.Group("/")
 	{
@@ -74,6 +245,9 @@ func main() {
 		// 鉴权相关API
 		api.POST("/logout", handlers.Logout)
 		api.GET("/me", handlers.GetCurrentUser)
+		api.POST("/tokens", handlers.CreateAPIToken)
+		api.GET("/tokens", handlers.GetAPITokens)
+		api.DELETE("/tokens/:id", handlers.DeleteAPIToken)
 		// 任务相关API
 		api.POST("/tasks", handlers.CreateTask)
 		api.GET("/tasks", handlers.GetTasks)
@@ -85,8 +259,13 @@ func main() {
 		api.POST("/validate-script", handlers.ValidateScript)
 		api.GET("/tasks/:id/result", handlers.GetTaskResult)
 		api.PUT("/tasks/:id/bark-config", handlers.UpdateBarkConfig)
+		api.POST("/tasks/:id/bark/preview", handlers.PreviewBarkConfig)
 		api.GET("/tasks/:id/bark-keys", handlers.GetTaskBarkKeys)
 
+		// 任务导入导出API
+		api.GET("/export", handlers.ExportTasks)
+		api.POST("/import", handlers.ImportTasks)
+
 		// 日志相关API
 		api.DELETE("/logs/all", handlers.DeleteAllLogs)
 		api.DELETE("/tasks/:id/logs", handlers.DeleteTaskLogs)
@@ -98,6 +277,7 @@ func main() {
 		api.GET("/bark/servers/:id", handlers.GetBarkServer)
 		api.PUT("/bark/servers/:id", handlers.UpdateBarkServer)
 		api.DELETE("/bark/servers/:id", handlers.DeleteBarkServer)
+		api.POST("/bark/servers/:id/check", handlers.CheckBarkServer)
 
 		// Bark设备管理API
 		api.POST("/bark/devices", handlers.CreateBarkDevice)
@@ -106,13 +286,103 @@ func main() {
 		api.GET("/bark/devices/:id", handlers.GetBarkDevice)
 		api.PUT("/bark/devices/:id", handlers.UpdateBarkDevice)
 		api.DELETE("/bark/devices/:id", handlers.DeleteBarkDevice)
+		api.POST("/bark/devices/:id/test", handlers.TestBarkDevice)
+
+		// Bark设备分组管理API
+		api.GET("/bark/groups", handlers.GetBarkDeviceGroups)
+		api.POST("/bark/groups", handlers.CreateBarkDeviceGroup)
+		api.GET("/bark/groups/:id", handlers.GetBarkDeviceGroup)
+		api.PUT("/bark/groups/:id", handlers.UpdateBarkDeviceGroup)
+		api.DELETE("/bark/groups/:id", handlers.DeleteBarkDeviceGroup)
+
+		// 通知渠道管理API
+		api.GET("/channels", handlers.GetChannels)
+		api.POST("/channels", handlers.CreateChannel)
+		api.GET("/channels/types", handlers.GetChannelTypes)
+		api.GET("/channels/selection", handlers.GetChannelsForSelection)
+		api.GET("/channels/:id", handlers.GetChannel)
+		api.PUT("/channels/:id", handlers.UpdateChannel)
+		api.DELETE("/channels/:id", handlers.DeleteChannel)
+		api.POST("/channels/:id/test", handlers.TestChannel)
 
 		// Bark历史记录API
 		api.GET("/bark/records", handlers.GetBarkRecords)
+		api.GET("/bark/records/export", handlers.ExportBarkRecords)
 		api.GET("/bark/stats", handlers.GetBarkStats)
 		api.DELETE("/bark/records/all", handlers.DeleteAllBarkRecords)
+		api.POST("/bark/records/:id/resend", handlers.ResendBarkRecord)
+		api.GET("/bark/records/:id/digest", handlers.GetBarkDigestRecords)
+		api.GET("/bark/outbox", handlers.GetBarkOutbox)
+
+		// 管理API
+		api.GET("/status", handlers.GetStatus)
+		api.GET("/admin/scheduler", handlers.GetSchedulerState)
+		api.POST("/admin/scheduler/pause", handlers.PauseScheduler)
+		api.POST("/admin/scheduler/resume", handlers.ResumeScheduler)
+		api.GET("/admin/cluster", handlers.GetClusterStatus)
+		api.GET("/admin/config", handlers.GetConfig)
+		api.POST("/admin/backups", handlers.CreateBackup)
+		api.GET("/admin/backups", handlers.GetBackups)
+		api.GET("/admin/backups/:name", handlers.DownloadBackup)
+		api.GET("/admin/gitsync", handlers.GetGitSyncStatus)
+		api.POST("/admin/gitsync/sync", handlers.TriggerGitSync)
+	}
+
+	srv := &http.Server{
+		Addr:    cfg.Server.Addr,
+		Handler: r,
+	}
+
+	go func() {
+		slog.Info("Server starting", "addr", cfg.Server.Addr, "instance", instanceID)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logging.Fatal("Server failed", "error", err)
+		}
+	}()
+
+	// 等待退出信号
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-quit
+	slog.Info("Shutting down", "signal", sig.String())
+
+	// 1. 停止定时触发，释放租约以便其他实例尽快接管
+	elector.Stop()
+	taskScheduler.Stop()
+	if gitSyncer != nil {
+		gitSyncer.Stop()
+	}
+
+	// HTTP 停机和等待执行各自使用完整的停机超时，慢请求不会占用执行的等待时间
+	shutdownTimeout := time.Duration(cfg.Server.ShutdownTimeout)
+
+	// 2. 停止接收新的HTTP请求（包括手动执行）
+	httpCtx, cancelHTTP := context.WithTimeout(context.Background(), shutdownTimeout)
+	if err := srv.Shutdown(httpCtx); err != nil {
+		slog.Error("HTTP server shutdown error", "error", err)
+	}
+	cancelHTTP()
+
+	// 3. 等待正在运行的执行，超时后终止剩余的脚本进程
+	execCtx, cancelExec := context.WithTimeout(context.Background(), shutdownTimeout)
+	if err := executor.Shutdown(execCtx); err != nil {
+		slog.Warn("Executor shutdown did not finish in time", "error", err)
+	}
+	cancelExec()
+
+	// 执行结束后再停止心跳
+	heartbeat.Stop()
+
+	outboxWorker.Stop()
+	healthChecker.Stop()
+	backupMgr.Stop()
+
+	// 4. 导出剩余的 span
+	tracingCtx, cancelTracing := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := shutdownTracing(tracingCtx); err != nil {
+		slog.Warn("Tracing shutdown error", "error", err)
 	}
+	cancelTracing()
 
-	log.Println("Server starting on port 8080...")
-	r.Run(":8080")
+	slog.Info("Shutdown complete")
 }
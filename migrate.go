@@ -0,0 +1,82 @@
+package main
+
+import (
+	"autobot/internal/database"
+	"autobot/internal/migrations"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+)
+
+// runMigrate 处理 autobot migrate up|down|status 子命令
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: autobot migrate up|down [steps]|status")
+	}
+
+	db := database.GetDB()
+
+	switch args[0] {
+	case "up":
+		ran, err := migrations.Up(db)
+		if err != nil {
+			return err
+		}
+		if len(ran) == 0 {
+			fmt.Println("Database is up to date")
+		}
+		for _, m := range ran {
+			fmt.Printf("Applied %04d_%s\n", m.Version, m.Name)
+		}
+		return nil
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid steps: %s", args[1])
+			}
+			steps = n
+		}
+		rolledBack, err := migrations.Down(db, steps)
+		if err != nil {
+			return err
+		}
+		if len(rolledBack) == 0 {
+			fmt.Println("No migrations to roll back")
+		}
+		for _, m := range rolledBack {
+			fmt.Printf("Rolled back %04d_%s\n", m.Version, m.Name)
+		}
+		return nil
+
+	case "status":
+		statuses, err := migrations.Status(db)
+		if err != nil {
+			return err
+		}
+		current, err := migrations.CurrentVersion(db)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Database version: %d, binary version: %d\n\n", current, migrations.Latest())
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, status := range statuses {
+			state := "pending"
+			appliedAt := ""
+			if status.Applied {
+				state = "applied"
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+		}
+		return w.Flush()
+
+	default:
+		return fmt.Errorf("unknown migrate command: %s", args[0])
+	}
+}
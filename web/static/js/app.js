@@ -323,6 +323,11 @@ const Utils = {
                         <span class="w-1.5 h-1.5 bg-blue-400 rounded-full mr-1.5"></span>
                         运行中
                     </span>`;
+        } else if (status === 'interrupted') {
+            return `<span class="inline-flex items-center px-2.5 py-0.5 rounded-full text-xs font-medium bg-yellow-100 text-yellow-800">
+                        <span class="w-1.5 h-1.5 bg-yellow-400 rounded-full mr-1.5"></span>
+                        已中断
+                    </span>`;
         } else {
             return `<span class="inline-flex items-center px-2.5 py-0.5 rounded-full text-xs font-medium bg-gray-100 text-gray-800">
                         <span class="w-1.5 h-1.5 bg-gray-400 rounded-full mr-1.5"></span>
@@ -5,38 +5,38 @@ let currentServerPage = 1;
 let currentDevicePage = 1;
 let currentEditingServerId = null;
 let currentEditingDeviceId = null;
+let currentEditingChannelId = null;
+let currentEditingGroupId = null;
 
 // 页面初始化
 document.addEventListener('DOMContentLoaded', function() {
     initializeTabs();
     loadServers();
     loadDevices();
+    loadGroups();
+    loadChannels();
     setupEventListeners();
 });
 
 // 初始化选项卡
 function initializeTabs() {
-    const serversTab = document.getElementById('servers-tab');
-    const devicesTab = document.getElementById('devices-tab');
-    const serversContent = document.getElementById('servers-content');
-    const devicesContent = document.getElementById('devices-content');
+    const tabs = [
+        [document.getElementById('servers-tab'), document.getElementById('servers-content')],
+        [document.getElementById('devices-tab'), document.getElementById('devices-content')],
+        [document.getElementById('groups-tab'), document.getElementById('groups-content')],
+        [document.getElementById('channels-tab'), document.getElementById('channels-content')]
+    ];
     
     // 设置初始状态
-    updateTabState(serversTab, true);
-    updateTabState(devicesTab, false);
-    
-    serversTab.addEventListener('click', function() {
-        updateTabState(serversTab, true);
-        updateTabState(devicesTab, false);
-        serversContent.classList.remove('hidden');
-        devicesContent.classList.add('hidden');
-    });
+    tabs.forEach(([tab], index) => updateTabState(tab, index === 0));
     
-    devicesTab.addEventListener('click', function() {
-        updateTabState(serversTab, false);
-        updateTabState(devicesTab, true);
-        serversContent.classList.add('hidden');
-        devicesContent.classList.remove('hidden');
+    tabs.forEach(([tab]) => {
+        tab.addEventListener('click', function() {
+            tabs.forEach(([other, content]) => {
+                updateTabState(other, other === tab);
+                content.classList.toggle('hidden', other !== tab);
+            });
+        });
     });
 }
 
@@ -65,10 +65,32 @@ function setupEventListeners() {
         saveDevice();
     });
     
+    // 分组表单提交
+    document.getElementById('group-form').addEventListener('submit', function(e) {
+        e.preventDefault();
+        saveGroup();
+    });
+    
+    // 渠道表单提交
+    document.getElementById('channel-form').addEventListener('submit', function(e) {
+        e.preventDefault();
+        saveChannel();
+    });
+    
+    // 切换渠道类型时填入配置示例
+    document.getElementById('channel-type').addEventListener('change', function() {
+        const config = document.getElementById('channel-config');
+        if (!currentEditingChannelId || !config.value.trim()) {
+            config.value = JSON.stringify(CHANNEL_CONFIG_EXAMPLES[this.value] || {}, null, 2);
+        }
+    });
+    
     // 模态框点击外部关闭
     window.addEventListener('click', function(e) {
         const serverModal = document.getElementById('server-modal');
         const deviceModal = document.getElementById('device-modal');
+        const groupModal = document.getElementById('group-modal');
+        const channelModal = document.getElementById('channel-modal');
         
         if (e.target === serverModal) {
             closeServerModal();
@@ -76,6 +98,12 @@ function setupEventListeners() {
         if (e.target === deviceModal) {
             closeDeviceModal();
         }
+        if (e.target === groupModal) {
+            closeGroupModal();
+        }
+        if (e.target === channelModal) {
+            closeChannelModal();
+        }
     });
 }
 
@@ -116,7 +144,7 @@ function renderServersTable(servers) {
     if (servers.length === 0) {
         tbody.innerHTML = `
             <tr>
-                <td colspan="7" class="px-6 py-12 text-center">
+                <td colspan="8" class="px-6 py-12 text-center">
                     <div class="flex flex-col items-center">
                         <i data-lucide="server" class="w-12 h-12 text-slate-300 mb-4"></i>
                         <h3 class="text-sm font-medium text-slate-900 mb-1">暂无服务器配置</h3>
@@ -134,12 +162,14 @@ function renderServersTable(servers) {
         return;
     }
     
+    const serverNames = Object.fromEntries(servers.map(server => [server.id, server.name]));
     tbody.innerHTML = servers.map(server => `
         <tr class="hover:bg-slate-50">
             <td class="px-6 py-4 whitespace-nowrap">
                 <div class="flex items-center">
                     <div class="text-sm font-medium text-slate-900">${escapeHtml(server.name)}</div>
                     ${server.is_default ? '<span class="ml-2 inline-flex items-center px-2 py-0.5 rounded text-xs font-medium bg-yellow-100 text-yellow-800">默认</span>' : ''}
+                    ${rateLimitBadge(server)}
                 </div>
             </td>
             <td class="px-6 py-4 whitespace-nowrap">
@@ -153,6 +183,10 @@ function renderServersTable(servers) {
                     ${server.status === 'active' ? '启用' : '禁用'}
                 </span>
             </td>
+            <td class="px-6 py-4 whitespace-nowrap">
+                ${healthBadge(server)}
+                ${server.fallback_server_id ? `<div class="text-xs text-slate-500 mt-1">备用：${escapeHtml(serverNames[server.fallback_server_id] || '#' + server.fallback_server_id)}</div>` : ''}
+            </td>
             <td class="px-6 py-4 whitespace-nowrap text-sm text-slate-600">
                 ${server.is_default ? '是' : '否'}
             </td>
@@ -161,6 +195,7 @@ function renderServersTable(servers) {
             </td>
             <td class="px-6 py-4 whitespace-nowrap text-right text-sm font-medium">
                 <div class="flex items-center justify-end space-x-2">
+                    <button onclick="checkServer(${server.id})" class="text-green-600 hover:text-green-900">检查</button>
                     <button onclick="editServer(${server.id})" class="text-blue-600 hover:text-blue-900">编辑</button>
                     <button onclick="deleteServer(${server.id}, '${escapeHtml(server.name)}')" class="text-red-600 hover:text-red-900">删除</button>
                 </div>
@@ -169,6 +204,61 @@ function renderServersTable(servers) {
     `).join('');
 }
 
+// 健康状态标签，附带响应时间和检查时间
+function healthBadge(server) {
+    const styles = {
+        healthy: ['bg-green-100 text-green-800', '健康'],
+        unhealthy: ['bg-red-100 text-red-800', '不可用']
+    };
+    const [style, label] = styles[server.health] || ['bg-slate-100 text-slate-600', '未检查'];
+    const details = [];
+    if (server.health === 'healthy') {
+        details.push(`${server.health_latency_ms} ms`);
+    }
+    if (server.health_failures > 0) {
+        details.push(`连续失败 ${server.health_failures} 次`);
+    }
+    if (server.health_checked_at) {
+        details.push(formatDateTime(server.health_checked_at));
+    }
+    return `
+        <span class="inline-flex items-center px-2 py-0.5 rounded-full text-xs font-medium ${style}" title="${escapeHtml(server.health_error || '')}">${label}</span>
+        ${details.length > 0 ? `<div class="text-xs text-slate-500 mt-1">${details.join(' · ')}</div>` : ''}
+    `;
+}
+
+// 立即检查服务器健康状态
+async function checkServer(serverId) {
+    try {
+        const response = await fetch(`/api/bark/servers/${serverId}/check`, { method: 'POST' });
+        const server = await response.json();
+        
+        if (!response.ok) {
+            showAlert('检查失败: ' + (server.error || '未知错误'), 'error');
+        } else if (server.health_error) {
+            showAlert(`服务器检查失败: ${server.health_error}`, 'error');
+        } else {
+            showAlert(`服务器正常，响应时间 ${server.health_latency_ms} ms`, 'success');
+        }
+        loadServers(currentServerPage);
+    } catch (error) {
+        console.error('Error checking server:', error);
+        showAlert('检查失败: ' + error.message, 'error');
+    }
+}
+
+// 推送限额标签
+function rateLimitBadge(item) {
+    const limits = [
+        item.rate_limit_hour ? `${item.rate_limit_hour}/时` : '',
+        item.rate_limit_day ? `${item.rate_limit_day}/天` : ''
+    ].filter(Boolean);
+    if (limits.length === 0) {
+        return '';
+    }
+    return `<span class="ml-2 inline-flex items-center px-2 py-0.5 rounded text-xs font-medium bg-orange-100 text-orange-800">限额 ${limits.join(' ')}</span>`;
+}
+
 // 渲染服务器分页
 function renderServersPagination(total, page, limit) {
     const totalPages = Math.ceil(total / limit);
@@ -206,14 +296,38 @@ function showServerModal(serverId = null) {
     
     if (serverId) {
         title.textContent = '编辑服务器';
-        loadServerData(serverId);
+        loadFallbackOptions(serverId).then(() => loadServerData(serverId));
     } else {
         title.textContent = '添加服务器';
+        loadFallbackOptions(null);
     }
     
     modal.classList.remove('hidden');
 }
 
+// 加载备用服务器选项，不包含正在编辑的服务器
+async function loadFallbackOptions(serverId) {
+    const select = document.getElementById('server-fallback');
+    select.innerHTML = '<option value="0">不转移</option>';
+    try {
+        const response = await fetch('/api/bark/servers?limit=100');
+        const data = await response.json();
+        
+        if (response.ok && data.servers) {
+            data.servers.forEach(server => {
+                if (server.id !== serverId && server.status === 'active') {
+                    const option = document.createElement('option');
+                    option.value = server.id;
+                    option.textContent = server.name;
+                    select.appendChild(option);
+                }
+            });
+        }
+    } catch (error) {
+        console.error('Error loading fallback options:', error);
+    }
+}
+
 // 加载服务器数据
 async function loadServerData(serverId) {
     try {
@@ -225,6 +339,10 @@ async function loadServerData(serverId) {
             document.getElementById('server-url').value = server.url || '';
             document.getElementById('server-description').value = server.description || '';
             document.getElementById('server-is-default').checked = server.is_default || false;
+            document.getElementById('server-timeout').value = server.timeout || '';
+            document.getElementById('server-rate-hour').value = server.rate_limit_hour || '';
+            document.getElementById('server-rate-day').value = server.rate_limit_day || '';
+            document.getElementById('server-fallback').value = server.fallback_server_id || 0;
         } else {
             showAlert('加载服务器数据失败: ' + (server.error || '未知错误'), 'error');
         }
@@ -240,6 +358,10 @@ async function saveServer() {
     const url = document.getElementById('server-url').value.trim();
     const description = document.getElementById('server-description').value.trim();
     const isDefault = document.getElementById('server-is-default').checked;
+    const timeout = parseInt(document.getElementById('server-timeout').value, 10) || 0;
+    const rateLimitHour = parseInt(document.getElementById('server-rate-hour').value, 10) || 0;
+    const rateLimitDay = parseInt(document.getElementById('server-rate-day').value, 10) || 0;
+    const fallbackServerId = parseInt(document.getElementById('server-fallback').value, 10) || 0;
     
     if (!name || !url) {
         showAlert('请填写必填字段', 'error');
@@ -250,7 +372,11 @@ async function saveServer() {
         name,
         url,
         description,
-        is_default: isDefault
+        is_default: isDefault,
+        timeout,
+        rate_limit_hour: rateLimitHour,
+        rate_limit_day: rateLimitDay,
+        fallback_server_id: fallbackServerId
     };
     
     try {
@@ -387,6 +513,9 @@ function renderDevicesTable(devices) {
                 <div class="flex items-center">
                     <div class="text-sm font-medium text-slate-900">${escapeHtml(device.name)}</div>
                     ${device.is_default ? '<span class="ml-2 inline-flex items-center px-2 py-0.5 rounded text-xs font-medium bg-yellow-100 text-yellow-800">默认</span>' : ''}
+                    ${device.encryption_algorithm ? `<span class="ml-2 inline-flex items-center px-2 py-0.5 rounded text-xs font-medium bg-purple-100 text-purple-800">${escapeHtml(device.encryption_algorithm.toUpperCase())}-${escapeHtml((device.encryption_mode || '').toUpperCase())}</span>` : ''}
+                    ${rateLimitBadge(device)}
+                    ${device.quiet_start ? `<span class="ml-2 inline-flex items-center px-2 py-0.5 rounded text-xs font-medium bg-slate-100 text-slate-700" title="${device.quiet_mode === 'delay' ? '推迟发送' : '静默推送'}">免打扰 ${escapeHtml(device.quiet_start)}-${escapeHtml(device.quiet_end)}</span>` : ''}
                 </div>
             </td>
             <td class="px-6 py-4 whitespace-nowrap">
@@ -411,6 +540,7 @@ function renderDevicesTable(devices) {
             </td>
             <td class="px-6 py-4 whitespace-nowrap text-right text-sm font-medium">
                 <div class="flex items-center justify-end space-x-2">
+                    <button onclick="testDevice(${device.id})" class="text-green-600 hover:text-green-900">测试</button>
                     <button onclick="editDevice(${device.id})" class="text-blue-600 hover:text-blue-900">编辑</button>
                     <button onclick="deleteDevice(${device.id}, '${escapeHtml(device.name)}')" class="text-red-600 hover:text-red-900">删除</button>
                 </div>
@@ -463,10 +593,26 @@ async function showDeviceModal(deviceId = null) {
     } else {
         title.textContent = '添加设备';
     }
+    toggleDeviceEncryption();
     
     modal.classList.remove('hidden');
 }
 
+// 切换加密配置输入框
+// GCM 每次推送必须使用新的 IV，不能指定固定 IV；ECB 不使用 IV；CBC 使用固定 IV 时提示
+function toggleDeviceEncryption() {
+    const enabled = document.getElementById('device-encryption-algorithm').value !== '';
+    const mode = document.getElementById('device-encryption-mode').value;
+    const iv = document.getElementById('device-encryption-iv');
+    document.getElementById('device-encryption-mode').disabled = !enabled;
+    document.getElementById('device-encryption-fields').classList.toggle('hidden', !enabled);
+    iv.classList.toggle('hidden', mode !== 'cbc');
+    if (mode !== 'cbc') {
+        iv.value = '';
+    }
+    document.getElementById('device-encryption-iv-warning').classList.toggle('hidden', mode !== 'cbc' || iv.value.trim() === '');
+}
+
 // 加载服务器选项
 async function loadServerOptions() {
     try {
@@ -503,6 +649,18 @@ async function loadDeviceData(deviceId) {
             document.getElementById('device-server').value = device.server_id || '';
             document.getElementById('device-description').value = device.description || '';
             document.getElementById('device-is-default').checked = device.is_default || false;
+            document.getElementById('device-encryption-algorithm').value = device.encryption_algorithm || '';
+            document.getElementById('device-encryption-mode').value = device.encryption_mode || 'cbc';
+            document.getElementById('device-encryption-iv').value = device.encryption_iv || '';
+            document.getElementById('device-rate-hour').value = device.rate_limit_hour || '';
+            document.getElementById('device-rate-day').value = device.rate_limit_day || '';
+            document.getElementById('device-quiet-start').value = device.quiet_start || '';
+            document.getElementById('device-quiet-end').value = device.quiet_end || '';
+            document.getElementById('device-quiet-mode').value = device.quiet_mode || 'passive';
+            document.getElementById('device-encryption-key').placeholder = device.has_encryption_key
+                ? '已设置，留空则保持不变'
+                : '密钥（16/24/32 位，与 App 中一致）';
+            toggleDeviceEncryption();
         } else {
             showAlert('加载设备数据失败: ' + (device.error || '未知错误'), 'error');
         }
@@ -530,8 +688,23 @@ async function saveDevice() {
         device_key: deviceKey,
         server_id: serverId ? parseInt(serverId) : 0,
         description,
-        is_default: isDefault
+        is_default: isDefault,
+        rate_limit_hour: parseInt(document.getElementById('device-rate-hour').value, 10) || 0,
+        rate_limit_day: parseInt(document.getElementById('device-rate-day').value, 10) || 0,
+        quiet_start: document.getElementById('device-quiet-start').value,
+        quiet_end: document.getElementById('device-quiet-end').value,
+        quiet_mode: document.getElementById('device-quiet-mode').value
     };
+
+    const algorithm = document.getElementById('device-encryption-algorithm').value;
+    const encryptionKey = document.getElementById('device-encryption-key').value;
+    data.encryption_algorithm = algorithm;
+    data.encryption_mode = algorithm ? document.getElementById('device-encryption-mode').value : '';
+    data.encryption_iv = algorithm ? document.getElementById('device-encryption-iv').value.trim() : '';
+    // 编辑时密钥留空表示保持原密钥
+    if (encryptionKey || !currentEditingDeviceId) {
+        data.encryption_key = algorithm ? encryptionKey : '';
+    }
     
     try {
         let response;
@@ -573,6 +746,23 @@ function editDevice(deviceId) {
     showDeviceModal(deviceId);
 }
 
+// 发送测试推送
+async function testDevice(deviceId) {
+    try {
+        const response = await fetch(`/api/bark/devices/${deviceId}/test`, { method: 'POST' });
+        const result = await response.json();
+        
+        if (response.ok) {
+            showAlert(result.message || '测试推送已发送', 'success');
+        } else {
+            showAlert(result.error || '测试推送发送失败', 'error');
+        }
+    } catch (error) {
+        console.error('Error testing device:', error);
+        showAlert('测试推送发送失败: ' + error.message, 'error');
+    }
+}
+
 // 删除设备
 async function deleteDevice(deviceId, deviceName) {
     Utils.showConfirm(
@@ -606,6 +796,470 @@ function closeDeviceModal() {
     currentEditingDeviceId = null;
 }
 
+// ===== 设备分组管理 =====
+
+// 加载分组列表
+async function loadGroups() {
+    const loading = document.getElementById('groups-loading');
+    const tableContainer = document.getElementById('groups-table-container');
+    
+    loading.classList.remove('hidden');
+    tableContainer.classList.add('hidden');
+    
+    try {
+        const response = await fetch('/api/bark/groups');
+        const data = await response.json();
+        
+        if (response.ok) {
+            renderGroupsTable(data.groups || []);
+        } else {
+            showAlert('加载分组列表失败: ' + (data.error || '未知错误'), 'error');
+        }
+    } catch (error) {
+        console.error('Error loading groups:', error);
+        showAlert('加载分组列表失败: ' + error.message, 'error');
+    } finally {
+        loading.classList.add('hidden');
+        tableContainer.classList.remove('hidden');
+    }
+}
+
+// 渲染分组表格
+function renderGroupsTable(groups) {
+    const tbody = document.getElementById('groups-tbody');
+    
+    if (groups.length === 0) {
+        tbody.innerHTML = `
+            <tr>
+                <td colspan="5" class="px-6 py-12 text-center">
+                    <div class="flex flex-col items-center">
+                        <i data-lucide="users" class="w-12 h-12 text-slate-300 mb-4"></i>
+                        <h3 class="text-sm font-medium text-slate-900 mb-1">暂无设备分组</h3>
+                        <p class="text-sm text-slate-500 mb-4">把设备归入 on-call、family 等分组，在任务中按分组选择目标</p>
+                        <button onclick="showGroupModal()" class="inline-flex items-center px-4 py-2 bg-blue-600 text-white rounded-lg text-sm font-medium hover:bg-blue-700 transition-colors">
+                            <i data-lucide="plus" class="w-4 h-4 mr-2"></i>
+                            添加分组
+                        </button>
+                    </div>
+                </td>
+            </tr>
+        `;
+        lucide.createIcons();
+        return;
+    }
+    
+    tbody.innerHTML = groups.map(group => {
+        const devices = group.devices || [];
+        const members = devices.length === 0
+            ? '<span class="text-sm text-slate-400">无成员</span>'
+            : devices.map(device => `<span class="text-xs text-slate-600 bg-slate-100 px-2 py-0.5 rounded">${escapeHtml(device.name)}</span>`).join(' ');
+        return `
+        <tr class="hover:bg-slate-50">
+            <td class="px-6 py-4 whitespace-nowrap">
+                <div class="text-sm font-medium text-slate-900">${escapeHtml(group.name)}</div>
+            </td>
+            <td class="px-6 py-4">
+                <div class="flex flex-wrap gap-1 max-w-md">${members}</div>
+            </td>
+            <td class="px-6 py-4">
+                <div class="text-sm text-slate-600 max-w-xs truncate">${escapeHtml(group.description || '-')}</div>
+            </td>
+            <td class="px-6 py-4 whitespace-nowrap text-sm text-slate-600">
+                ${formatDateTime(group.created_at)}
+            </td>
+            <td class="px-6 py-4 whitespace-nowrap text-right text-sm font-medium">
+                <div class="flex items-center justify-end space-x-2">
+                    <button onclick="showGroupModal(${group.id})" class="text-blue-600 hover:text-blue-900">编辑</button>
+                    <button onclick="deleteGroup(${group.id}, '${escapeHtml(group.name)}')" class="text-red-600 hover:text-red-900">删除</button>
+                </div>
+            </td>
+        </tr>
+    `;
+    }).join('');
+}
+
+// 显示分组模态框
+async function showGroupModal(groupId = null) {
+    currentEditingGroupId = groupId;
+    document.getElementById('group-form').reset();
+    document.getElementById('group-modal-title').textContent = groupId ? '编辑分组' : '添加分组';
+    
+    let memberIds = [];
+    if (groupId) {
+        try {
+            const response = await fetch(`/api/bark/groups/${groupId}`);
+            const group = await response.json();
+            if (!response.ok) {
+                showAlert('加载分组数据失败: ' + (group.error || '未知错误'), 'error');
+                return;
+            }
+            document.getElementById('group-name').value = group.name || '';
+            document.getElementById('group-description').value = group.description || '';
+            memberIds = (group.devices || []).map(device => device.id);
+        } catch (error) {
+            console.error('Error loading group data:', error);
+            showAlert('加载分组数据失败: ' + error.message, 'error');
+            return;
+        }
+    }
+    
+    await loadGroupDeviceOptions(memberIds);
+    document.getElementById('group-modal').classList.remove('hidden');
+}
+
+// 加载分组成员的设备选项
+async function loadGroupDeviceOptions(memberIds) {
+    const container = document.getElementById('group-devices');
+    try {
+        const response = await fetch('/api/bark/devices?limit=100');
+        const data = await response.json();
+        const devices = data.devices || [];
+        
+        if (devices.length === 0) {
+            container.innerHTML = '<p class="text-sm text-slate-500">暂无设备，请先添加设备</p>';
+            return;
+        }
+        container.innerHTML = devices.map(device => `
+            <label class="flex items-center space-x-2">
+                <input type="checkbox" value="${device.id}" ${memberIds.includes(device.id) ? 'checked' : ''}
+                       class="h-4 w-4 text-blue-600 focus:ring-blue-500 border-slate-300 rounded">
+                <span class="text-sm text-slate-700">${escapeHtml(device.name)}</span>
+                ${device.status === 'active' ? '' : '<span class="text-xs text-slate-400">已禁用</span>'}
+            </label>
+        `).join('');
+    } catch (error) {
+        console.error('Error loading device options:', error);
+        container.innerHTML = '<p class="text-sm text-red-500">加载设备失败</p>';
+    }
+}
+
+// 保存分组
+async function saveGroup() {
+    const name = document.getElementById('group-name').value.trim();
+    const description = document.getElementById('group-description').value.trim();
+    const deviceIds = Array.from(document.querySelectorAll('#group-devices input[type="checkbox"]:checked'))
+        .map(input => parseInt(input.value));
+    
+    if (!name) {
+        showAlert('请填写分组名称', 'error');
+        return;
+    }
+    
+    const url = currentEditingGroupId ? `/api/bark/groups/${currentEditingGroupId}` : '/api/bark/groups';
+    const method = currentEditingGroupId ? 'PUT' : 'POST';
+    
+    try {
+        const response = await fetch(url, {
+            method,
+            headers: {
+                'Content-Type': 'application/json'
+            },
+            body: JSON.stringify({ name, description, device_ids: deviceIds })
+        });
+        const result = await response.json();
+        
+        if (response.ok) {
+            showAlert(currentEditingGroupId ? '分组更新成功' : '分组创建成功', 'success');
+            closeGroupModal();
+            loadGroups();
+        } else {
+            showAlert('保存失败: ' + (result.error || '未知错误'), 'error');
+        }
+    } catch (error) {
+        console.error('Error saving group:', error);
+        showAlert('保存失败: ' + error.message, 'error');
+    }
+}
+
+// 删除分组
+async function deleteGroup(groupId, groupName) {
+    Utils.showConfirm(
+        '删除分组',
+        `确定要删除分组 "${groupName}" 吗？引用该分组的任务将不再向其发送通知，分组中的设备不受影响。`,
+        async function() {
+            try {
+                const response = await fetch(`/api/bark/groups/${groupId}`, {
+                    method: 'DELETE'
+                });
+                
+                const result = await response.json();
+                
+                if (response.ok) {
+                    showAlert('分组删除成功', 'success');
+                    loadGroups();
+                } else {
+                    showAlert('删除失败: ' + (result.error || '未知错误'), 'error');
+                }
+            } catch (error) {
+                console.error('Error deleting group:', error);
+                showAlert('删除失败: ' + error.message, 'error');
+            }
+        }
+    );
+}
+
+// 关闭分组模态框
+function closeGroupModal() {
+    document.getElementById('group-modal').classList.add('hidden');
+    currentEditingGroupId = null;
+}
+
+// ===== 通知渠道管理 =====
+
+// 各渠道类型的配置示例
+const CHANNEL_CONFIG_EXAMPLES = {
+    webhook: { url: 'https://example.com/hook', method: 'POST', headers: {}, body: '{"text": "$title\\n$body"}' },
+    email: { host: 'smtp.example.com', port: 587, username: '', password: '', from: 'autobot@example.com', to: ['me@example.com'], security: 'starttls' },
+    telegram: { bot_token: '', chat_id: '' },
+    slack: { webhook_url: 'https://hooks.slack.com/services/...' },
+    dingtalk: { webhook_url: 'https://oapi.dingtalk.com/robot/send?access_token=...', secret: '' },
+    wecom: { webhook_url: 'https://qyapi.weixin.qq.com/cgi-bin/webhook/send?key=...' },
+    feishu: { webhook_url: 'https://open.feishu.cn/open-apis/bot/v2/hook/...', secret: '' },
+    ntfy: { server_url: 'https://ntfy.sh', topic: '', token: '' },
+    gotify: { server_url: 'https://gotify.example.com', app_token: '' }
+};
+
+// 渠道类型显示名称
+const CHANNEL_TYPE_NAMES = {
+    webhook: 'Webhook',
+    email: '邮件',
+    telegram: 'Telegram',
+    slack: 'Slack',
+    dingtalk: '钉钉',
+    wecom: '企业微信',
+    feishu: '飞书',
+    ntfy: 'ntfy',
+    gotify: 'Gotify'
+};
+
+// 加载渠道列表
+async function loadChannels() {
+    const loading = document.getElementById('channels-loading');
+    const tableContainer = document.getElementById('channels-table-container');
+    
+    loading.classList.remove('hidden');
+    tableContainer.classList.add('hidden');
+    
+    try {
+        const response = await fetch('/api/channels');
+        const data = await response.json();
+        
+        if (response.ok) {
+            renderChannelsTable(data.channels || []);
+        } else {
+            showAlert('加载渠道列表失败: ' + (data.error || '未知错误'), 'error');
+        }
+    } catch (error) {
+        console.error('Error loading channels:', error);
+        showAlert('加载渠道列表失败: ' + error.message, 'error');
+    } finally {
+        loading.classList.add('hidden');
+        tableContainer.classList.remove('hidden');
+    }
+}
+
+// 渲染渠道表格
+function renderChannelsTable(channels) {
+    const tbody = document.getElementById('channels-tbody');
+    
+    if (channels.length === 0) {
+        tbody.innerHTML = `
+            <tr>
+                <td colspan="6" class="px-6 py-12 text-center">
+                    <div class="flex flex-col items-center">
+                        <i data-lucide="send" class="w-12 h-12 text-slate-300 mb-4"></i>
+                        <h3 class="text-sm font-medium text-slate-900 mb-1">暂无通知渠道</h3>
+                        <p class="text-sm text-slate-500 mb-4">添加 Webhook、邮件、Telegram 等渠道，在任务中与 Bark 设备一起选择</p>
+                        <button onclick="showChannelModal()" class="inline-flex items-center px-4 py-2 bg-blue-600 text-white rounded-lg text-sm font-medium hover:bg-blue-700 transition-colors">
+                            <i data-lucide="plus" class="w-4 h-4 mr-2"></i>
+                            添加渠道
+                        </button>
+                    </div>
+                </td>
+            </tr>
+        `;
+        lucide.createIcons();
+        return;
+    }
+    
+    tbody.innerHTML = channels.map(channel => `
+        <tr class="hover:bg-slate-50">
+            <td class="px-6 py-4 whitespace-nowrap">
+                <div class="text-sm font-medium text-slate-900">${escapeHtml(channel.name)}</div>
+            </td>
+            <td class="px-6 py-4 whitespace-nowrap">
+                <span class="text-sm text-slate-600 bg-slate-100 px-2 py-1 rounded">${escapeHtml(CHANNEL_TYPE_NAMES[channel.type] || channel.type)}</span>
+            </td>
+            <td class="px-6 py-4">
+                <div class="text-sm text-slate-600 max-w-xs truncate">${escapeHtml(channel.description || '-')}</div>
+            </td>
+            <td class="px-6 py-4 whitespace-nowrap">
+                <span class="inline-flex items-center px-2 py-0.5 rounded-full text-xs font-medium ${channel.status === 'active' ? 'bg-green-100 text-green-800' : 'bg-red-100 text-red-800'}">
+                    ${channel.status === 'active' ? '启用' : '禁用'}
+                </span>
+            </td>
+            <td class="px-6 py-4 whitespace-nowrap text-sm text-slate-600">
+                ${formatDateTime(channel.created_at)}
+            </td>
+            <td class="px-6 py-4 whitespace-nowrap text-right text-sm font-medium">
+                <div class="flex items-center justify-end space-x-2">
+                    <button onclick="testChannel(${channel.id})" class="text-green-600 hover:text-green-900">测试</button>
+                    <button onclick="showChannelModal(${channel.id})" class="text-blue-600 hover:text-blue-900">编辑</button>
+                    <button onclick="deleteChannel(${channel.id}, '${escapeHtml(channel.name)}')" class="text-red-600 hover:text-red-900">删除</button>
+                </div>
+            </td>
+        </tr>
+    `).join('');
+}
+
+// 显示渠道模态框
+async function showChannelModal(channelId = null) {
+    currentEditingChannelId = channelId;
+    const title = document.getElementById('channel-modal-title');
+    const typeSelect = document.getElementById('channel-type');
+    
+    document.getElementById('channel-form').reset();
+    typeSelect.disabled = !!channelId;
+    document.getElementById('channel-status-row').classList.toggle('hidden', !channelId);
+    
+    if (channelId) {
+        title.textContent = '编辑渠道';
+        try {
+            const response = await fetch(`/api/channels/${channelId}`);
+            const channel = await response.json();
+            if (!response.ok) {
+                showAlert('加载渠道数据失败: ' + (channel.error || '未知错误'), 'error');
+                return;
+            }
+            document.getElementById('channel-name').value = channel.name || '';
+            typeSelect.value = channel.type;
+            document.getElementById('channel-config').value = formatChannelConfig(channel.config);
+            document.getElementById('channel-description').value = channel.description || '';
+            document.getElementById('channel-active').checked = channel.status === 'active';
+        } catch (error) {
+            console.error('Error loading channel data:', error);
+            showAlert('加载渠道数据失败: ' + error.message, 'error');
+            return;
+        }
+    } else {
+        title.textContent = '添加渠道';
+        document.getElementById('channel-config').value = JSON.stringify(CHANNEL_CONFIG_EXAMPLES[typeSelect.value] || {}, null, 2);
+    }
+    
+    document.getElementById('channel-modal').classList.remove('hidden');
+}
+
+// 格式化渠道配置 JSON
+function formatChannelConfig(config) {
+    try {
+        return JSON.stringify(JSON.parse(config), null, 2);
+    } catch (error) {
+        return config || '';
+    }
+}
+
+// 保存渠道
+async function saveChannel() {
+    const name = document.getElementById('channel-name').value.trim();
+    const type = document.getElementById('channel-type').value;
+    const config = document.getElementById('channel-config').value.trim();
+    const description = document.getElementById('channel-description').value.trim();
+    
+    if (!name || !config) {
+        showAlert('请填写必填字段', 'error');
+        return;
+    }
+    try {
+        JSON.parse(config);
+    } catch (error) {
+        showAlert('配置不是有效的 JSON: ' + error.message, 'error');
+        return;
+    }
+    
+    const data = { name, config, description };
+    let url = '/api/channels';
+    let method = 'POST';
+    if (currentEditingChannelId) {
+        url = `/api/channels/${currentEditingChannelId}`;
+        method = 'PUT';
+        data.status = document.getElementById('channel-active').checked ? 'active' : 'inactive';
+    } else {
+        data.type = type;
+    }
+    
+    try {
+        const response = await fetch(url, {
+            method,
+            headers: {
+                'Content-Type': 'application/json'
+            },
+            body: JSON.stringify(data)
+        });
+        const result = await response.json();
+        
+        if (response.ok) {
+            showAlert(currentEditingChannelId ? '渠道更新成功' : '渠道创建成功', 'success');
+            closeChannelModal();
+            loadChannels();
+        } else {
+            showAlert('保存失败: ' + (result.error || '未知错误'), 'error');
+        }
+    } catch (error) {
+        console.error('Error saving channel:', error);
+        showAlert('保存失败: ' + error.message, 'error');
+    }
+}
+
+// 发送测试通知
+async function testChannel(channelId) {
+    try {
+        const response = await fetch(`/api/channels/${channelId}/test`, { method: 'POST' });
+        const result = await response.json();
+        
+        if (response.ok) {
+            showAlert(result.message || '测试通知已发送', 'success');
+        } else {
+            showAlert(result.error || '测试通知发送失败', 'error');
+        }
+    } catch (error) {
+        console.error('Error testing channel:', error);
+        showAlert('测试通知发送失败: ' + error.message, 'error');
+    }
+}
+
+// 删除渠道
+async function deleteChannel(channelId, channelName) {
+    Utils.showConfirm(
+        '删除渠道',
+        `确定要删除渠道 "${channelName}" 吗？引用该渠道的任务将不再向其发送通知。`,
+        async function() {
+            try {
+                const response = await fetch(`/api/channels/${channelId}`, {
+                    method: 'DELETE'
+                });
+                
+                const result = await response.json();
+                
+                if (response.ok) {
+                    showAlert('渠道删除成功', 'success');
+                    loadChannels();
+                } else {
+                    showAlert('删除失败: ' + (result.error || '未知错误'), 'error');
+                }
+            } catch (error) {
+                console.error('Error deleting channel:', error);
+                showAlert('删除失败: ' + error.message, 'error');
+            }
+        }
+    );
+}
+
+// 关闭渠道模态框
+function closeChannelModal() {
+    document.getElementById('channel-modal').classList.add('hidden');
+    currentEditingChannelId = null;
+}
+
 // ===== 工具函数 =====
 
 // 显示提示消息
@@ -50,6 +50,11 @@ function bindEvents() {
         deleteAllBarkLogs();
     });
     
+    // 导出Bark记录按钮（CSV，筛选参数与 /api/bark/records 相同）
+    $('#exportBarkRecordsBtn').on('click', function() {
+        window.location.href = '/api/bark/records/export?format=csv';
+    });
+    
     // 统计信息按钮
     $('#logStatsBtn').on('click', function() {
         showLogStats();
@@ -558,6 +563,65 @@ async function deleteAllLogs() {
     );
 }
 
+// Bark记录保留时间，按天显示
+function formatRetention(hours) {
+    return hours % 24 === 0 ? `${hours / 24} 天` : `${hours} 小时`;
+}
+
+// 渲染Bark按设备（前 10 个）和最近每天的发送统计
+function renderBarkBreakdown(barkStats) {
+    const byDevice = (barkStats.by_device || []).slice(0, 10);
+    const byDay = barkStats.by_day || [];
+    if (byDevice.length === 0 && byDay.length === 0) {
+        return '';
+    }
+    
+    const deviceRows = byDevice.map(item => `
+        <tr class="border-t border-slate-100">
+            <td class="py-1 pr-2 text-slate-700">${Utils.escapeHtml(item.name || (item.channel_id ? '渠道 #' + item.channel_id : item.device_key || '-'))}</td>
+            <td class="py-1 px-2 text-right">${item.total}</td>
+            <td class="py-1 px-2 text-right text-emerald-600">${item.success}</td>
+            <td class="py-1 px-2 text-right text-red-600">${item.failed}</td>
+            <td class="py-1 pl-2 text-right text-slate-500">${item.skipped}</td>
+        </tr>
+    `).join('');
+    
+    const maxDay = Math.max(1, ...byDay.map(item => item.total));
+    const dayRows = byDay.map(item => `
+        <div class="flex items-center gap-2 text-xs" title="成功 ${item.success}，失败 ${item.failed}，跳过 ${item.skipped}">
+            <span class="w-20 text-slate-500">${item.day}</span>
+            <div class="flex-1 bg-slate-100 rounded h-2">
+                <div class="bg-orange-400 h-2 rounded" style="width: ${Math.round(item.total / maxDay * 100)}%"></div>
+            </div>
+            <span class="w-10 text-right text-slate-600">${item.total}</span>
+        </div>
+    `).join('');
+    
+    return `
+        <div class="grid grid-cols-1 md:grid-cols-2 gap-3 mb-4">
+            <div class="bg-slate-50 rounded-lg p-3">
+                <h4 class="font-semibold text-slate-900 mb-2 text-sm">按设备</h4>
+                <table class="w-full text-xs">
+                    <thead>
+                        <tr class="text-slate-500">
+                            <th class="text-left font-medium pr-2">设备/渠道</th>
+                            <th class="text-right font-medium px-2">总数</th>
+                            <th class="text-right font-medium px-2">成功</th>
+                            <th class="text-right font-medium px-2">失败</th>
+                            <th class="text-right font-medium pl-2">跳过</th>
+                        </tr>
+                    </thead>
+                    <tbody>${deviceRows}</tbody>
+                </table>
+            </div>
+            <div class="bg-slate-50 rounded-lg p-3">
+                <h4 class="font-semibold text-slate-900 mb-2 text-sm">最近 30 天</h4>
+                <div class="space-y-1 max-h-64 overflow-y-auto">${dayRows || '<div class="text-xs text-slate-500">暂无记录</div>'}</div>
+            </div>
+        </div>
+    `;
+}
+
 // 删除所有Bark日志
 async function deleteAllBarkLogs() {
     Utils.showConfirm(
@@ -642,9 +706,15 @@ async function showLogStats() {
                         <div class="text-xs text-slate-500 mt-1">成功发送的通知</div>
                     </div>
                     <div class="bg-gradient-to-r from-yellow-50 to-yellow-100 rounded-lg p-4 text-center">
-                        <div class="text-2xl font-bold text-yellow-600 mb-1">${barkStats.total_records - barkStats.success_records}</div>
+                        <div class="text-2xl font-bold text-yellow-600 mb-1">${barkStats.total_records - barkStats.success_records - (barkStats.pending_records || 0) - (barkStats.queued_records || 0)}</div>
                         <div class="text-sm text-slate-600">跳过/失败</div>
-                        <div class="text-xs text-slate-500 mt-1">跳过或失败的通知</div>
+                        <div class="text-xs text-slate-500 mt-1">${[
+                            barkStats.throttled_records ? `其中 ${barkStats.throttled_records} 条超过推送限额` : '',
+                            ...[
+                                barkStats.pending_records ? `${barkStats.pending_records} 条等待重试` : '',
+                                barkStats.queued_records ? `${barkStats.queued_records} 条等待汇总` : ''
+                            ].filter(Boolean).map(text => '另有 ' + text)
+                        ].filter(Boolean).join('，') || '跳过或失败的通知'}</div>
                     </div>
                     <div class="bg-gradient-to-r from-indigo-50 to-indigo-100 rounded-lg p-4 text-center">
                         <div class="text-2xl font-bold text-indigo-600 mb-1">${Math.round((barkStats.total_records / barkStats.max_records) * 100)}%</div>
@@ -655,6 +725,9 @@ async function showLogStats() {
             </div>
         `;
         
+        // Bark按设备和按天统计
+        html += renderBarkBreakdown(barkStats);
+        
         // 时间范围信息
         if (logStats.total_logs > 0 || barkStats.total_records > 0) {
             html += `
@@ -706,7 +779,7 @@ async function showLogStats() {
                         <i data-lucide="bell" class="w-4 h-4 mr-2 mt-0.5 text-orange-500 flex-shrink-0"></i>
                         <div>
                             <div class="font-medium mb-1">通知记录</div>
-                            <div class="text-xs">Bark通知记录最多保留 ${barkStats.max_records} 条，包含去重检查</div>
+                            <div class="text-xs">Bark通知记录最多保留 ${barkStats.max_records} 条${barkStats.retention_hours ? `、${formatRetention(barkStats.retention_hours)}` : ''}，包含去重检查</div>
                         </div>
                     </div>
                     <div class="flex items-start">
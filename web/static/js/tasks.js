@@ -128,7 +128,8 @@ function renderTasks(tasks) {
 
 // 渲染单个任务卡片
 function renderTaskCard(task) {
-    const statusBadge = getStatusBadge(task.status, task.id);
+    const readOnly = task.source === 'git';
+    const statusBadge = readOnly ? getReadOnlyStatusBadge(task.status) : getStatusBadge(task.status, task.id);
     const lastRun = task.last_run ? Utils.formatRelativeTime(task.last_run) : '从未执行';
     const nextRun = task.next_run ? Utils.formatDateTime(task.next_run) : '-';
     const taskNameEscaped = task.name.replace(/'/g, "\\'");
@@ -141,6 +142,11 @@ function renderTaskCard(task) {
                     <h3 class="text-lg font-semibold text-slate-900 truncate" title="${task.name}">
                         ${task.name}
                     </h3>
+                    ${readOnly ? `
+                    <span class="inline-flex items-center gap-1 mt-1 px-2 py-0.5 bg-amber-50 text-amber-700 rounded text-xs font-medium" title="由 Git 仓库管理：${task.source_path}">
+                        <i data-lucide="git-branch" class="w-3 h-3"></i>
+                        Git
+                    </span>` : ''}
                     <p class="text-sm text-slate-600 mt-1 line-clamp-2" title="${task.description}">
                         ${task.description || '无描述'}
                     </p>
@@ -180,11 +186,12 @@ function renderTaskCard(task) {
                     </button>
                 </div>
                 
-                <!-- 右侧删除按钮 -->
+                <!-- 右侧删除按钮（Git 同步的任务只能在仓库中删除） -->
+                ${readOnly ? '' : `
                 <button onclick="deleteTask(${task.id}, '${taskNameEscaped}')" 
                         class="flex items-center gap-1 px-3 py-1.5 bg-red-50 text-red-700 rounded-lg hover:bg-red-100 transition-colors text-sm font-medium">
                     <i data-lucide="trash-2" class="w-3.5 h-3.5"></i>
-                </button>
+                </button>`}
             </div>
         </div>
     `;
@@ -211,6 +218,14 @@ function getStatusBadge(status, taskId) {
     `;
 }
 
+// 获取只读状态标签（Git 同步的任务不能在界面切换状态）
+function getReadOnlyStatusBadge(status) {
+    const isActive = status === 'active';
+    return `
+        <span class="text-xs font-medium ${isActive ? 'text-blue-800' : 'text-slate-600'}">${isActive ? '已激活' : '未激活'}</span>
+    `;
+}
+
 // 渲染分页
 function renderPagination(total, page, limit) {
     const totalPages = Math.ceil(total / limit);